@@ -2,6 +2,7 @@ package application
 
 import (
 	"rashikzaman/api/config"
+	"rashikzaman/api/services"
 
 	"github.com/uptrace/bun"
 )
@@ -10,3 +11,42 @@ type Application struct {
 	DB     *bun.DB
 	Config config.Config
 }
+
+// Notifications returns the notification service, sending in-app notifications and on
+// every other channel that is configured.
+func (app *Application) Notifications() *services.NotificationService {
+	notifiers := []services.Notifier{services.InAppNotifier{}}
+
+	if app.Config.GetTwilioAccountSID() != "" {
+		notifiers = append(notifiers, services.SMSNotifier{
+			TwilioAccountSID:  app.Config.GetTwilioAccountSID(),
+			TwilioPhoneNumber: app.Config.GetTwilioPhoneNumber(),
+			TwilioAuthToken:   app.Config.GetTwilioAuthToken(),
+		})
+	}
+
+	if app.Config.GetSMTPHost() != "" {
+		notifiers = append(notifiers, services.EmailNotifier{SMTP: app.SMTPConfig()})
+	}
+
+	if app.Config.GetVAPIDPrivateKey() != "" {
+		notifiers = append(notifiers, services.PushNotifier{
+			VAPIDPublicKey:  app.Config.GetVAPIDPublicKey(),
+			VAPIDPrivateKey: app.Config.GetVAPIDPrivateKey(),
+			Subject:         app.Config.GetVAPIDSubject(),
+		})
+	}
+
+	return services.NewNotificationService(notifiers...)
+}
+
+// SMTPConfig returns the settings of the SMTP server emails are sent through.
+func (app *Application) SMTPConfig() services.SMTPConfig {
+	return services.SMTPConfig{
+		Host:     app.Config.GetSMTPHost(),
+		Port:     app.Config.GetSMTPPort(),
+		Username: app.Config.GetSMTPUsername(),
+		Password: app.Config.GetSMTPPassword(),
+		From:     app.Config.GetSMTPFrom(),
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"rashikzaman/api/application"
 	"rashikzaman/api/config"
 	"rashikzaman/api/db"
 	"rashikzaman/api/http"
+	"rashikzaman/api/jobs"
 	"rashikzaman/api/log"
 )
 
@@ -32,5 +34,7 @@ func main() {
 	app.DB = db
 	app.Config = config
 
+	jobs.Run(context.Background(), app, logger)
+
 	http.RunHTTPServer(app)
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"rashikzaman/api/application"
+	"rashikzaman/api/config"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/db"
+	"rashikzaman/api/log"
+	"syscall"
+	"time"
+)
+
+// The worker sends the notifications queued in the outbox. Any number of workers can run
+// side by side.
+func main() {
+	logger := log.NewLogger()
+
+	config, err := config.InitConfig("../../.env")
+	if err != nil {
+		logger.Fatal(err, err.Error())
+	}
+
+	db, err := db.InitDB(config.GetDBConfig())
+	if err != nil {
+		logger.Fatal(err, err.Error())
+	}
+
+	app := application.Application{DB: db, Config: config}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Infof("notification worker started")
+
+	run(ctx, &app, logger)
+
+	logger.Infof("notification worker stopped")
+}
+
+// run delivers the outbox batch by batch, waiting for new jobs once it runs out, until ctx
+// is cancelled.
+func run(ctx context.Context, app *application.Application, logger log.Logger) {
+	notifications := app.Notifications()
+
+	for {
+		claimed, err := notifications.DeliverPending(ctx, app.DB, constants.NotificationWorkerBatchSize)
+		if err != nil {
+			logger.Errorf(err, "delivering notifications failed")
+		}
+
+		if claimed > 0 {
+			logger.Infof("processed %d notification deliveries", claimed)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		// more jobs are likely waiting behind a full batch
+		if err == nil && claimed == constants.NotificationWorkerBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.NotificationWorkerPollSeconds * time.Second):
+		}
+	}
+}
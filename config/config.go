@@ -22,6 +22,14 @@ type envConfig struct {
 	TwilioAccountSID      string `env:"TWILIO_ACCOUNT_SID"`
 	TwilioAuthToken       string `env:"TWILIO_AUTH_TOKEN"`
 	TwilioPhoneNumber     string `env:"TWILIO_PHONE_NUMBER"`
+	SMTPHost              string `env:"SMTP_HOST"`
+	SMTPPort              string `env:"SMTP_PORT"`
+	SMTPUsername          string `env:"SMTP_USERNAME"`
+	SMTPPassword          string `env:"SMTP_PASSWORD"`
+	SMTPFrom              string `env:"SMTP_FROM"`
+	VAPIDPublicKey        string `env:"VAPID_PUBLIC_KEY"`
+	VAPIDPrivateKey       string `env:"VAPID_PRIVATE_KEY"`
+	VAPIDSubject          string `env:"VAPID_SUBJECT"`
 }
 
 type Config struct {
@@ -51,6 +59,14 @@ func InitConfig(filepath string) (Config, error) {
 			TwilioAccountSID:      os.Getenv("TWILIO_ACCOUNT_SID"),
 			TwilioAuthToken:       os.Getenv("TWILIO_AUTH_TOKEN"),
 			TwilioPhoneNumber:     os.Getenv("TWILIO_PHONE_NUMBER"),
+			SMTPHost:              os.Getenv("SMTP_HOST"),
+			SMTPPort:              os.Getenv("SMTP_PORT"),
+			SMTPUsername:          os.Getenv("SMTP_USERNAME"),
+			SMTPPassword:          os.Getenv("SMTP_PASSWORD"),
+			SMTPFrom:              os.Getenv("SMTP_FROM"),
+			VAPIDPublicKey:        os.Getenv("VAPID_PUBLIC_KEY"),
+			VAPIDPrivateKey:       os.Getenv("VAPID_PRIVATE_KEY"),
+			VAPIDSubject:          os.Getenv("VAPID_SUBJECT"),
 		}
 	} else {
 		// If filepath loading succeeds, parse env vars
@@ -119,3 +135,35 @@ func (config Config) GetTwilioAuthToken() string {
 func (config Config) GetTwilioPhoneNumber() string {
 	return config.envConfig.TwilioPhoneNumber
 }
+
+func (config Config) GetSMTPHost() string {
+	return config.envConfig.SMTPHost
+}
+
+func (config Config) GetSMTPPort() string {
+	return config.envConfig.SMTPPort
+}
+
+func (config Config) GetSMTPUsername() string {
+	return config.envConfig.SMTPUsername
+}
+
+func (config Config) GetSMTPPassword() string {
+	return config.envConfig.SMTPPassword
+}
+
+func (config Config) GetSMTPFrom() string {
+	return config.envConfig.SMTPFrom
+}
+
+func (config Config) GetVAPIDPublicKey() string {
+	return config.envConfig.VAPIDPublicKey
+}
+
+func (config Config) GetVAPIDPrivateKey() string {
+	return config.envConfig.VAPIDPrivateKey
+}
+
+func (config Config) GetVAPIDSubject() string {
+	return config.envConfig.VAPIDSubject
+}
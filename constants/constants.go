@@ -3,4 +3,103 @@ package constants
 const (
 	SessionRedisSize    = 10
 	SessionCookieMaxAge = 604800
+
+	// RecurrenceWindowDays is how far ahead occurrences of recurring tasks are materialized.
+	RecurrenceWindowDays = 60
+
+	// CheckInOpensMinutesBefore is how early volunteers may check in before a task starts.
+	CheckInOpensMinutesBefore = 60
+
+	// CheckOutGraceMinutes is how long after a task ends volunteers are still credited for
+	// when they check out late.
+	CheckOutGraceMinutes = 30
+
+	// AttendanceTokenRotationSeconds is how often the attendance QR code of a task changes.
+	// A token stays valid until the end of the rotation after the one it was issued in.
+	AttendanceTokenRotationSeconds = 60
+
+	// LateWithdrawalHours is how close to a task's start withdrawing counts against the
+	// volunteer's reliability.
+	LateWithdrawalHours = 24
+
+	// DocumentURLExpiryMinutes is how long the links admins get to verification documents
+	// stay valid.
+	DocumentURLExpiryMinutes = 15
+
+	// NoShowSweepMinutes is how often ended tasks are checked for volunteers who never
+	// checked in.
+	NoShowSweepMinutes = 15
+
+	// DefaultSearchRadiusKm is the radius tasks are searched in around a location when the
+	// request does not give one.
+	DefaultSearchRadiusKm = 25
+
+	// MapClusterMaxZoom is the zoom level up to which tasks on the map are clustered.
+	MapClusterMaxZoom = 13
+
+	// MapMaxPoints caps the number of individual tasks returned for a map viewport.
+	MapMaxPoints = 500
+
+	// MatchSkillWeight is how much the skills count in the match score of a task, against
+	// 1 - MatchSkillWeight for its distance.
+	MatchSkillWeight = 0.7
+
+	// MatchDistanceHalfScoreKm is how far from the volunteer a task scores half of the
+	// distance part of its match score.
+	MatchDistanceHalfScoreKm = 10
+
+	// RecommendationCandidates is how many open tasks, nearest first, the recommendations
+	// feed ranks for a volunteer.
+	RecommendationCandidates = 200
+
+	// RecommendationFeedSize is how many tasks the recommendations feed returns by default.
+	RecommendationFeedSize = 20
+
+	// RecommendationNearbyKm is how close a task has to be to be recommended as near you.
+	RecommendationNearbyKm = 5
+
+	// weights of the parts of a recommended task's score, they add up to 1
+	RecommendationSkillWeight    = 0.45
+	RecommendationDistanceWeight = 0.35
+	RecommendationCategoryWeight = 0.2
+
+	// RecommendationCategoryDecay is what the score of a task is multiplied by for every
+	// task of the same category ranked above it, so the feed is not all one category.
+	RecommendationCategoryDecay = 0.6
+
+	// NearbyTaskNotificationKm is how close to a new task users are told about it, unless
+	// they chose another radius, up to NotificationMaxRadiusKm.
+	NearbyTaskNotificationKm = 10
+	NotificationMaxRadiusKm  = 100
+
+	// PushNotificationTTLSeconds is how long push services keep a notification for a
+	// browser that is offline.
+	PushNotificationTTLSeconds = 24 * 60 * 60
+
+	// NotificationWorkerBatchSize is how many outbox jobs the worker claims at once.
+	NotificationWorkerBatchSize = 50
+
+	// NotificationWorkerPollSeconds is how long the worker waits before looking for new
+	// outbox jobs once it has run out of them.
+	NotificationWorkerPollSeconds = 5
+
+	// NotificationMaxAttempts is how many times a notification is tried before it is
+	// dead-lettered.
+	NotificationMaxAttempts = 8
+
+	// a failed notification is retried after NotificationRetryBaseSeconds, doubling with
+	// every attempt up to NotificationRetryMaxSeconds
+	NotificationRetryBaseSeconds = 30
+	NotificationRetryMaxSeconds  = 60 * 60
+
+	// DigestSweepMinutes is how often users in digest mode are checked for a digest that is
+	// due.
+	DigestSweepMinutes = 15
+
+	// DigestHour is the hour of the day, in the user's time zone, digests go out at. Weekly
+	// digests go out on Mondays.
+	DigestHour = 8
+
+	// DigestMaxTasks is how many tasks a digest lists at most, nearest first.
+	DigestMaxTasks = 50
 )
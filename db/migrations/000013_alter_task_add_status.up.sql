@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE tasks
+ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'open' CHECK (
+    status IN ('draft', 'open', 'filled', 'in_progress', 'completed', 'cancelled')
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks (status);
+
+COMMIT;
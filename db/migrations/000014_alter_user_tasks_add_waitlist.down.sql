@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_user_tasks_task_id_waitlisted;
+
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS waitlisted;
+
+ALTER TABLE user_tasks DROP CONSTRAINT IF EXISTS uq_user_tasks_user_id_task_id;
+
+COMMIT;
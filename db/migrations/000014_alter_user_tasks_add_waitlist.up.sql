@@ -0,0 +1,18 @@
+BEGIN;
+
+-- keep only the earliest application of users who applied to the same task more than once
+DELETE FROM user_tasks a
+USING user_tasks b
+WHERE a.user_id = b.user_id
+    AND a.task_id = b.task_id
+    AND (a.created_at, a.id) > (b.created_at, b.id);
+
+ALTER TABLE user_tasks
+ADD CONSTRAINT uq_user_tasks_user_id_task_id UNIQUE (user_id, task_id);
+
+ALTER TABLE user_tasks
+ADD COLUMN waitlisted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_user_tasks_task_id_waitlisted ON user_tasks (task_id, waitlisted, created_at);
+
+COMMIT;
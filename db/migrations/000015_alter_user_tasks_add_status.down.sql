@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_user_tasks_task_id_status;
+
+-- applications that are no longer active did not exist before statuses were introduced
+DELETE FROM user_tasks WHERE status IN ('pending', 'rejected', 'withdrawn');
+
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS status;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS auto_accept;
+
+CREATE INDEX IF NOT EXISTS idx_user_tasks_task_id_waitlisted ON user_tasks (task_id, waitlisted, created_at);
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+ALTER TABLE user_tasks
+ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'accepted' CHECK (
+    status IN ('pending', 'accepted', 'rejected', 'withdrawn')
+);
+
+-- existing tasks keep accepting volunteers as soon as they apply
+ALTER TABLE tasks ADD COLUMN auto_accept BOOLEAN NOT NULL DEFAULT TRUE;
+
+DROP INDEX IF EXISTS idx_user_tasks_task_id_waitlisted;
+
+CREATE INDEX IF NOT EXISTS idx_user_tasks_task_id_status ON user_tasks (task_id, status, waitlisted, created_at);
+
+COMMIT;
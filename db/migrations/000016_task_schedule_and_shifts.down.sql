@@ -0,0 +1,23 @@
+BEGIN;
+
+DROP INDEX IF EXISTS uq_user_tasks_user_id_task_id_shift_id;
+
+DELETE FROM user_tasks WHERE shift_id IS NOT NULL;
+
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS shift_id;
+
+ALTER TABLE user_tasks
+ADD CONSTRAINT uq_user_tasks_user_id_task_id UNIQUE (user_id, task_id);
+
+DROP TABLE IF EXISTS task_shifts;
+
+DROP INDEX IF EXISTS idx_tasks_starts_at;
+DROP INDEX IF EXISTS idx_tasks_ends_at;
+
+ALTER TABLE tasks
+DROP CONSTRAINT IF EXISTS chk_tasks_schedule,
+DROP COLUMN IF EXISTS starts_at,
+DROP COLUMN IF EXISTS ends_at,
+DROP COLUMN IF EXISTS time_zone;
+
+COMMIT;
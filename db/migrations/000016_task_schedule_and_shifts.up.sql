@@ -0,0 +1,43 @@
+BEGIN;
+
+ALTER TABLE tasks
+ADD COLUMN starts_at TIMESTAMPTZ NULL,
+ADD COLUMN ends_at TIMESTAMPTZ NULL,
+ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+ADD CONSTRAINT chk_tasks_schedule CHECK (
+    ends_at IS NULL OR (starts_at IS NOT NULL AND ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_starts_at ON tasks (starts_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_ends_at ON tasks (ends_at);
+
+CREATE TABLE task_shifts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    task_id UUID NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    capacity INT NOT NULL DEFAULT 0 CHECK (capacity >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_task_shifts_schedule CHECK (ends_at > starts_at),
+    CONSTRAINT fk_task_task_shifts FOREIGN KEY (task_id) REFERENCES tasks (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_shifts_task_id ON task_shifts (task_id, starts_at);
+
+create trigger set_timestamp_task_shifts before
+update
+    on task_shifts for each row execute procedure trigger_set_updated_at_timestamp();
+
+ALTER TABLE user_tasks
+ADD COLUMN shift_id UUID NULL,
+ADD CONSTRAINT fk_task_shift_user_tasks FOREIGN KEY (shift_id) REFERENCES task_shifts (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+-- a user can sign up for several shifts of the same task, but only once per shift
+ALTER TABLE user_tasks DROP CONSTRAINT IF EXISTS uq_user_tasks_user_id_task_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_tasks_user_id_task_id_shift_id ON user_tasks (
+    user_id, task_id, COALESCE(shift_id, '00000000-0000-0000-0000-000000000000'::UUID)
+);
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_tasks_recurring;
+DROP INDEX IF EXISTS uq_tasks_parent_task_id_starts_at;
+
+DELETE FROM tasks WHERE parent_task_id IS NOT NULL;
+
+ALTER TABLE tasks
+DROP CONSTRAINT IF EXISTS fk_task_parent_task,
+DROP COLUMN IF EXISTS recurrence_rule,
+DROP COLUMN IF EXISTS parent_task_id,
+DROP COLUMN IF EXISTS recurrence_generated_until;
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+ALTER TABLE tasks
+ADD COLUMN recurrence_rule TEXT NOT NULL DEFAULT '',
+ADD COLUMN parent_task_id UUID NULL,
+ADD COLUMN recurrence_generated_until TIMESTAMPTZ NULL,
+ADD CONSTRAINT fk_task_parent_task FOREIGN KEY (parent_task_id) REFERENCES tasks (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+-- an occurrence is materialized at most once per start time
+CREATE UNIQUE INDEX IF NOT EXISTS uq_tasks_parent_task_id_starts_at ON tasks (parent_task_id, starts_at)
+WHERE
+    parent_task_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_recurring ON tasks (recurrence_generated_until)
+WHERE
+    recurrence_rule <> '';
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_user_tasks_user_id_hours;
+
+ALTER TABLE user_tasks
+DROP CONSTRAINT IF EXISTS chk_user_tasks_attendance,
+DROP CONSTRAINT IF EXISTS fk_user_user_tasks_corrected_by,
+DROP COLUMN IF EXISTS checked_in_at,
+DROP COLUMN IF EXISTS checked_out_at,
+DROP COLUMN IF EXISTS recorded_minutes,
+DROP COLUMN IF EXISTS corrected_minutes,
+DROP COLUMN IF EXISTS correction_note,
+DROP COLUMN IF EXISTS corrected_by_user_id;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS check_in_radius_meters;
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+ALTER TABLE tasks
+ADD COLUMN check_in_radius_meters INT NOT NULL DEFAULT 0 CHECK (check_in_radius_meters >= 0);
+
+ALTER TABLE user_tasks
+ADD COLUMN checked_in_at TIMESTAMPTZ NULL,
+ADD COLUMN checked_out_at TIMESTAMPTZ NULL,
+ADD COLUMN recorded_minutes INT NULL CHECK (recorded_minutes >= 0),
+ADD COLUMN corrected_minutes INT NULL CHECK (corrected_minutes >= 0),
+ADD COLUMN correction_note TEXT NOT NULL DEFAULT '',
+ADD COLUMN corrected_by_user_id UUID NULL,
+ADD CONSTRAINT fk_user_user_tasks_corrected_by FOREIGN KEY (corrected_by_user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL,
+ADD CONSTRAINT chk_user_tasks_attendance CHECK (
+    checked_out_at IS NULL OR (checked_in_at IS NOT NULL AND checked_out_at >= checked_in_at)
+);
+
+-- the hours ledger only looks at applications with credited time
+CREATE INDEX IF NOT EXISTS idx_user_tasks_user_id_hours ON user_tasks (user_id)
+WHERE
+    recorded_minutes IS NOT NULL OR corrected_minutes IS NOT NULL;
+
+COMMIT;
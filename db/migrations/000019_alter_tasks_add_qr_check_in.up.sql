@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE tasks
+ADD COLUMN qr_check_in_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
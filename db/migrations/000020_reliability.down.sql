@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_user_tasks_unsettled;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS min_reliability_score;
+
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS attendance_outcome;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS completed_tasks_count,
+DROP COLUMN IF EXISTS no_show_count,
+DROP COLUMN IF EXISTS late_withdrawal_count;
+
+COMMIT;
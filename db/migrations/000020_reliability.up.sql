@@ -0,0 +1,23 @@
+BEGIN;
+
+ALTER TABLE users
+ADD COLUMN completed_tasks_count INT NOT NULL DEFAULT 0,
+ADD COLUMN no_show_count INT NOT NULL DEFAULT 0,
+ADD COLUMN late_withdrawal_count INT NOT NULL DEFAULT 0;
+
+ALTER TABLE user_tasks
+ADD COLUMN attendance_outcome VARCHAR(20) NULL CHECK (
+    attendance_outcome IN ('completed', 'no_show', 'late_withdrawal')
+);
+
+ALTER TABLE tasks
+ADD COLUMN min_reliability_score INT NOT NULL DEFAULT 0 CHECK (
+    min_reliability_score BETWEEN 0 AND 100
+);
+
+-- the no-show sweep looks for accepted applications that have not been settled yet
+CREATE INDEX IF NOT EXISTS idx_user_tasks_unsettled ON user_tasks (task_id)
+WHERE
+    status = 'accepted' AND attendance_outcome IS NULL;
+
+COMMIT;
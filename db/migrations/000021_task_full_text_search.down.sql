@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_categories_name_search;
+DROP INDEX IF EXISTS idx_tasks_search_vector;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS immutable_array_to_string(TEXT[], TEXT);
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+-- array_to_string is only STABLE, generated columns need an IMMUTABLE expression
+CREATE OR REPLACE FUNCTION immutable_array_to_string(TEXT[], TEXT)
+RETURNS TEXT LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+    SELECT array_to_string($1, $2)
+$$;
+
+-- the category name lives in another table, so it is matched and ranked at query time
+ALTER TABLE tasks
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(immutable_array_to_string(required_skills, ' '), '')), 'C') ||
+    setweight(to_tsvector('english', COALESCE(formatted_address, '')), 'D')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON tasks USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_categories_name_search ON categories USING GIN (to_tsvector('english', name));
+
+COMMIT;
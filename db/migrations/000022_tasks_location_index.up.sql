@@ -0,0 +1,6 @@
+BEGIN;
+
+-- viewport queries filter with the bounding box operator
+CREATE INDEX IF NOT EXISTS idx_tasks_location ON tasks USING GIST (location);
+
+COMMIT;
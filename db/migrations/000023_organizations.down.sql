@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_tasks_organization_id;
+
+ALTER TABLE tasks
+DROP CONSTRAINT IF EXISTS fk_organization_tasks,
+DROP COLUMN IF EXISTS organization_id;
+
+DROP TABLE IF EXISTS organization_members;
+
+DROP TABLE IF EXISTS organizations;
+
+COMMIT;
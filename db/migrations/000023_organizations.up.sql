@@ -0,0 +1,44 @@
+BEGIN;
+
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    website VARCHAR(255) NOT NULL DEFAULT '',
+    logo_url VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+create trigger set_timestamp_organizations before
+update
+    on organizations for each row execute procedure trigger_set_updated_at_timestamp();
+
+CREATE TABLE organization_members (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    organization_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'manager', 'member')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_organization_members_organization_id_user_id UNIQUE (organization_id, user_id),
+    CONSTRAINT fk_organization_organization_members FOREIGN KEY (organization_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_user_organization_members FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+
+create trigger set_timestamp_organization_members before
+update
+    on organization_members for each row execute procedure trigger_set_updated_at_timestamp();
+
+-- tasks posted on behalf of an organization are still created by one of its staff
+ALTER TABLE tasks
+ADD COLUMN organization_id UUID NULL,
+ADD CONSTRAINT fk_organization_tasks FOREIGN KEY (organization_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_organization_id ON tasks (organization_id)
+WHERE
+    organization_id IS NOT NULL;
+
+COMMIT;
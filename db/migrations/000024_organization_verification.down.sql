@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP TABLE IF EXISTS organization_verification_documents;
+
+DROP TABLE IF EXISTS organization_verification_requests;
+
+ALTER TABLE organizations
+DROP COLUMN IF EXISTS verified_at;
+
+COMMIT;
@@ -0,0 +1,41 @@
+BEGIN;
+
+ALTER TABLE organizations
+ADD COLUMN verified_at TIMESTAMPTZ NULL;
+
+CREATE TABLE organization_verification_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    organization_id UUID NOT NULL,
+    requested_by_user_id UUID NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    note TEXT NOT NULL DEFAULT '',
+    review_note TEXT NOT NULL DEFAULT '',
+    reviewed_by_user_id UUID NULL,
+    reviewed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_organization_verification_requests FOREIGN KEY (organization_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_user_verification_requests_requested_by FOREIGN KEY (requested_by_user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT fk_user_verification_requests_reviewed_by FOREIGN KEY (reviewed_by_user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+-- an organization waits on at most one review at a time
+CREATE UNIQUE INDEX IF NOT EXISTS uq_organization_verification_requests_pending ON organization_verification_requests (organization_id)
+WHERE
+    status = 'pending';
+
+create trigger set_timestamp_organization_verification_requests before
+update
+    on organization_verification_requests for each row execute procedure trigger_set_updated_at_timestamp();
+
+CREATE TABLE organization_verification_documents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    request_id UUID NOT NULL,
+    mime_type VARCHAR NOT NULL,
+    object_key VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_verification_request_documents FOREIGN KEY (request_id) REFERENCES organization_verification_requests (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+COMMIT;
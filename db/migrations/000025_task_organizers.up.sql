@@ -0,0 +1,28 @@
+BEGIN;
+
+-- co-organizers help the task's owners run it, within the permissions they were given.
+-- An invitation only takes effect once the invited user accepts it.
+CREATE TABLE task_organizers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    task_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    invited_by_user_id UUID NULL,
+    can_edit_details BOOLEAN NOT NULL DEFAULT FALSE,
+    can_manage_volunteers BOOLEAN NOT NULL DEFAULT FALSE,
+    can_message_volunteers BOOLEAN NOT NULL DEFAULT FALSE,
+    accepted_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_task_organizers_task_id_user_id UNIQUE (task_id, user_id),
+    CONSTRAINT fk_task_task_organizers FOREIGN KEY (task_id) REFERENCES tasks (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_user_task_organizers FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_inviter_task_organizers FOREIGN KEY (invited_by_user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_organizers_user_id ON task_organizers (user_id);
+
+create trigger set_timestamp_task_organizers before
+update
+    on task_organizers for each row execute procedure trigger_set_updated_at_timestamp();
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_tasks_required_skills;
+
+DROP TABLE IF EXISTS user_skills;
+
+DROP TABLE IF EXISTS skill_synonyms;
+
+DROP TABLE IF EXISTS skills;
+
+COMMIT;
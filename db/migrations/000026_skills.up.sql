@@ -0,0 +1,73 @@
+BEGIN;
+
+-- the skills tasks can ask for and volunteers can declare. Tasks keep the names of the
+-- skills they require in required_skills, synonyms are resolved to those names on save.
+CREATE TABLE skills (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_skills_name ON skills (LOWER(name));
+
+create trigger set_timestamp_skills before
+update
+    on skills for each row execute procedure trigger_set_updated_at_timestamp();
+
+CREATE TABLE skill_synonyms (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    skill_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_skill_skill_synonyms FOREIGN KEY (skill_id) REFERENCES skills (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_skill_synonyms_name ON skill_synonyms (LOWER(name));
+
+create trigger set_timestamp_skill_synonyms before
+update
+    on skill_synonyms for each row execute procedure trigger_set_updated_at_timestamp();
+
+CREATE TABLE user_skills (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL,
+    skill_id UUID NOT NULL,
+    proficiency VARCHAR(20) NULL CHECK (proficiency IN ('beginner', 'intermediate', 'expert')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_user_skills_user_id_skill_id UNIQUE (user_id, skill_id),
+    CONSTRAINT fk_user_user_skills FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_skill_user_skills FOREIGN KEY (skill_id) REFERENCES skills (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_skills_skill_id ON user_skills (skill_id);
+
+create trigger set_timestamp_user_skills before
+update
+    on user_skills for each row execute procedure trigger_set_updated_at_timestamp();
+
+-- seed the taxonomy with the skills tasks already ask for, and spell them the same way
+INSERT INTO
+    skills (name)
+SELECT DISTINCT ON (LOWER(TRIM(skill))) TRIM(skill)
+FROM tasks, unnest(required_skills) AS skill
+WHERE
+    TRIM(skill) <> ''
+ORDER BY LOWER(TRIM(skill)), TRIM(skill)
+ON CONFLICT DO NOTHING;
+
+UPDATE tasks
+SET
+    required_skills = ARRAY(
+        SELECT DISTINCT s.name
+        FROM unnest(tasks.required_skills) AS skill
+            JOIN skills s ON LOWER(s.name) = LOWER(TRIM(skill))
+    )
+WHERE
+    required_skills IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_required_skills ON tasks USING GIN (required_skills);
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP TABLE IF EXISTS availability_exceptions;
+
+DROP TABLE IF EXISTS availability_windows;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS time_zone;
+
+COMMIT;
@@ -0,0 +1,50 @@
+BEGIN;
+
+-- availability windows and exceptions are in the user's own time zone
+ALTER TABLE users
+ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- the hours a volunteer is usually free on a day of the week, 0 being Sunday
+CREATE TABLE availability_windows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time VARCHAR(5) NOT NULL,
+    end_time VARCHAR(5) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_availability_windows_times CHECK (start_time::time < end_time::time),
+    CONSTRAINT fk_user_availability_windows FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_availability_windows_user_id ON availability_windows (user_id);
+
+create trigger set_timestamp_availability_windows before
+update
+    on availability_windows for each row execute procedure trigger_set_updated_at_timestamp();
+
+-- exceptions replace the weekly windows on their date: the volunteer is either away all day
+-- or only free in the hours of the exceptions marked available
+CREATE TABLE availability_exceptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL,
+    date DATE NOT NULL,
+    available BOOLEAN NOT NULL DEFAULT FALSE,
+    start_time VARCHAR(5) NULL,
+    end_time VARCHAR(5) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_availability_exceptions_times CHECK (
+        (available AND start_time::time < end_time::time)
+        OR (NOT available AND start_time IS NULL AND end_time IS NULL)
+    ),
+    CONSTRAINT fk_user_availability_exceptions FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_availability_exceptions_user_id_date ON availability_exceptions (user_id, date);
+
+create trigger set_timestamp_availability_exceptions before
+update
+    on availability_exceptions for each row execute procedure trigger_set_updated_at_timestamp();
+
+COMMIT;
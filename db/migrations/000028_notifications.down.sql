@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS push_subscriptions;
+
+DROP TABLE IF EXISTS notification_deliveries;
+
+DROP TABLE IF EXISTS notifications;
+
+COMMIT;
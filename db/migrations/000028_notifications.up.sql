@@ -0,0 +1,60 @@
+BEGIN;
+
+-- a notification is what one user is told about an event, rendered once for every channel
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    task_id UUID NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    read_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user_notifications FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_task_notifications FOREIGN KEY (task_id) REFERENCES tasks (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_created_at ON notifications (user_id, created_at);
+
+create trigger set_timestamp_notifications before
+update
+    on notifications for each row execute procedure trigger_set_updated_at_timestamp();
+
+-- one delivery per notification and channel, recording how sending it went
+CREATE TABLE notification_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    notification_id UUID NOT NULL,
+    channel VARCHAR(16) NOT NULL CHECK (channel IN ('sms', 'email', 'in_app', 'push')),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+    error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_notification_deliveries_notification_id_channel UNIQUE (notification_id, channel),
+    CONSTRAINT fk_notification_notification_deliveries FOREIGN KEY (notification_id) REFERENCES notifications (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+create trigger set_timestamp_notification_deliveries before
+update
+    on notification_deliveries for each row execute procedure trigger_set_updated_at_timestamp();
+
+-- the browsers a user allowed to show web push notifications
+CREATE TABLE push_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh TEXT NOT NULL,
+    auth TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user_push_subscriptions FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user_id ON push_subscriptions (user_id);
+
+create trigger set_timestamp_push_subscriptions before
+update
+    on push_subscriptions for each row execute procedure trigger_set_updated_at_timestamp();
+
+COMMIT;
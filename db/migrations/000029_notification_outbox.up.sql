@@ -0,0 +1,25 @@
+BEGIN;
+
+-- deliveries waiting to be sent by the worker. Jobs are written in the same transaction as
+-- the change they notify of and deleted once sent, those that keep failing are kept as dead.
+CREATE TABLE notification_outbox_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    delivery_id UUID NOT NULL UNIQUE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_notification_delivery_notification_outbox_jobs FOREIGN KEY (delivery_id) REFERENCES notification_deliveries (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_jobs_run_at ON notification_outbox_jobs (run_at)
+WHERE
+    status = 'pending';
+
+create trigger set_timestamp_notification_outbox_jobs before
+update
+    on notification_outbox_jobs for each row execute procedure trigger_set_updated_at_timestamp();
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_notifications_user_id_event_type_created_at;
+
+DROP TABLE IF EXISTS notification_preferences;
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+-- how a user wants to be notified. Users without preferences are alerted of every task
+-- within 10 km in the inbox and by text at any time, empty categories and skills mean all
+-- of them and events missing from event_channels go out on their default channels.
+CREATE TABLE notification_preferences (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL UNIQUE,
+    alert_radius_km INT NOT NULL DEFAULT 10 CHECK (alert_radius_km BETWEEN 1 AND 100),
+    category_ids UUID[] NOT NULL DEFAULT '{}',
+    skills TEXT[] NOT NULL DEFAULT '{}',
+    event_channels JSONB NOT NULL DEFAULT '{}',
+    quiet_hours_start VARCHAR(5) NULL,
+    quiet_hours_end VARCHAR(5) NULL,
+    daily_cap INT NULL CHECK (daily_cap > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_notification_preferences_quiet_hours CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL)),
+    CONSTRAINT fk_user_notification_preferences FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+create trigger set_timestamp_notification_preferences before
+update
+    on notification_preferences for each row execute procedure trigger_set_updated_at_timestamp();
+
+-- daily caps count the alerts a user got today
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_event_type_created_at ON notifications (user_id, event_type, created_at);
+
+COMMIT;
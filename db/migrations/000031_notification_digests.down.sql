@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP TABLE IF EXISTS digest_tasks;
+
+DROP TABLE IF EXISTS digests;
+
+ALTER TABLE notification_preferences
+DROP COLUMN IF EXISTS last_digest_at,
+DROP COLUMN IF EXISTS digest_frequency;
+
+COMMIT;
@@ -0,0 +1,43 @@
+BEGIN;
+
+-- users in digest mode get a daily or weekly email of new nearby tasks instead of an alert
+-- per task
+ALTER TABLE notification_preferences
+ADD COLUMN digest_frequency VARCHAR(16) NOT NULL DEFAULT 'off' CHECK (digest_frequency IN ('off', 'daily', 'weekly')),
+ADD COLUMN last_digest_at TIMESTAMPTZ NULL;
+
+CREATE TABLE digests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    user_id UUID NOT NULL,
+    frequency VARCHAR(16) NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user_digests FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_digests_user_id ON digests (user_id);
+
+create trigger set_timestamp_digests before
+update
+    on digests for each row execute procedure trigger_set_updated_at_timestamp();
+
+-- the tasks a digest included, a task is only ever sent to a user in one digest
+CREATE TABLE digest_tasks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+    digest_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    task_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_digest_tasks_user_id_task_id UNIQUE (user_id, task_id),
+    CONSTRAINT fk_digest_digest_tasks FOREIGN KEY (digest_id) REFERENCES digests (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_user_digest_tasks FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_task_digest_tasks FOREIGN KEY (task_id) REFERENCES tasks (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+create trigger set_timestamp_digest_tasks before
+update
+    on digest_tasks for each row execute procedure trigger_set_updated_at_timestamp();
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_notifications_user_id_unread;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+-- the unread count of the in-app inbox
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+COMMIT;
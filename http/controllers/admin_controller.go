@@ -1,14 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"rashikzaman/api/models"
 	"rashikzaman/api/services"
 	"rashikzaman/api/utils"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/uptrace/bun"
 )
 
 func (ac *Controller) GetAdmin(c *gin.Context) {
@@ -17,16 +20,31 @@ func (ac *Controller) GetAdmin(c *gin.Context) {
 }
 
 func (ac *Controller) FetchTasksForAdmin(c *gin.Context) {
-	pagination := utils.PaginationConfigFromRequest(c)
+	ac.respondWithTasks(c, ac.paginationFromRequest(c), services.Filter{})
+}
+
+func (ac *Controller) FetchUsersForAdmin(c *gin.Context) {
+	pagination := ac.paginationFromRequest(c)
+
+	err := pagination.Validate(services.UserListSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 
-	tasks, count, err := services.FetchTasks(
+		return
+	}
+
+	users, count, err := services.FetchUsersForAdmin(
 		c, ac.App.DB,
 		models.QueryParam{
-			Pagination: utils.PaginationConfigFromRequest(c),
-			Relations:  []string{"User", "Category", "Media", "SubscribedUsers"},
+			Pagination: pagination,
 		},
-		services.Filter{},
 	)
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrInvalidCursor.Error()})
+
+		return
+	}
+
 	if err != nil {
 		fmt.Println(err)
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -34,26 +52,7 @@ func (ac *Controller) FetchTasksForAdmin(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, struct {
-		Count      int         `json:"count"`
-		PageNumber int         `json:"pageNumber"`
-		Records    interface{} `json:"records"`
-	}{
-		Count:      count,
-		PageNumber: pagination.Page,
-		Records:    tasks,
-	})
-}
-
-func (ac *Controller) FetchUsersForAdmin(c *gin.Context) {
-	pagination := utils.PaginationConfigFromRequest(c)
-
-	tasks, count, err := services.FetchUsersForAdmin(
-		c, ac.App.DB,
-		models.QueryParam{
-			Pagination: utils.PaginationConfigFromRequest(c),
-		},
-	)
+	nextCursor, err := services.NextUsersCursor(pagination, users)
 	if err != nil {
 		fmt.Println(err)
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -61,15 +60,7 @@ func (ac *Controller) FetchUsersForAdmin(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, struct {
-		Count      int         `json:"count"`
-		PageNumber int         `json:"pageNumber"`
-		Records    interface{} `json:"records"`
-	}{
-		Count:      count,
-		PageNumber: pagination.Page,
-		Records:    tasks,
-	})
+	c.JSON(http.StatusOK, newListResponse(pagination, count, nextCursor, users))
 }
 
 func (ac *Controller) ApplyActionToUser(c *gin.Context) {
@@ -117,3 +108,85 @@ func (ac *Controller) ApplyActionToTask(c *gin.Context) {
 
 	c.Status(http.StatusOK)
 }
+
+// FetchOrganizationsForReview is the queue of organizations waiting for verification.
+func (ac *Controller) FetchOrganizationsForReview(c *gin.Context) {
+	pagination := ac.paginationFromRequest(c)
+
+	statuses := utils.DeleteEmptyFromSlice(strings.Split(c.Query("statuses"), ","))
+	for _, status := range statuses {
+		if status != models.VerificationStatusPending && status != models.VerificationStatusApproved &&
+			status != models.VerificationStatusRejected {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + status})
+
+			return
+		}
+	}
+
+	requests, count, err := services.FetchVerificationRequests(
+		c, ac.App.DB, models.QueryParam{Pagination: pagination},
+		ac.App.Config.GetAWSAccessKey(), ac.App.Config.GetAWSSecretAccessKey(), statuses...,
+	)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newListResponse(pagination, count, "", requests))
+}
+
+// ApplyActionToOrganization approves or rejects the organization's pending verification
+// request, or revokes its verification.
+func (ac *Controller) ApplyActionToOrganization(c *gin.Context) {
+	body := struct {
+		Note string `json:"note"`
+	}{}
+
+	// the note is optional, so is the body
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	user := GetUser(c)
+
+	var organization *models.Organization
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		organization, err = services.ReviewOrganizationVerification(c, tx, organizationID, user.ID, c.Param("action"), body.Note)
+		return err
+	})
+
+	if errors.Is(err, services.ErrInvalidVerificationAction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrNoPendingVerification) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
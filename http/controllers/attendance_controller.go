@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+	"github.com/uptrace/bun"
+)
+
+const attendanceQRSize = 512
+
+// CheckIn checks the current user in to a task they were accepted for. The body carries the
+// token scanned from the task's attendance QR code, or the volunteer's location on tasks
+// with a check-in radius.
+func (ac *Controller) CheckIn(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	shiftID, err := shiftIDFromQuery(c)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	body := struct {
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		Token     string   `json:"token"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	var location *services.CheckInLocation
+	if body.Latitude != nil && body.Longitude != nil {
+		location = &services.CheckInLocation{Latitude: *body.Latitude, Longitude: *body.Longitude}
+	}
+
+	user := GetUser(c)
+
+	var userTask *models.UserTask
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		userTask, err = services.CheckIn(
+			c, tx, taskID, user.ID, shiftID, location, body.Token, ac.App.Config.GetSessionSecret(),
+		)
+		return err
+	})
+
+	if handleAttendanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, userTask)
+}
+
+func (ac *Controller) CheckOut(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	shiftID, err := shiftIDFromQuery(c)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	user := GetUser(c)
+
+	var userTask *models.UserTask
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		userTask, err = services.CheckOut(c, tx, taskID, user.ID, shiftID)
+		return err
+	})
+
+	if handleAttendanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, userTask)
+}
+
+// RecordAttendance lets the organizer check a volunteer in or out on their behalf.
+func (ac *Controller) RecordAttendance(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	volunteerID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	action := c.Param("action")
+	if action != "check-in" && action != "check-out" {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	shiftID, err := shiftIDFromQuery(c)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if !ac.isTaskManager(c, taskID) {
+		return
+	}
+
+	var userTask *models.UserTask
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		if action == "check-out" {
+			userTask, err = services.CheckOut(c, tx, taskID, volunteerID, shiftID)
+		} else {
+			userTask, err = services.CheckInOnBehalf(c, tx, taskID, volunteerID, shiftID)
+		}
+		return err
+	})
+
+	if handleAttendanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, userTask)
+}
+
+// CorrectHours lets the organizer override the minutes a volunteer is credited with.
+func (ac *Controller) CorrectHours(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	volunteerID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	shiftID, err := shiftIDFromQuery(c)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	body := struct {
+		Minutes *int   `json:"minutes" binding:"required"`
+		Note    string `json:"note"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if !ac.isTaskManager(c, taskID) {
+		return
+	}
+
+	user := GetUser(c)
+
+	var userTask *models.UserTask
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		userTask, err = services.CorrectHours(c, tx, taskID, volunteerID, shiftID, *body.Minutes, body.Note, user.ID)
+		return err
+	})
+
+	if handleAttendanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, userTask)
+}
+
+// FetchMyHours returns the current user's hours ledger by category and month. Months are
+// taken in the time_zone query parameter, UTC by default.
+func (ac *Controller) FetchMyHours(c *gin.Context) {
+	location, err := services.LoadTimeZone(c.DefaultQuery("time_zone", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidTimeZone.Error()})
+
+		return
+	}
+
+	user := GetUser(c)
+
+	summary, err := services.FetchHoursSummary(c, ac.App.DB, user.ID, location)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// FetchAttendanceQR renders the task's current attendance token as a PNG QR code for the
+// organizer to display on site. The code rotates, so clients should refetch it before the
+// time in the X-Attendance-Token-Expires-At header.
+func (ac *Controller) FetchAttendanceQR(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if !ac.isTaskManager(c, taskID) {
+		return
+	}
+
+	token, expiresAt, err := services.IssueAttendanceToken(ac.App.Config.GetSessionSecret(), taskID, time.Now())
+	if errors.Is(err, services.ErrAttendanceTokensDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	png, err := qrcode.Encode(token, qrcode.Medium, attendanceQRSize)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Attendance-Token-Expires-At", expiresAt.UTC().Format(time.RFC3339))
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// isTaskManager aborts the request unless the current user may manage the task's
+// volunteers.
+func (ac *Controller) isTaskManager(c *gin.Context, taskID uuid.UUID) bool {
+	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusNotFound)
+
+		return false
+	}
+
+	if !ac.hasTaskPermission(c, task, GetUser(c), models.TaskPermissionManageVolunteers) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return false
+	}
+
+	return true
+}
+
+// handleAttendanceError writes the response for a failed attendance action and reports
+// whether there was an error.
+func handleAttendanceError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrApplicationNotFound), errors.Is(err, services.ErrShiftNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotAttending), errors.Is(err, services.ErrOutsideGeofence),
+		errors.Is(err, services.ErrInvalidAttendanceToken), errors.Is(err, services.ErrAttendanceTokenExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyCheckedIn), errors.Is(err, services.ErrAlreadyCheckedOut),
+		errors.Is(err, services.ErrNotCheckedIn), errors.Is(err, services.ErrCheckInNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLocationRequired), errors.Is(err, services.ErrInvalidHours),
+		errors.Is(err, services.ErrAttendanceTokenRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+	}
+
+	return true
+}
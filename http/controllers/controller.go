@@ -3,6 +3,7 @@ package controllers
 import (
 	"rashikzaman/api/application"
 	"rashikzaman/api/models"
+	"rashikzaman/api/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +12,29 @@ type Controller struct {
 	App *application.Application
 }
 
+// listResponse is the body of paginated listings. Count is left out when the client asked
+// not to count and NextCursor is only set in cursor mode while more records follow.
+type listResponse struct {
+	Count      *int        `json:"count,omitempty"`
+	PageNumber int         `json:"pageNumber,omitempty"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	Records    interface{} `json:"records"`
+}
+
+func newListResponse(pagination utils.PaginationConfig, count int, nextCursor string, records interface{}) listResponse {
+	response := listResponse{NextCursor: nextCursor, Records: records}
+
+	if !pagination.SkipCount {
+		response.Count = &count
+	}
+
+	if !pagination.UseCursor {
+		response.PageNumber = pagination.Page
+	}
+
+	return response
+}
+
 func GetUser(c *gin.Context) *models.User {
 	userValue, _ := c.Get("user")
 
@@ -19,3 +43,12 @@ func GetUser(c *gin.Context) *models.User {
 	return user
 
 }
+
+// paginationFromRequest reads the pagination of a listing from the query string, along with
+// the secret its cursors are signed with.
+func (ac *Controller) paginationFromRequest(c *gin.Context) utils.PaginationConfig {
+	pagination := utils.PaginationConfigFromRequest(c)
+	pagination.CursorSecret = ac.App.Config.GetSessionSecret()
+
+	return pagination
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"rashikzaman/api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// pushSubscriptionRequest is a browser's push subscription as given by
+// PushSubscription.toJSON().
+type pushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// FetchPushPublicKey returns the VAPID public key browsers subscribe to push notifications
+// with.
+func (ac *Controller) FetchPushPublicKey(c *gin.Context) {
+	publicKey := ac.App.Config.GetVAPIDPublicKey()
+	if publicKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "push notifications are not configured"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"public_key": publicKey})
+}
+
+func (ac *Controller) SubscribeToPush(c *gin.Context) {
+	body := pushSubscriptionRequest{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	subscription := &models.PushSubscription{
+		Endpoint: body.Endpoint,
+		P256dh:   body.Keys.P256dh,
+		Auth:     body.Keys.Auth,
+	}
+
+	err := services.SavePushSubscription(c, ac.App.DB, GetUser(c).ID, subscription)
+	if errors.Is(err, services.ErrInvalidPushSubscription) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+func (ac *Controller) UnsubscribeFromPush(c *gin.Context) {
+	body := pushSubscriptionRequest{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	err := services.DeletePushSubscription(c, ac.App.DB, GetUser(c).ID, body.Endpoint)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// inboxResponse is a page of the in-app inbox along with how many of the user's
+// notifications are unread.
+type inboxResponse struct {
+	listResponse
+	UnreadCount int `json:"unreadCount"`
+}
+
+type markNotificationRequest struct {
+	Read *bool `json:"read" binding:"required"`
+}
+
+// FetchMyNotifications lists the user's in-app inbox, newest first, always paginated with
+// a cursor. unread=true lists only the unread notifications.
+func (ac *Controller) FetchMyNotifications(c *gin.Context) {
+	user := GetUser(c)
+
+	pagination := ac.paginationFromRequest(c)
+	pagination.UseCursor = true
+
+	notifications, count, err := services.FetchInbox(
+		c, ac.App.DB, user.ID, models.QueryParam{Pagination: pagination}, c.Query("unread") == "true",
+	)
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrInvalidCursor.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	nextCursor, err := services.NextInboxCursor(pagination, notifications)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	unreadCount, err := services.CountUnreadNotifications(c, ac.App.DB, user.ID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, inboxResponse{
+		listResponse: newListResponse(pagination, count, nextCursor, notifications),
+		UnreadCount:  unreadCount,
+	})
+}
+
+// MarkMyNotification marks a notification of the user's inbox read or unread.
+func (ac *Controller) MarkMyNotification(c *gin.Context) {
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	body := markNotificationRequest{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	notification, err := services.MarkNotificationRead(c, ac.App.DB, GetUser(c).ID, notificationID, *body.Read)
+	if errors.Is(err, services.ErrNotificationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+func (ac *Controller) MarkAllMyNotificationsRead(c *gin.Context) {
+	marked, err := services.MarkAllNotificationsRead(c, ac.App.DB, GetUser(c).ID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+func (ac *Controller) CreateOrganization(c *gin.Context) {
+	organization := &models.Organization{}
+
+	if err := c.ShouldBindJSON(&organization); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	user := GetUser(c)
+
+	err := models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.CreateOrganization(c, tx, organization, user.ID)
+	})
+
+	if errors.Is(err, services.ErrInvalidOrganization) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, organization)
+}
+
+func (ac *Controller) FetchMyOrganizations(c *gin.Context) {
+	memberships, err := services.FetchOrganizationsOfUser(c, ac.App.DB, GetUser(c).ID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
+
+// FetchOrganization is the organization's profile. Its staff is only shown to its members.
+func (ac *Controller) FetchOrganization(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	organization, err := services.FetchOrganizationByID(c, ac.App.DB, organizationID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusNotFound)
+
+		return
+	}
+
+	_, err = services.FetchOrganizationMember(c, ac.App.DB, organizationID, GetUser(c).ID)
+	if err != nil {
+		organization.Members = nil
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// FetchOrganizationTasks lists the organization's published upcoming tasks.
+func (ac *Controller) FetchOrganizationTasks(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	ac.respondWithTasks(c, ac.paginationFromRequest(c), services.Filter{
+		OrganizationID:         organizationID,
+		ApplyBlockFilter:       true,
+		Statuses:               []string{models.TaskStatusOpen, models.TaskStatusFilled, models.TaskStatusInProgress},
+		HidePast:               true,
+		HideRecurringTemplates: true,
+	})
+}
+
+func (ac *Controller) UpdateOrganization(c *gin.Context) {
+	organizationBody := models.Organization{}
+
+	if err := c.ShouldBindJSON(&organizationBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if !ac.hasOrganizationRole(c, organizationID, models.OrganizationRoleOwner, models.OrganizationRoleManager) {
+		return
+	}
+
+	organization, err := services.FetchOrganizationByID(c, ac.App.DB, organizationID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusNotFound)
+
+		return
+	}
+
+	organization, err = services.UpdateOrganization(c, ac.App.DB, *organization, organizationBody)
+	if errors.Is(err, services.ErrInvalidOrganization) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+func (ac *Controller) AddOrganizationMember(c *gin.Context) {
+	body := struct {
+		Email string `json:"email" binding:"required"`
+		Role  string `json:"role" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if !ac.hasOrganizationRole(c, organizationID, models.OrganizationRoleOwner) {
+		return
+	}
+
+	member, err := services.AddOrganizationMember(c, ac.App.DB, organizationID, body.Email, body.Role)
+	if handleOrganizationMemberError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (ac *Controller) ChangeOrganizationMemberRole(c *gin.Context) {
+	body := struct {
+		Role string `json:"role" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if !ac.hasOrganizationRole(c, organizationID, models.OrganizationRoleOwner) {
+		return
+	}
+
+	var member *models.OrganizationMember
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		member, err = services.ChangeOrganizationMemberRole(c, tx, organizationID, userID, body.Role)
+		return err
+	})
+	if handleOrganizationMemberError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveOrganizationMember takes a member out of the organization. Owners remove others,
+// anyone can leave on their own.
+func (ac *Controller) RemoveOrganizationMember(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if userID != GetUser(c).ID && !ac.hasOrganizationRole(c, organizationID, models.OrganizationRoleOwner) {
+		return
+	}
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.RemoveOrganizationMember(c, tx, organizationID, userID)
+	})
+	if handleOrganizationMemberError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// RequestOrganizationVerification submits the organization for review by the admins.
+func (ac *Controller) RequestOrganizationVerification(c *gin.Context) {
+	request := &models.OrganizationVerificationRequest{}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if !ac.hasOrganizationRole(c, organizationID, models.OrganizationRoleOwner, models.OrganizationRoleManager) {
+		return
+	}
+
+	user := GetUser(c)
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.RequestOrganizationVerification(
+			c, tx, organizationID, user.ID, request, ac.App.Config.GetAWSAccessKey(), ac.App.Config.GetAWSSecretAccessKey(),
+		)
+	})
+
+	if errors.Is(err, services.ErrVerificationDocumentsRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrOrganizationAlreadyVerified) || errors.Is(err, services.ErrVerificationPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrMediaStorageUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// FetchOrganizationVerification shows the organization's members where its latest
+// verification request stands.
+func (ac *Controller) FetchOrganizationVerification(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	if !ac.hasOrganizationRole(
+		c, organizationID,
+		models.OrganizationRoleOwner, models.OrganizationRoleManager, models.OrganizationRoleMember,
+	) {
+		return
+	}
+
+	request, err := services.FetchLatestVerificationRequest(c, ac.App.DB, organizationID)
+	if errors.Is(err, services.ErrOrganizationVerificationAbsent) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// hasOrganizationRole aborts the request unless the current user has one of the roles in
+// the organization.
+func (ac *Controller) hasOrganizationRole(c *gin.Context, organizationID uuid.UUID, roles ...string) bool {
+	member, err := services.FetchOrganizationMember(c, ac.App.DB, organizationID, GetUser(c).ID)
+	if errors.Is(err, services.ErrNotOrganizationMember) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return false
+	}
+
+	for _, role := range roles {
+		if member.Role == role {
+			return true
+		}
+	}
+
+	c.AbortWithStatus(http.StatusUnauthorized)
+
+	return false
+}
+
+// handleOrganizationMemberError writes the response for a failed change to the members of
+// an organization and reports whether there was an error.
+func handleOrganizationMemberError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrInvalidOrganizationRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotOrganizationMember), errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyOrganizationMember), errors.Is(err, services.ErrLastOrganizationOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+	}
+
+	return true
+}
@@ -1,16 +1,21 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"rashikzaman/api/models"
 	"rashikzaman/api/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
 )
 
+// FetchSkills lists the skill taxonomy, narrowed down by the search query parameter.
 func (ac *Controller) FetchSkills(c *gin.Context) {
 	skills, err := services.FetchSkills(
-		c, ac.App.DB)
+		c, ac.App.DB, c.Query("search"))
 	if err != nil {
 		fmt.Println(err)
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -20,3 +25,190 @@ func (ac *Controller) FetchSkills(c *gin.Context) {
 
 	c.JSON(http.StatusOK, skills)
 }
+
+func (ac *Controller) CreateSkill(c *gin.Context) {
+	skill := &models.Skill{}
+
+	if err := c.ShouldBindJSON(&skill); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	err := models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.CreateSkill(c, tx, skill)
+	})
+	if handleSkillError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, skill)
+}
+
+// RenameSkill renames a skill of the taxonomy, and the tasks requiring it along with it.
+func (ac *Controller) RenameSkill(c *gin.Context) {
+	body := struct {
+		Name string `json:"name" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	skillID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	var skill *models.Skill
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		skill, err = services.RenameSkill(c, tx, skillID, body.Name)
+		return err
+	})
+	if handleSkillError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, skill)
+}
+
+func (ac *Controller) DeleteSkill(c *gin.Context) {
+	skillID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.DeleteSkill(c, tx, skillID)
+	})
+	if handleSkillError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (ac *Controller) AddSkillSynonym(c *gin.Context) {
+	body := struct {
+		Name string `json:"name" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	skillID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	var synonym *models.SkillSynonym
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		synonym, err = services.AddSkillSynonym(c, tx, skillID, body.Name)
+		return err
+	})
+	if handleSkillError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, synonym)
+}
+
+func (ac *Controller) RemoveSkillSynonym(c *gin.Context) {
+	skillID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	synonymID, err := uuid.Parse(c.Param("synonym_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.RemoveSkillSynonym(c, tx, skillID, synonymID)
+	})
+	if handleSkillError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// FetchMySkills lists the skills the current user declared.
+func (ac *Controller) FetchMySkills(c *gin.Context) {
+	userSkills, err := services.FetchUserSkills(c, ac.App.DB, GetUser(c).ID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, userSkills)
+}
+
+// UpdateMySkills replaces the skills the current user declared, given by name or synonym
+// with an optional proficiency.
+func (ac *Controller) UpdateMySkills(c *gin.Context) {
+	inputs := []services.UserSkillInput{}
+
+	if err := c.ShouldBindJSON(&inputs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	var userSkills []models.UserSkill
+
+	err := models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		userSkills, err = services.SetUserSkills(c, tx, GetUser(c).ID, inputs)
+		return err
+	})
+	if handleSkillError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, userSkills)
+}
+
+// handleSkillError writes the response for a failed change to the skill taxonomy or to a
+// user's skills and reports whether there was an error.
+func handleSkillError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrInvalidSkill),
+		errors.Is(err, services.ErrUnknownSkill),
+		errors.Is(err, services.ErrInvalidSkillProficiency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSkillNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSkillExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+	}
+
+	return true
+}
@@ -1,14 +1,17 @@
 package controllers
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"rashikzaman/api/constants"
 	"rashikzaman/api/models"
 	"rashikzaman/api/services"
 	"rashikzaman/api/utils"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,7 +19,8 @@ import (
 )
 
 func (ac *Controller) CreateTask(c *gin.Context) {
-	task := &models.Task{}
+	// volunteers are accepted straight away unless the organizer opts into screening them
+	task := &models.Task{AutoAccept: true}
 
 	if err := c.ShouldBindJSON(&task); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -32,24 +36,45 @@ func (ac *Controller) CreateTask(c *gin.Context) {
 			fmt.Println(err)
 			return err
 		}
-		return err
+
+		// drafts are announced once they are published, see ChangeTaskStatus
+		if task.Status == models.TaskStatusOpen {
+			ac.notifyNearbyUsers(c, tx, task)
+		}
+
+		return nil
 	})
 
+	if errors.Is(err, services.ErrInvalidTaskStatus) || isTaskValidationError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrNotOrganizationManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err != nil {
 		fmt.Println(err)
 		c.Status(http.StatusInternalServerError)
 		return
 	}
 
-	_ = SendSMS(c, ac.App.DB, float32(task.Latitude), float32(task.Longitude), ac.App.Config.GetTwilioAccountSID(), ac.App.Config.GetTwilioPhoneNumber(), ac.App.Config.GetTwilioAuthToken(), task)
-
 	c.Status(http.StatusCreated)
 }
 
+// taskUpdateRequest is the body of a task update. AutoAccept defaults to true on new tasks,
+// so leaving it out keeps the task's setting rather than switching it to manual review.
+type taskUpdateRequest struct {
+	models.Task
+	AutoAccept *bool `json:"auto_accept"`
+}
+
 func (ac *Controller) UpdateTask(c *gin.Context) {
-	task := &models.Task{}
+	body := taskUpdateRequest{}
 
-	if err := c.ShouldBindJSON(&task); err != nil {
+	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 
 		return
@@ -62,6 +87,14 @@ func (ac *Controller) UpdateTask(c *gin.Context) {
 		return
 	}
 
+	// on an occurrence of a recurring task, "future" also updates every later occurrence
+	scope := c.DefaultQuery("scope", "this")
+	if scope != "this" && scope != "future" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope: " + scope})
+
+		return
+	}
+
 	user := GetUser(c)
 
 	existingTask, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
@@ -71,8 +104,10 @@ func (ac *Controller) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	if user.ID != existingTask.UserID {
+	if !ac.hasTaskPermission(c, existingTask, user, models.TaskPermissionEditDetails) {
 		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
 	}
 
 	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
@@ -81,10 +116,51 @@ func (ac *Controller) UpdateTask(c *gin.Context) {
 			return err
 		}
 
-		_, err = services.UpdateTask(c, tx, existingTask, *task)
-		return err
+		task := body.Task
+		task.AutoAccept = existingTask.AutoAccept
+
+		if body.AutoAccept != nil {
+			task.AutoAccept = *body.AutoAccept
+		}
+
+		var promoted []*models.UserTask
+
+		if scope == "future" {
+			promoted, err = services.UpdateFutureOccurrences(c, tx, user.ID, existingTask, task)
+		} else {
+			_, promoted, err = services.UpdateTask(c, tx, existingTask, task)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// volunteers moved off the waitlist by a raised capacity hear about it
+		for _, userTask := range promoted {
+			err = ac.App.Notifications().NotifyApplicant(c, tx, services.NotificationEventPromotedFromWaitlist, userTask)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 
+	if errors.Is(err, services.ErrTaskNotEditable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrNotTaskOrganizer) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if isTaskValidationError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err != nil {
 		fmt.Println(err)
 		c.Status(http.StatusInternalServerError)
@@ -95,110 +171,110 @@ func (ac *Controller) UpdateTask(c *gin.Context) {
 }
 
 func (ac *Controller) FetchTasks(c *gin.Context) {
-	pagination := utils.PaginationConfigFromRequest(c)
-	categoryIDs := c.Query("category_ids")
-	skills := c.Query("skills")
-	categoryIDArray := []string{}
-	skillsArray := []string{}
+	filter, ok := ac.publicTaskFilterFromRequest(c)
+	if !ok {
+		return
+	}
 
-	if categoryIDs != "" {
-		categoryIDArray = strings.Split(categoryIDs, ",")
+	ac.respondWithTasks(c, ac.paginationFromRequest(c), filter)
+}
+
+func (ac *Controller) FetchTasksCreatedByUser(c *gin.Context) {
+	user := GetUser(c)
+
+	statuses, ok := taskStatusesFromQuery(c)
+	if !ok {
+		return
 	}
 
-	if skills != "" {
-		skillsArray = strings.Split(skills, ",")
+	ac.respondWithTasks(c, ac.paginationFromRequest(c), services.Filter{
+		CreatedByUserID: user.ID,
+		Statuses:        statuses,
+	})
+}
+
+func (ac *Controller) FetchTasksSubscribedByUser(c *gin.Context) {
+	user := GetUser(c)
+
+	statuses, ok := taskStatusesFromQuery(c)
+	if !ok {
+		return
 	}
 
-	var (
-		longitude, latitude float32
-		subscribeUserId     uuid.UUID
-	)
+	ac.respondWithTasks(c, ac.paginationFromRequest(c), services.Filter{
+		SubscribedByUserID: user.ID,
+		ApplyBlockFilter:   true,
+		Statuses:           statuses,
+	})
+}
 
-	if c.Query("longitude") != "" {
-		lng, err := strconv.ParseFloat(c.Query("longitude"), 32)
-		if err != nil {
-			fmt.Println(err)
-			c.AbortWithStatus(http.StatusInternalServerError)
+// taskStatusesFromQuery reads the optional statuses query parameter. It responds with 400
+// and returns false when one of them is not a task status.
+func taskStatusesFromQuery(c *gin.Context) ([]string, bool) {
+	statuses := utils.DeleteEmptyFromSlice(strings.Split(c.Query("statuses"), ","))
 
-			return
-		}
+	for _, status := range statuses {
+		if !services.IsValidTaskStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + status})
 
-		longitude = float32(lng)
+			return nil, false
+		}
 	}
 
-	if c.Query("latitude") != "" {
-		lat, err := strconv.ParseFloat(c.Query("latitude"), 32)
-		if err != nil {
-			fmt.Println(err)
-			c.AbortWithStatus(http.StatusInternalServerError)
+	return statuses, true
+}
+
+// FetchRecommendedTasks returns the tasks picked for the current user, each with the
+// reasons it was picked. The limit query parameter caps their number.
+func (ac *Controller) FetchRecommendedTasks(c *gin.Context) {
+	limit := constants.RecommendationFeedSize
+
+	if c.Query("limit") != "" {
+		l, err := strconv.Atoi(c.Query("limit"))
+		if err != nil || l <= 0 || l > constants.RecommendationCandidates {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit: " + c.Query("limit")})
 
 			return
 		}
 
-		latitude = float32(lat)
-	}
-
-	if c.Query("is_subscribed") == "true" {
-		subscribeUserId = GetUser(c).ID
+		limit = l
 	}
 
-	distance, err := strconv.ParseInt(c.Query("distance"), 10, 64)
+	tasks, err := services.FetchRecommendedTasks(c, ac.App.DB, GetUser(c).ID, limit)
 	if err != nil {
 		fmt.Println(err)
-		c.AbortWithStatus(http.StatusBadRequest)
+		c.AbortWithStatus(http.StatusInternalServerError)
 
 		return
 	}
 
-	tasks, count, err := services.FetchTasks(
-		c, ac.App.DB,
-		models.QueryParam{
-			Pagination: utils.PaginationConfigFromRequest(c),
-			Relations:  []string{"User", "Category", "Media", "SubscribedUsers"},
-		},
-		services.Filter{
-			CategoryIDs:      categoryIDArray,
-			Skills:           skillsArray,
-			Longitude:        longitude,
-			Latitude:         latitude,
-			SubscribeUserID:  subscribeUserId,
-			SearchTerm:       c.Query("search_term"),
-			ApplyBlockFilter: true,
-			Distance:         int(distance),
-		},
-	)
+	c.JSON(http.StatusOK, tasks)
+}
+
+// respondWithTasks writes a page of the tasks matching filter, along with the cursor of the
+// next page in cursor mode.
+func (ac *Controller) respondWithTasks(c *gin.Context, pagination utils.PaginationConfig, filter services.Filter) {
+	err := services.ValidateTaskListQuery(pagination, filter)
 	if err != nil {
-		fmt.Println(err)
-		c.AbortWithStatus(http.StatusInternalServerError)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 
 		return
 	}
 
-	c.JSON(http.StatusOK, struct {
-		Count      int         `json:"count"`
-		PageNumber int         `json:"pageNumber"`
-		Records    interface{} `json:"records"`
-	}{
-		Count:      count,
-		PageNumber: pagination.Page,
-		Records:    tasks,
-	})
-}
-
-func (ac *Controller) FetchTasksCreatedByUser(c *gin.Context) {
-	user := GetUser(c)
-	pagination := utils.PaginationConfigFromRequest(c)
-
 	tasks, count, err := services.FetchTasks(
 		c, ac.App.DB,
 		models.QueryParam{
-			Pagination: utils.PaginationConfigFromRequest(c),
-			Relations:  []string{"User", "Category", "Media", "SubscribedUsers"},
-		},
-		services.Filter{
-			CreatedByUserID: user.ID,
+			Pagination: pagination,
+			Relations:  []string{"User", "Organization", "Category", "Media", "Shifts"},
 		},
+		filter,
 	)
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrInvalidCursor.Error()})
+
+		return
+	}
+
 	if err != nil {
 		fmt.Println(err)
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -206,32 +282,7 @@ func (ac *Controller) FetchTasksCreatedByUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, struct {
-		Count      int         `json:"count"`
-		PageNumber int         `json:"pageNumber"`
-		Records    interface{} `json:"records"`
-	}{
-		Count:      count,
-		PageNumber: pagination.Page,
-		Records:    tasks,
-	})
-}
-
-func (ac *Controller) FetchTasksSubscribedByUser(c *gin.Context) {
-	user := GetUser(c)
-	pagination := utils.PaginationConfigFromRequest(c)
-
-	tasks, count, err := services.FetchTasks(
-		c, ac.App.DB,
-		models.QueryParam{
-			Pagination: utils.PaginationConfigFromRequest(c),
-			Relations:  []string{"User", "Category", "Media", "SubscribedUsers"},
-		},
-		services.Filter{
-			SubscribedByUserID: user.ID,
-			ApplyBlockFilter:   true,
-		},
-	)
+	nextCursor, err := services.NextTasksCursor(pagination, filter, tasks)
 	if err != nil {
 		fmt.Println(err)
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -239,15 +290,7 @@ func (ac *Controller) FetchTasksSubscribedByUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, struct {
-		Count      int         `json:"count"`
-		PageNumber int         `json:"pageNumber"`
-		Records    interface{} `json:"records"`
-	}{
-		Count:      count,
-		PageNumber: pagination.Page,
-		Records:    tasks,
-	})
+	c.JSON(http.StatusOK, newListResponse(pagination, count, nextCursor, tasks))
 }
 
 func (ac *Controller) ApplyToTask(c *gin.Context) {
@@ -260,18 +303,57 @@ func (ac *Controller) ApplyToTask(c *gin.Context) {
 		return
 	}
 
+	body := struct {
+		ShiftID *uuid.UUID `json:"shift_id"`
+	}{}
+
+	// the body is optional, it is only needed to pick a shift
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	var userTask *models.UserTask
+
 	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
-		err := services.ApplyToTask(c, tx, taskID, user.ID)
-		return err
+		var err error
+		userTask, err = services.ApplyToTask(c, tx, taskID, user.ID, body.ShiftID)
+		if err != nil {
+			return err
+		}
+
+		return ac.App.Notifications().NotifyApplication(c, tx, userTask)
 	})
 
+	if errors.Is(err, services.ErrShiftNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrShiftRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrTaskNotOpen) || errors.Is(err, services.ErrAlreadyApplied) ||
+		errors.Is(err, services.ErrRecurringTaskTemplate) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrApplicationRejected) || errors.Is(err, services.ErrReliabilityTooLow) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err != nil {
 		fmt.Println(err)
 		c.Status(http.StatusInternalServerError)
 		return
 	}
 
-	c.Status(http.StatusCreated)
+	c.JSON(http.StatusCreated, userTask)
 }
 
 func (ac *Controller) WithdrawFromTask(c *gin.Context) {
@@ -284,11 +366,30 @@ func (ac *Controller) WithdrawFromTask(c *gin.Context) {
 		return
 	}
 
+	shiftID, err := shiftIDFromQuery(c)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	var promoted *models.UserTask
+
 	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
-		err := services.WithdrawFromTask(c, tx, taskID, user.ID)
-		return err
+		var err error
+		promoted, err = services.WithdrawFromTask(c, tx, taskID, user.ID, shiftID)
+		if err != nil || promoted == nil {
+			return err
+		}
+
+		return ac.App.Notifications().NotifyApplicant(c, tx, services.NotificationEventPromotedFromWaitlist, promoted)
 	})
 
+	if errors.Is(err, services.ErrWithdrawalClosed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err != nil {
 		fmt.Println(err)
 		c.Status(http.StatusInternalServerError)
@@ -315,8 +416,8 @@ func (ac *Controller) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	//only the user who created the task can delete the task
-	if task.UserID != user.ID {
+	//only the task's owners can delete the task
+	if !ac.isTaskOwner(c, task, user) {
 		c.AbortWithStatus(http.StatusUnauthorized)
 
 		return
@@ -345,7 +446,7 @@ func (ac *Controller) FetchTask(c *gin.Context) {
 	}
 
 	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{
-		Relations: []string{"User", "Category", "Media", "SubscribedUsers"},
+		Relations: []string{"User", "Organization", "Category", "Media", "Shifts"},
 		Alias:     "task",
 	})
 	if err != nil {
@@ -355,45 +456,598 @@ func (ac *Controller) FetchTask(c *gin.Context) {
 		return
 	}
 
+	// drafts and blocked tasks are left out of every listing, they are only shown to the
+	// task's organizers and admins
+	user := GetUser(c)
+	if (task.Status == models.TaskStatusDraft || task.Blocked) && user.Role != "admin" && !ac.isTaskOrganizer(c, task, user) {
+		c.AbortWithStatus(http.StatusNotFound)
+
+		return
+	}
+
 	c.JSON(http.StatusOK, task)
 }
 
-func (ac *Controller) GetSubscribersOfTask(c *gin.Context) {
+func (ac *Controller) ChangeTaskStatus(c *gin.Context) {
+	body := struct {
+		Status string `json:"status" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
 	taskID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		fmt.Println(err)
 		c.AbortWithStatus(http.StatusBadRequest)
 
 		return
 	}
 
-	userTasks, err := services.FetchSubscribersForTask(c, ac.App.DB, taskID)
+	user := GetUser(c)
+
+	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
 	if err != nil {
 		fmt.Println(err)
-		c.AbortWithStatus(http.StatusInternalServerError)
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	//only the task's organizers or an admin can change its status
+	if user.Role != "admin" && !ac.hasTaskPermission(c, task, user, models.TaskPermissionEditDetails) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	var updatedTask *models.Task
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		updatedTask, err = services.ChangeTaskStatus(c, tx, taskID, body.Status)
+		if err != nil {
+			return err
+		}
+
+		if task.Status == models.TaskStatusDraft && updatedTask.Status == models.TaskStatusOpen {
+			ac.notifyNearbyUsers(c, tx, updatedTask)
+		}
+
+		event := services.TaskStatusEvent(updatedTask.Status)
+		if event == "" {
+			return nil
+		}
+
+		return ac.App.Notifications().NotifyTaskSubscribers(c, tx, event, updatedTask, "")
+	})
+
+	if errors.Is(err, services.ErrInvalidTaskStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrInvalidTaskStatusTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
 		return
 	}
 
-	c.JSON(http.StatusOK, userTasks)
+	c.JSON(http.StatusOK, updatedTask)
 }
 
-func SendSMS(ctx context.Context, db bun.IDB, latitude, longitude float32, twilioAccountSID, twilioPhoneNumber, twilioAuthToken string, task *models.Task) error {
-	userLocations, err := services.FetchNearbyUsersOfTask(ctx, db, task.ID, latitude, longitude, 10)
+func (ac *Controller) GetSubscribersOfTask(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		fmt.Println(err)
-		return err
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
 	}
 
-	for _, location := range userLocations {
-		if location.User.ReceiveSMSNotification && location.User.PhoneNumber != nil {
-			fmt.Println("sending sms to number", location.User.PhoneNumber)
-			err = services.SendSMS(*location.User.PhoneNumber, task.Title, twilioAccountSID, twilioPhoneNumber, twilioAuthToken)
-			if err != nil {
-				return err
-			}
+	statuses := utils.DeleteEmptyFromSlice(strings.Split(c.Query("statuses"), ","))
+	for _, status := range statuses {
+		if !services.IsValidApplicationStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + status})
+
+			return
 		}
 	}
 
-	return nil
+	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusNotFound)
+
+		return
+	}
+
+	//only the task's organizers or an admin can see who signed up
+	user := GetUser(c)
+	if user.Role != "admin" && !ac.isTaskOrganizer(c, task, user) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	// without cursor pagination the whole list is returned, as it always has been
+	pagination := ac.paginationFromRequest(c)
+	if !pagination.UseCursor {
+		userTasks, err := services.FetchSubscribersForTask(c, ac.App.DB, taskID, statuses...)
+		if err != nil {
+			fmt.Println(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return
+		}
+
+		attachApplicantReliability(userTasks)
+		c.JSON(http.StatusOK, userTasks)
+
+		return
+	}
+
+	userTasks, count, err := services.FetchSubscribersPage(
+		c, ac.App.DB, taskID, models.QueryParam{Pagination: pagination}, statuses...,
+	)
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrInvalidCursor.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	nextCursor, err := services.NextSubscribersCursor(pagination, userTasks)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	attachApplicantReliability(userTasks)
+	c.JSON(http.StatusOK, newListResponse(pagination, count, nextCursor, userTasks))
+}
+
+// attachApplicantReliability shows the task's organizers how reliable their applicants
+// are. Subscriber listings are only open to organizers, so nobody else gets to see it.
+func attachApplicantReliability(userTasks []models.UserTask) {
+	for _, userTask := range userTasks {
+		if userTask.User != nil {
+			userTask.User.AttachReliability()
+		}
+	}
+}
+
+func (ac *Controller) ReviewApplication(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	applicantID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	action := c.Param("action")
+	if action != "accept" && action != "reject" {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	shiftID, err := shiftIDFromQuery(c)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	user := GetUser(c)
+
+	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	//only the task's organizers can review its applications
+	if !ac.hasTaskPermission(c, task, user, models.TaskPermissionManageVolunteers) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	var userTask, promoted *models.UserTask
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		if action == "accept" {
+			userTask, err = services.AcceptApplication(c, tx, taskID, applicantID, shiftID)
+		} else {
+			userTask, promoted, err = services.RejectApplication(c, tx, taskID, applicantID, shiftID)
+		}
+		if err != nil {
+			return err
+		}
+
+		notifications := ac.App.Notifications()
+
+		if event := services.ApplicationStatusEvent(userTask); event != "" {
+			err = notifications.NotifyApplicant(c, tx, event, userTask)
+			if err != nil {
+				return err
+			}
+		}
+
+		if promoted != nil {
+			return notifications.NotifyApplicant(c, tx, services.NotificationEventPromotedFromWaitlist, promoted)
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, services.ErrApplicationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errors.Is(err, services.ErrInvalidApplicationTransition) || errors.Is(err, services.ErrTaskNotOpen) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, userTask)
+}
+
+func (ac *Controller) FetchTaskShifts(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	shifts, err := services.FetchTaskShifts(c, ac.App.DB, taskID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, shifts)
+}
+
+func (ac *Controller) CreateTaskShift(c *gin.Context) {
+	shift := &models.TaskShift{}
+
+	if err := c.ShouldBindJSON(&shift); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	user := GetUser(c)
+
+	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	//only the task's organizers can manage its shifts
+	if !ac.hasTaskPermission(c, task, user, models.TaskPermissionEditDetails) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.CreateTaskShift(c, tx, taskID, shift)
+	})
+
+	if isTaskValidationError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, shift)
+}
+
+func (ac *Controller) DeleteTaskShift(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	shiftID, err := uuid.Parse(c.Param("shift_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	user := GetUser(c)
+
+	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	//only the task's organizers can manage its shifts
+	if !ac.hasTaskPermission(c, task, user, models.TaskPermissionEditDetails) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.DeleteTaskShift(c, tx, taskID, shiftID)
+	})
+
+	if errors.Is(err, services.ErrShiftNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// publicTaskFilterFromRequest reads the filters shared by the public task listings from the
+// query string, with skills given by name or synonym. It responds with 400 and returns false
+// when one of them is invalid.
+func (ac *Controller) publicTaskFilterFromRequest(c *gin.Context) (services.Filter, bool) {
+	categoryIDs := c.Query("category_ids")
+	skills := c.Query("skills")
+	categoryIDArray := []string{}
+	skillsArray := []string{}
+
+	if categoryIDs != "" {
+		categoryIDArray = strings.Split(categoryIDs, ",")
+	}
+
+	if skills != "" {
+		resolved, err := services.ResolveSkillNames(c, ac.App.DB, strings.Split(skills, ","))
+		if errors.Is(err, services.ErrUnknownSkill) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return services.Filter{}, false
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return services.Filter{}, false
+		}
+
+		skillsArray = resolved
+	}
+
+	// only published tasks are listed unless specific statuses are asked for
+	statusArray := []string{models.TaskStatusOpen, models.TaskStatusFilled, models.TaskStatusInProgress}
+	if c.Query("statuses") != "" {
+		statusArray = strings.Split(c.Query("statuses"), ",")
+	}
+
+	for _, status := range statusArray {
+		if !services.IsValidTaskStatus(status) || status == models.TaskStatusDraft {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + status})
+
+			return services.Filter{}, false
+		}
+	}
+
+	var (
+		longitude, latitude float32
+		subscribeUserId     uuid.UUID
+		matchUserID         uuid.UUID
+		availableForUserID  uuid.UUID
+	)
+
+	if c.Query("longitude") != "" {
+		lng, err := strconv.ParseFloat(c.Query("longitude"), 32)
+		if err != nil {
+			fmt.Println(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return services.Filter{}, false
+		}
+
+		longitude = float32(lng)
+	}
+
+	if c.Query("latitude") != "" {
+		lat, err := strconv.ParseFloat(c.Query("latitude"), 32)
+		if err != nil {
+			fmt.Println(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return services.Filter{}, false
+		}
+
+		latitude = float32(lat)
+	}
+
+	if c.Query("is_subscribed") == "true" {
+		subscribeUserId = GetUser(c).ID
+	}
+
+	// sort=matching ranks tasks by how well they suit the current user's skills and location
+	if c.Query("sort") == "matching" {
+		matchUserID = GetUser(c).ID
+	}
+
+	// available_only=true keeps the tasks the current user said they are free for
+	if c.Query("available_only") == "true" {
+		availableForUserID = GetUser(c).ID
+	}
+
+	distance := int64(constants.DefaultSearchRadiusKm)
+
+	if c.Query("distance") != "" {
+		d, err := strconv.ParseInt(c.Query("distance"), 10, 64)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid distance: " + c.Query("distance")})
+
+			return services.Filter{}, false
+		}
+
+		distance = d
+	}
+
+	location, err := services.LoadTimeZone(c.DefaultQuery("time_zone", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidTimeZone.Error()})
+
+		return services.Filter{}, false
+	}
+
+	from, to, err := utils.ParseDateRange(c.Query("when"), c.Query("from"), c.Query("to"), location, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return services.Filter{}, false
+	}
+
+	filter := services.Filter{
+		CategoryIDs:            categoryIDArray,
+		Skills:                 skillsArray,
+		Longitude:              longitude,
+		Latitude:               latitude,
+		SubscribeUserID:        subscribeUserId,
+		SearchTerm:             c.Query("search_term"),
+		ApplyBlockFilter:       true,
+		Distance:               int(distance),
+		Statuses:               statusArray,
+		From:                   from,
+		To:                     to,
+		HidePast:               c.Query("include_past") != "true",
+		HideRecurringTemplates: true,
+		SortByRelevance:        c.Query("sort") == "relevance",
+		VerifiedOnly:           c.Query("verified_only") == "true",
+		MatchUserID:            matchUserID,
+		AvailableForUserID:     availableForUserID,
+	}
+
+	return filter, true
+}
+
+// shiftIDFromQuery reads the optional shift_id query parameter.
+func shiftIDFromQuery(c *gin.Context) (*uuid.UUID, error) {
+	if c.Query("shift_id") == "" {
+		return nil, nil
+	}
+
+	shiftID, err := uuid.Parse(c.Query("shift_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &shiftID, nil
+}
+
+// notifyNearbyUsers queues the alerts of a newly published task with it, the worker sends
+// them once it is committed. The task is published even when its alerts cannot be queued,
+// they are rolled back on their own.
+func (ac *Controller) notifyNearbyUsers(c *gin.Context, tx *bun.Tx, task *models.Task) {
+	err := models.WithTransaction(c, tx, func(savepoint *bun.Tx) error {
+		return ac.App.Notifications().NotifyNearbyUsers(c, savepoint, task)
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// isTaskOwner reports whether the user owns the task, either as the user who posted it or,
+// for a task posted as an organization, as one of its current managers.
+func (ac *Controller) isTaskOwner(c *gin.Context, task models.Task, user *models.User) bool {
+	return allowedOnTask(services.IsTaskOwner(c, ac.App.DB, task, user.ID))
+}
+
+// hasTaskPermission reports whether the user may act on the task with the permission, as
+// one of its owners or as a co-organizer who was given it.
+func (ac *Controller) hasTaskPermission(c *gin.Context, task models.Task, user *models.User, permission string) bool {
+	return allowedOnTask(services.HasTaskPermission(c, ac.App.DB, task, user.ID, permission))
+}
+
+// isTaskOrganizer reports whether the user owns or co-organizes the task.
+func (ac *Controller) isTaskOrganizer(c *gin.Context, task models.Task, user *models.User) bool {
+	return allowedOnTask(services.IsTaskOrganizer(c, ac.App.DB, task, user.ID))
+}
+
+func allowedOnTask(allowed bool, err error) bool {
+	if err != nil {
+		fmt.Println(err)
+
+		return false
+	}
+
+	return allowed
+}
+
+func isTaskValidationError(err error) bool {
+	return errors.Is(err, services.ErrInvalidSchedule) ||
+		errors.Is(err, services.ErrInvalidTimeZone) ||
+		errors.Is(err, services.ErrInvalidShiftCapacity) ||
+		errors.Is(err, services.ErrInvalidRecurrenceRule) ||
+		errors.Is(err, services.ErrRecurrenceNeedsSchedule) ||
+		errors.Is(err, services.ErrNotAnOccurrence) ||
+		errors.Is(err, services.ErrInvalidCheckInArea) ||
+		errors.Is(err, services.ErrInvalidMinReliability) ||
+		errors.Is(err, services.ErrUnknownSkill) ||
+		errors.Is(err, services.ErrTaskStatusNotEditable)
 }
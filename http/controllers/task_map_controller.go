@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"rashikzaman/api/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FetchTaskMap returns the tasks in the map viewport given by bbox
+// (min_lng,min_lat,max_lng,max_lat) at the given zoom level, clustered when zoomed out.
+// It takes the same filters as FetchTasks, except that the viewport replaces the radius.
+func (ac *Controller) FetchTaskMap(c *gin.Context) {
+	bbox, err := boundingBoxFromQuery(c.Query("bbox"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	zoom, err := strconv.Atoi(c.Query("zoom"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidZoom.Error()})
+
+		return
+	}
+
+	filter, ok := ac.publicTaskFilterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	filter.Latitude, filter.Longitude = 0, 0
+
+	taskMap, err := services.FetchTaskMap(c, ac.App.DB, bbox, zoom, filter)
+	if errors.Is(err, services.ErrInvalidBoundingBox) || errors.Is(err, services.ErrInvalidZoom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, taskMap)
+}
+
+func boundingBoxFromQuery(value string) (services.BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return services.BoundingBox{}, services.ErrInvalidBoundingBox
+	}
+
+	coordinates := make([]float64, len(parts))
+
+	for i, part := range parts {
+		coordinate, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return services.BoundingBox{}, services.ErrInvalidBoundingBox
+		}
+
+		coordinates[i] = coordinate
+	}
+
+	return services.BoundingBox{
+		MinLongitude: coordinates[0],
+		MinLatitude:  coordinates[1],
+		MaxLongitude: coordinates[2],
+		MaxLatitude:  coordinates[3],
+	}, nil
+}
+
+// FetchTasksGeoJSON returns a page of tasks as a GeoJSON FeatureCollection, taking the
+// same filters and pagination as FetchTasks.
+func (ac *Controller) FetchTasksGeoJSON(c *gin.Context) {
+	filter, ok := ac.publicTaskFilterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	pagination := ac.paginationFromRequest(c)
+
+	err := services.ValidateTaskListQuery(pagination, filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	collection, err := services.FetchTasksFeatureCollection(
+		c, ac.App.DB,
+		models.QueryParam{Pagination: pagination},
+		filter,
+	)
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrInvalidCursor.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, collection)
+}
+
+// FetchTaskTile serves the tasks in the z/x/y tile as a Mapbox Vector Tile, taking the same
+// filters as FetchTasks except for the radius.
+func (ac *Controller) FetchTaskTile(c *gin.Context) {
+	z, errZ := strconv.Atoi(c.Param("z"))
+	x, errX := strconv.Atoi(c.Param("x"))
+	y, errY := strconv.Atoi(strings.TrimSuffix(c.Param("y"), ".mvt"))
+
+	if errZ != nil || errX != nil || errY != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidTile.Error()})
+
+		return
+	}
+
+	filter, ok := ac.publicTaskFilterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	filter.Latitude, filter.Longitude = 0, 0
+
+	tile, err := services.FetchTaskTile(c, ac.App.DB, z, x, y, filter)
+	if errors.Is(err, services.ErrInvalidTile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.Data(http.StatusOK, "application/vnd.mapbox-vector-tile", tile)
+}
@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// FetchTaskOrganizers lists the co-organizers of the task, with pending invitations, to
+// its organizers.
+func (ac *Controller) FetchTaskOrganizers(c *gin.Context) {
+	task, ok := ac.taskFromParam(c)
+	if !ok {
+		return
+	}
+
+	if !ac.isTaskOrganizer(c, task, GetUser(c)) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	organizers, err := services.FetchTaskOrganizers(c, ac.App.DB, task.ID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, organizers)
+}
+
+// FetchMyOrganizerInvitations lists the tasks the current user was invited to co-organize
+// and has not answered yet.
+func (ac *Controller) FetchMyOrganizerInvitations(c *gin.Context) {
+	invitations, err := services.FetchTaskOrganizerInvitations(c, ac.App.DB, GetUser(c).ID)
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// InviteTaskOrganizer lets the task's owners invite another user, by email, to co-organize
+// it with the permissions in the body.
+func (ac *Controller) InviteTaskOrganizer(c *gin.Context) {
+	body := struct {
+		Email                string `json:"email" binding:"required"`
+		CanEditDetails       bool   `json:"can_edit_details"`
+		CanManageVolunteers  bool   `json:"can_manage_volunteers"`
+		CanMessageVolunteers bool   `json:"can_message_volunteers"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	task, ok := ac.taskFromParam(c)
+	if !ok {
+		return
+	}
+
+	user := GetUser(c)
+
+	//only the task's owners pick its co-organizers
+	if !ac.isTaskOwner(c, task, user) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	organizer := &models.TaskOrganizer{
+		CanEditDetails:       body.CanEditDetails,
+		CanManageVolunteers:  body.CanManageVolunteers,
+		CanMessageVolunteers: body.CanMessageVolunteers,
+	}
+
+	err := models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.InviteTaskOrganizer(c, tx, task, body.Email, organizer, user.ID)
+	})
+	if handleTaskOrganizerError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, organizer)
+}
+
+// AcceptTaskOrganizerInvitation makes the current user a co-organizer of the task they
+// were invited to.
+func (ac *Controller) AcceptTaskOrganizerInvitation(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	var organizer *models.TaskOrganizer
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		organizer, err = services.AcceptTaskOrganizerInvitation(c, tx, taskID, GetUser(c).ID)
+		return err
+	})
+	if handleTaskOrganizerError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, organizer)
+}
+
+// UpdateTaskOrganizer lets the task's owners change what a co-organizer may do.
+func (ac *Controller) UpdateTaskOrganizer(c *gin.Context) {
+	permissions := models.TaskOrganizer{}
+
+	if err := c.ShouldBindJSON(&permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	task, ok := ac.taskFromParam(c)
+	if !ok {
+		return
+	}
+
+	if !ac.isTaskOwner(c, task, GetUser(c)) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	var organizer *models.TaskOrganizer
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		var err error
+		organizer, err = services.UpdateTaskOrganizerPermissions(c, tx, task.ID, userID, permissions)
+		return err
+	})
+	if handleTaskOrganizerError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, organizer)
+}
+
+// RemoveTaskOrganizer takes a co-organizer off the task. Owners remove others, anyone can
+// step down or decline an invitation on their own.
+func (ac *Controller) RemoveTaskOrganizer(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return
+	}
+
+	task, ok := ac.taskFromParam(c)
+	if !ok {
+		return
+	}
+
+	if userID != GetUser(c).ID && !ac.isTaskOwner(c, task, GetUser(c)) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	err = models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return services.RemoveTaskOrganizer(c, tx, task.ID, userID)
+	})
+	if handleTaskOrganizerError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// MessageTaskVolunteers sends a message from the task's organizers to its volunteers.
+func (ac *Controller) MessageTaskVolunteers(c *gin.Context) {
+	body := struct {
+		Message string `json:"message" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	task, ok := ac.taskFromParam(c)
+	if !ok {
+		return
+	}
+
+	if !ac.hasTaskPermission(c, task, GetUser(c), models.TaskPermissionMessageVolunteers) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+
+		return
+	}
+
+	// queued for every volunteer or none, so that a retry does not message some of them twice
+	err := models.WithTransaction(c, ac.App.DB, func(tx *bun.Tx) error {
+		return ac.App.Notifications().NotifyTaskSubscribers(c, tx, services.NotificationEventTaskMessage, &task, body.Message)
+	})
+	if err != nil {
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// taskFromParam fetches the task in the id path parameter. It responds with 400 or 404 and
+// returns false when there is no such task.
+func (ac *Controller) taskFromParam(c *gin.Context) (models.Task, bool) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+
+		return models.Task{}, false
+	}
+
+	task, err := services.FetchTaskByID(c, ac.App.DB, taskID, models.QueryParam{})
+	if err != nil {
+		fmt.Println(err)
+		c.AbortWithStatus(http.StatusNotFound)
+
+		return models.Task{}, false
+	}
+
+	return task, true
+}
+
+// handleTaskOrganizerError writes the response for a failed change to the co-organizers of
+// a task and reports whether there was an error.
+func handleTaskOrganizerError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrNotTaskOrganizer), errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyTaskOrganizer), errors.Is(err, services.ErrTaskOwnerAsOrganizer):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		fmt.Println(err)
+		c.Status(http.StatusInternalServerError)
+	}
+
+	return true
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"rashikzaman/api/models"
@@ -21,6 +22,8 @@ func (ac *Controller) FetchMe(c *gin.Context) {
 		return
 	}
 
+	detailedUser.AttachReliability()
+
 	c.JSON(http.StatusOK, detailedUser)
 }
 
@@ -51,6 +54,13 @@ func (ac *Controller) UpdateMe(c *gin.Context) {
 		return nil
 	})
 
+	if errors.Is(err, services.ErrInvalidTimeZone) || errors.Is(err, services.ErrInvalidAvailability) ||
+		errors.Is(err, services.ErrInvalidNotificationPreferences) || errors.Is(err, services.ErrUnknownSkill) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 
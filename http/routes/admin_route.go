@@ -20,4 +20,11 @@ func AdminRoutes(r *gin.Engine, app application.Application) {
 	routeGroup.GET("/users", controller.FetchUsersForAdmin)
 	routeGroup.PATCH("/users/:id/:action", controller.ApplyActionToUser)
 	routeGroup.PATCH("/tasks/:id/:action", controller.ApplyActionToTask)
+	routeGroup.GET("/organizations", controller.FetchOrganizationsForReview)
+	routeGroup.PATCH("/organizations/:id/:action", controller.ApplyActionToOrganization)
+	routeGroup.POST("/skills", controller.CreateSkill)
+	routeGroup.PUT("/skills/:id", controller.RenameSkill)
+	routeGroup.DELETE("/skills/:id", controller.DeleteSkill)
+	routeGroup.POST("/skills/:id/synonyms", controller.AddSkillSynonym)
+	routeGroup.DELETE("/skills/:id/synonyms/:synonym_id", controller.RemoveSkillSynonym)
 }
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"rashikzaman/api/application"
+	"rashikzaman/api/http/controllers"
+	middleware "rashikzaman/api/http/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func OrganizationRoutes(r *gin.Engine, app application.Application) {
+	controller := controllers.Controller{
+		App: &app,
+	}
+
+	routeGroup := r.Group("/organizations", middleware.ClerkMiddleware(&app))
+
+	routeGroup.POST("/", controller.CreateOrganization)
+	routeGroup.GET("/me", controller.FetchMyOrganizations)
+	routeGroup.GET("/:id", controller.FetchOrganization)
+	routeGroup.PUT("/:id", controller.UpdateOrganization)
+	routeGroup.GET("/:id/tasks", controller.FetchOrganizationTasks)
+	routeGroup.POST("/:id/verification", controller.RequestOrganizationVerification)
+	routeGroup.GET("/:id/verification", controller.FetchOrganizationVerification)
+	routeGroup.POST("/:id/members", controller.AddOrganizationMember)
+	routeGroup.PUT("/:id/members/:user_id", controller.ChangeOrganizationMemberRole)
+	routeGroup.DELETE("/:id/members/:user_id", controller.RemoveOrganizationMember)
+}
@@ -15,14 +15,35 @@ func TaskRoutes(r *gin.Engine, app application.Application) {
 
 	routeGroup := r.Group("/tasks", middleware.ClerkMiddleware(&app))
 
+	r.GET("/tasks.geojson", middleware.ClerkMiddleware(&app), controller.FetchTasksGeoJSON)
+
 	routeGroup.GET("/", controller.FetchTasks)
 	routeGroup.GET("/me", controller.FetchTasksCreatedByUser)
 	routeGroup.GET("/me/subscribed", controller.FetchTasksSubscribedByUser)
+	routeGroup.GET("/me/organizer-invitations", controller.FetchMyOrganizerInvitations)
+	routeGroup.GET("/map", controller.FetchTaskMap)
+	routeGroup.GET("/recommended", controller.FetchRecommendedTasks)
 	routeGroup.POST("/", controller.CreateTask)
 	routeGroup.DELETE("/:id/", controller.DeleteTask)
 	routeGroup.GET("/:id", controller.FetchTask)
 	routeGroup.PUT("/:id", controller.UpdateTask)
+	routeGroup.PATCH("/:id/status", controller.ChangeTaskStatus)
 	routeGroup.POST("/:id/apply", controller.ApplyToTask)
 	routeGroup.DELETE("/:id/withdraw", controller.WithdrawFromTask)
 	routeGroup.GET("/:id/subscribers", controller.GetSubscribersOfTask)
+	routeGroup.PATCH("/:id/subscribers/:user_id/:action", controller.ReviewApplication)
+	routeGroup.GET("/:id/shifts", controller.FetchTaskShifts)
+	routeGroup.POST("/:id/shifts", controller.CreateTaskShift)
+	routeGroup.DELETE("/:id/shifts/:shift_id", controller.DeleteTaskShift)
+	routeGroup.GET("/:id/attendance-qr", controller.FetchAttendanceQR)
+	routeGroup.POST("/:id/check-in", controller.CheckIn)
+	routeGroup.POST("/:id/check-out", controller.CheckOut)
+	routeGroup.POST("/:id/subscribers/:user_id/:action", controller.RecordAttendance)
+	routeGroup.PUT("/:id/subscribers/:user_id/hours", controller.CorrectHours)
+	routeGroup.POST("/:id/messages", controller.MessageTaskVolunteers)
+	routeGroup.GET("/:id/organizers", controller.FetchTaskOrganizers)
+	routeGroup.POST("/:id/organizers", controller.InviteTaskOrganizer)
+	routeGroup.POST("/:id/organizers/accept", controller.AcceptTaskOrganizerInvitation)
+	routeGroup.PUT("/:id/organizers/:user_id", controller.UpdateTaskOrganizer)
+	routeGroup.DELETE("/:id/organizers/:user_id", controller.RemoveTaskOrganizer)
 }
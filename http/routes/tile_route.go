@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"rashikzaman/api/application"
+	"rashikzaman/api/http/controllers"
+	middleware "rashikzaman/api/http/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TileRoutes(r *gin.Engine, app application.Application) {
+	controller := controllers.Controller{
+		App: &app,
+	}
+
+	routeGroup := r.Group("/tiles", middleware.ClerkMiddleware(&app))
+
+	// :y carries the ".mvt" extension, gin has no way to match it separately
+	routeGroup.GET("/tasks/:z/:x/:y", controller.FetchTaskTile)
+}
@@ -17,4 +17,13 @@ func UserRoutes(r *gin.Engine, app application.Application) {
 
 	routeGroup.GET("/me", controller.FetchMe)
 	routeGroup.PUT("/me", controller.UpdateMe)
+	routeGroup.GET("/me/hours", controller.FetchMyHours)
+	routeGroup.GET("/me/skills", controller.FetchMySkills)
+	routeGroup.PUT("/me/skills", controller.UpdateMySkills)
+	routeGroup.GET("/me/push-subscriptions/key", controller.FetchPushPublicKey)
+	routeGroup.POST("/me/push-subscriptions", controller.SubscribeToPush)
+	routeGroup.DELETE("/me/push-subscriptions", controller.UnsubscribeFromPush)
+	routeGroup.GET("/me/notifications", controller.FetchMyNotifications)
+	routeGroup.POST("/me/notifications/mark-all-read", controller.MarkAllMyNotificationsRead)
+	routeGroup.PATCH("/me/notifications/:id", controller.MarkMyNotification)
 }
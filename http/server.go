@@ -19,6 +19,8 @@ func RunHTTPServer(app application.Application) {
 	routes.SkillsRoutes(r, app)
 	routes.AdminRoutes(r, app)
 	routes.UserRoutes(r, app)
+	routes.TileRoutes(r, app)
+	routes.OrganizationRoutes(r, app)
 
 	_ = r.Run(":" + app.Config.GetHTTPPort())
 }
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/log"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const digestSweepInterval = constants.DigestSweepMinutes * time.Minute
+
+// sendDigests returns the job emailing users in digest mode the new tasks near them when
+// their digest is due. Each digest is sent in a transaction of its own.
+func sendDigests(smtpConfig services.SMTPConfig) func(ctx context.Context, db bun.IDB, logger log.Logger) error {
+	return func(ctx context.Context, db bun.IDB, logger log.Logger) error {
+		sent, failed, err := services.SendDueDigests(ctx, db, smtpConfig, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if sent > 0 {
+			logger.Infof("sent %d digests", sent)
+		}
+
+		if failed > 0 {
+			logger.Infof("failed to send %d digests, they are retried on the next run", failed)
+		}
+
+		return nil
+	}
+}
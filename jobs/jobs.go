@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"rashikzaman/api/application"
+	"rashikzaman/api/log"
+	"rashikzaman/api/models"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Run starts the periodic background jobs. They stop when ctx is cancelled.
+func Run(ctx context.Context, app application.Application, logger log.Logger) {
+	go runPeriodically(
+		ctx, app, logger, "recurrence generator", recurrenceGeneratorInterval, inTransaction(generateOccurrences),
+	)
+	go runPeriodically(ctx, app, logger, "no-show sweep", noShowSweepInterval, inTransaction(recordNoShows))
+
+	// digests can only be sent by email
+	if app.Config.GetSMTPHost() != "" {
+		go runPeriodically(ctx, app, logger, "digests", digestSweepInterval, sendDigests(app.SMTPConfig()))
+	}
+}
+
+// runPeriodically runs job right away and then every interval.
+func runPeriodically(
+	ctx context.Context, app application.Application, logger log.Logger, name string, interval time.Duration,
+	job func(ctx context.Context, db bun.IDB, logger log.Logger) error,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := job(ctx, app.DB, logger)
+		if err != nil {
+			logger.Errorf(err, "%s failed", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// inTransaction runs job in a transaction of its own, for jobs that are done all at once or
+// not at all.
+func inTransaction(
+	job func(ctx context.Context, tx *bun.Tx, logger log.Logger) error,
+) func(ctx context.Context, db bun.IDB, logger log.Logger) error {
+	return func(ctx context.Context, db bun.IDB, logger log.Logger) error {
+		return models.WithTransaction(ctx, db, func(tx *bun.Tx) error {
+			return job(ctx, tx, logger)
+		})
+	}
+}
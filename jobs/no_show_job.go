@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/log"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const noShowSweepInterval = constants.NoShowSweepMinutes * time.Minute
+
+// recordNoShows settles the attendance of volunteers of tasks that have ended, counting
+// those who never checked in as no-shows.
+func recordNoShows(ctx context.Context, tx *bun.Tx, logger log.Logger) error {
+	settled, err := services.RecordEndedTaskOutcomes(ctx, tx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if settled > 0 {
+		logger.Infof("settled the attendance of %d volunteers of ended tasks", settled)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"rashikzaman/api/log"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const recurrenceGeneratorInterval = time.Hour
+
+// generateOccurrences keeps the rolling window of upcoming occurrences of recurring tasks
+// filled.
+func generateOccurrences(ctx context.Context, tx *bun.Tx, logger log.Logger) error {
+	created, err := services.GenerateUpcomingOccurrences(ctx, tx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if created > 0 {
+		logger.Infof("generated %d occurrences of recurring tasks", created)
+	}
+
+	return nil
+}
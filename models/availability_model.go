@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// AvailabilityWindow is a time of the week the user is usually free, in the user's time
+// zone. Times are written HH:MM, the end of the day being 24:00.
+type AvailabilityWindow struct {
+	Base
+	UserID    uuid.UUID `bun:"type:uuid" json:"user_id"`
+	Weekday   int       `json:"weekday"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+}
+
+// AvailabilityException overrides the weekly windows on a date (YYYY-MM-DD): the user is
+// away all day unless they have exceptions that are Available, and then only free in their
+// hours.
+type AvailabilityException struct {
+	Base
+	UserID    uuid.UUID `bun:"type:uuid" json:"user_id"`
+	Date      string    `bun:"type:date" json:"date"`
+	Available bool      `json:"available"`
+	StartTime string    `bun:",nullzero" json:"start_time"`
+	EndTime   string    `bun:",nullzero" json:"end_time"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// how often users in digest mode get their email of new nearby tasks
+const (
+	DigestFrequencyOff    = "off"
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+)
+
+// Digest is an email of the new tasks near a user, sent instead of alerting them of each.
+type Digest struct {
+	Base
+	UserID    uuid.UUID     `bun:"type:uuid" json:"user_id"`
+	Frequency string        `json:"frequency"`
+	SentAt    time.Time     `bun:",nullzero,notnull,default:current_timestamp" json:"sent_at"`
+	Tasks     []*DigestTask `bun:"rel:has-many,join:id=digest_id" json:"tasks,omitempty"`
+}
+
+// DigestTask records that a digest included a task, so that it is not sent to the user again.
+type DigestTask struct {
+	Base
+	DigestID uuid.UUID `bun:"type:uuid" json:"digest_id"`
+	UserID   uuid.UUID `bun:"type:uuid" json:"user_id"`
+	TaskID   uuid.UUID `bun:"type:uuid" json:"task_id"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "github.com/google/uuid"
+
+// HoursSummary is a volunteer's hours ledger rolled up by category and by month.
+type HoursSummary struct {
+	TotalMinutes int             `json:"total_minutes"`
+	Categories   []CategoryHours `json:"categories"`
+	Months       []MonthHours    `json:"months"`
+}
+
+type CategoryHours struct {
+	CategoryID   uuid.UUID `bun:"type:uuid" json:"category_id"`
+	CategoryName string    `json:"category_name"`
+	Minutes      int       `json:"minutes"`
+}
+
+type MonthHours struct {
+	Month   string `json:"month"`
+	Minutes int    `json:"minutes"`
+}
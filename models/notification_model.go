@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// channels notifications are delivered on
+const (
+	NotificationChannelSMS   = "sms"
+	NotificationChannelEmail = "email"
+	NotificationChannelInApp = "in_app"
+	NotificationChannelPush  = "push"
+)
+
+// how delivering a notification on a channel went. Skipped deliveries are those the
+// recipient cannot get on the channel, say SMS for users without a phone number.
+const (
+	NotificationDeliveryStatusPending = "pending"
+	NotificationDeliveryStatusSent    = "sent"
+	NotificationDeliveryStatusFailed  = "failed"
+	NotificationDeliveryStatusSkipped = "skipped"
+)
+
+// Notification is what a user is told about an event, with a delivery for every channel it
+// was sent on. The notification itself is the user's in-app inbox entry.
+type Notification struct {
+	Base
+	UserID     uuid.UUID               `bun:"type:uuid" json:"user_id"`
+	EventType  string                  `json:"event_type"`
+	TaskID     *uuid.UUID              `bun:"type:uuid" json:"task_id"`
+	Subject    string                  `json:"subject"`
+	Body       string                  `json:"body"`
+	ReadAt     *time.Time              `json:"read_at"`
+	Deliveries []*NotificationDelivery `bun:"rel:has-many,join:id=notification_id" json:"deliveries,omitempty"`
+}
+
+// NotificationDelivery is a notification sent on a channel. Deliveries are pending until the
+// worker has sent them, see NotificationOutboxJob.
+type NotificationDelivery struct {
+	Base
+	NotificationID uuid.UUID     `bun:"type:uuid" json:"notification_id"`
+	Notification   *Notification `bun:"rel:belongs-to,join:notification_id=id" json:"-"`
+	Channel        string        `json:"channel"`
+	Status         string        `json:"status"`
+	Error          string        `json:"error"`
+	SentAt         *time.Time    `json:"sent_at"`
+}
+
+// outbox job statuses, jobs are deleted once their delivery has been made
+const (
+	NotificationOutboxJobStatusPending = "pending"
+	NotificationOutboxJobStatusDead    = "dead"
+)
+
+// NotificationOutboxJob queues a delivery for the worker. It is written in the same
+// transaction as the change the notification is about, so the notification is sent if and
+// only if the change is committed. Failed jobs are retried at RunAt until they run out of
+// attempts and are dead-lettered.
+type NotificationOutboxJob struct {
+	Base
+	DeliveryID uuid.UUID             `bun:"type:uuid" json:"delivery_id"`
+	Delivery   *NotificationDelivery `bun:"rel:belongs-to,join:delivery_id=id" json:"delivery,omitempty"`
+	Status     string                `json:"status"`
+	Attempts   int                   `json:"attempts"`
+	RunAt      time.Time             `bun:",nullzero,notnull,default:current_timestamp" json:"run_at"`
+	LastError  string                `json:"last_error"`
+}
+
+// PushSubscription is a browser the user allowed to show web push notifications. P256dh and
+// Auth are the keys the browser encrypts the notifications with.
+type PushSubscription struct {
+	Base
+	UserID   uuid.UUID `bun:"type:uuid" json:"user_id"`
+	Endpoint string    `json:"endpoint"`
+	P256dh   string    `bun:"p256dh" json:"-"`
+	Auth     string    `json:"-"`
+}
+
+// NotificationPreferences is how the user wants to be notified. Alerts of new tasks go to
+// users within AlertRadiusKm of them, narrowed to CategoryIDs and to tasks requiring any of
+// Skills when they are set, and to at most DailyCap a day. EventChannels lists the channels
+// of an event type, events it leaves out are sent on their default channels. During the quiet hours,
+// in the user's time zone, only the in-app inbox gets notifications right away, the others
+// wait for the quiet hours to end. Users with a DigestFrequency other than off get new
+// tasks in a digest email instead of alerts.
+type NotificationPreferences struct {
+	Base
+	UserID          uuid.UUID           `bun:"type:uuid" json:"user_id"`
+	User            *User               `bun:"rel:belongs-to,join:user_id=id" json:"-"`
+	AlertRadiusKm   int                 `bun:",nullzero,notnull,default:10" json:"alert_radius_km"`
+	CategoryIDs     []uuid.UUID         `bun:"category_ids,type:uuid[],array" json:"category_ids"`
+	Skills          []string            `bun:",array" json:"skills"`
+	EventChannels   map[string][]string `bun:"type:jsonb" json:"event_channels"`
+	QuietHoursStart string              `bun:",nullzero" json:"quiet_hours_start"`
+	QuietHoursEnd   string              `bun:",nullzero" json:"quiet_hours_end"`
+	DailyCap        *int                `json:"daily_cap"`
+	DigestFrequency string              `bun:",nullzero,notnull,default:'off'" json:"digest_frequency"`
+	LastDigestAt    *time.Time          `json:"last_digest_at"`
+}
+
+// WantsChannel reports whether the user wants to be notified of the event on the channel.
+// Users who did not pick the channels of the event get it on defaults, or on every channel
+// when the event has no defaults.
+func (p *NotificationPreferences) WantsChannel(event, channel string, defaults []string) bool {
+	channels, ok := []string(nil), false
+	if p != nil {
+		channels, ok = p.EventChannels[event]
+	}
+
+	if !ok && defaults == nil {
+		return true
+	}
+
+	if !ok {
+		channels = defaults
+	}
+
+	for _, wanted := range channels {
+		if wanted == channel {
+			return true
+		}
+	}
+
+	return false
+}
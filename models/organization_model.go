@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Owners run the organization and its membership, managers post and manage its tasks and
+// members are listed as its staff.
+const (
+	OrganizationRoleOwner   = "owner"
+	OrganizationRoleManager = "manager"
+	OrganizationRoleMember  = "member"
+)
+
+type Organization struct {
+	Base
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Website     string `json:"website"`
+	LogoURL     string `json:"logo_url"`
+	// VerifiedAt is set once an admin has vetted the organization, see
+	// OrganizationVerificationRequest
+	VerifiedAt *time.Time            `json:"verified_at"`
+	Members    []*OrganizationMember `bun:"rel:has-many,join:id=organization_id" json:"members,omitempty"`
+}
+
+type OrganizationMember struct {
+	Base
+	OrganizationID uuid.UUID     `bun:"type:uuid" json:"organization_id"`
+	Organization   *Organization `bun:"rel:belongs-to,join:organization_id=id" json:"organization,omitempty"`
+	UserID         uuid.UUID     `bun:"type:uuid" json:"user_id"`
+	User           *User         `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+	Role           string        `json:"role"`
+}
+
+// CanManageTasks reports whether the member may post and manage the organization's tasks.
+func (m *OrganizationMember) CanManageTasks() bool {
+	return m.Role == OrganizationRoleOwner || m.Role == OrganizationRoleManager
+}
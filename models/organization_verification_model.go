@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	VerificationStatusPending  = "pending"
+	VerificationStatusApproved = "approved"
+	VerificationStatusRejected = "rejected"
+)
+
+// OrganizationVerificationRequest is an organization asking admins to vet it, backed by
+// documents such as its registration certificate.
+type OrganizationVerificationRequest struct {
+	Base
+	OrganizationID    uuid.UUID                           `bun:"type:uuid" json:"organization_id"`
+	Organization      *Organization                       `bun:"rel:belongs-to,join:organization_id=id" json:"organization,omitempty"`
+	RequestedByUserID *uuid.UUID                          `bun:"type:uuid" json:"requested_by_user_id"`
+	RequestedBy       *User                               `bun:"rel:belongs-to,join:requested_by_user_id=id" json:"requested_by,omitempty"`
+	Status            string                              `bun:",nullzero,notnull,default:'pending'" json:"status"`
+	Note              string                              `json:"note"`
+	ReviewNote        string                              `json:"review_note"`
+	ReviewedByUserID  *uuid.UUID                          `bun:"type:uuid" json:"reviewed_by_user_id"`
+	ReviewedAt        *time.Time                          `json:"reviewed_at"`
+	Documents         []*OrganizationVerificationDocument `bun:"rel:has-many,join:id=request_id" json:"documents"`
+}
+
+// OrganizationVerificationDocument is kept in the private bucket under ObjectKey. Only
+// admins get a URL to it, see services.PresignMediaURL.
+type OrganizationVerificationDocument struct {
+	Base
+	RequestID uuid.UUID `bun:"type:uuid" json:"request_id"`
+	MimeType  string    `json:"mime_type"`
+	ObjectKey string    `json:"-"`
+	URL       string    `bun:"-" json:"url,omitempty"`
+	Base64    string    `bun:"-" json:"base64,omitempty"`
+}
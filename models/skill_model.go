@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// how well volunteers say they know a skill, it is optional
+const (
+	SkillProficiencyBeginner     = "beginner"
+	SkillProficiencyIntermediate = "intermediate"
+	SkillProficiencyExpert       = "expert"
+)
+
+// Skill is an entry of the skill taxonomy managed by the admins. Tasks refer to skills by
+// name in RequiredSkills, the synonyms are other names the skill is known by.
+type Skill struct {
+	Base
+	Name     string          `json:"name"`
+	Synonyms []*SkillSynonym `bun:"rel:has-many,join:id=skill_id" json:"synonyms"`
+}
+
+type SkillSynonym struct {
+	Base
+	SkillID uuid.UUID `bun:"type:uuid" json:"skill_id"`
+	Name    string    `json:"name"`
+}
+
+// UserSkill is a skill the user declared on their profile.
+type UserSkill struct {
+	Base
+	UserID      uuid.UUID `bun:"type:uuid" json:"user_id"`
+	SkillID     uuid.UUID `bun:"type:uuid" json:"skill_id"`
+	Skill       *Skill    `bun:"rel:belongs-to,join:skill_id=id" json:"skill"`
+	Proficiency string    `bun:",nullzero" json:"proficiency"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "github.com/paulmach/orb/geojson"
+
+// GeoJSONFeature describes the task as a GeoJSON point feature for GIS clients.
+func (t *Task) GeoJSONFeature() *geojson.Feature {
+	feature := geojson.NewFeature(t.Location.Geometry)
+	feature.ID = t.ID.String()
+	feature.Properties = geojson.Properties{
+		"title":                     t.Title,
+		"description":               t.Description,
+		"status":                    t.Status,
+		"category_id":               t.CategoryID,
+		"formatted_address":         t.FormattedAddress,
+		"required_volunteers_count": t.RequiredVolunteersCount,
+		"remaining_spots":           t.RemainingSpots,
+		"starts_at":                 t.StartsAt,
+		"ends_at":                   t.EndsAt,
+		"time_zone":                 t.TimeZone,
+		"verified_organizer":        t.VerifiedOrganizer,
+	}
+
+	if t.DistanceKm != nil {
+		feature.Properties["distance_km"] = *t.DistanceKm
+	}
+
+	return feature
+}
+
+// TasksFeatureCollection wraps the tasks in a GeoJSON FeatureCollection.
+func TasksFeatureCollection(tasks []Task) *geojson.FeatureCollection {
+	collection := geojson.NewFeatureCollection()
+
+	for i := range tasks {
+		collection.Append(tasks[i].GeoJSONFeature())
+	}
+
+	return collection
+}
@@ -0,0 +1,31 @@
+package models
+
+import "github.com/google/uuid"
+
+// TaskMap is what the map shows for a viewport: either the tasks themselves or, when
+// zoomed out, clusters of nearby tasks.
+type TaskMap struct {
+	Clustered bool             `json:"clustered"`
+	Points    []TaskMapPoint   `json:"points"`
+	Clusters  []TaskMapCluster `json:"clusters"`
+}
+
+type TaskMapPoint struct {
+	ID         uuid.UUID `bun:"type:uuid" json:"id"`
+	Title      string    `json:"title"`
+	Status     string    `json:"status"`
+	CategoryID uuid.UUID `bun:"type:uuid" json:"category_id"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+}
+
+type TaskMapCluster struct {
+	Count     int     `json:"count"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// the extent of the cluster, for zooming into it
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/paulmach/orb"
@@ -50,30 +51,104 @@ func (g *PostgisGeometry) Value() (driver.Value, error) {
 	return d, nil
 }
 
+const (
+	TaskStatusDraft      = "draft"
+	TaskStatusOpen       = "open"
+	TaskStatusFilled     = "filled"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusCompleted  = "completed"
+	TaskStatusCancelled  = "cancelled"
+)
+
+const (
+	ApplicationStatusPending   = "pending"
+	ApplicationStatusAccepted  = "accepted"
+	ApplicationStatusRejected  = "rejected"
+	ApplicationStatusWithdrawn = "withdrawn"
+)
+
+// attendance outcomes of an application that held a spot, counted towards the volunteer's
+// reliability
+const (
+	AttendanceOutcomeCompleted      = "completed"
+	AttendanceOutcomeNoShow         = "no_show"
+	AttendanceOutcomeLateWithdrawal = "late_withdrawal"
+)
+
 type Task struct {
 	Base
-	Title                   string          `json:"title"`
-	Description             string          `json:"description"`
-	RequiredVolunteersCount int             `json:"required_volunteers_count"`
-	RequiredSkills          []string        `bun:",array" json:"required_skills"`
-	Media                   []*TaskMedia    `bun:"rel:has-many,join:id=task_id" json:"media"`
-	Latitude                float64         `json:"latitude"`
-	Longitude               float64         `json:"longitude"`
-	Location                PostgisGeometry `bun:"type:location" json:"-"`
-	FormattedAddress        string          `json:"formatted_address"`
-	UserID                  uuid.UUID       `bun:"type:uuid" json:"user_id"`
-	User                    *User           `bun:"rel:belongs-to,join:user_id=id" json:"user"`
-	CategoryID              uuid.UUID       `bun:"type:uuid" json:"category_id"`
-	Category                *Category       `bun:"rel:belongs-to,join:category_id=id" json:"category"`
-	SubscribedUsers         []*UserTask     `bun:"rel:has-many,join:id=task_id" json:"subscribed_users"`
-	IsSubscribed            bool            `json:"is_subscribed" bun:"is_subscribed,scanonly"`
-	Blocked                 bool            `json:"blocked"`
+	Title                    string          `json:"title"`
+	Description              string          `json:"description"`
+	RequiredVolunteersCount  int             `json:"required_volunteers_count"`
+	RequiredSkills           []string        `bun:",array" json:"required_skills"`
+	Media                    []*TaskMedia    `bun:"rel:has-many,join:id=task_id" json:"media"`
+	Latitude                 float64         `json:"latitude"`
+	Longitude                float64         `json:"longitude"`
+	Location                 PostgisGeometry `bun:"type:location" json:"-"`
+	FormattedAddress         string          `json:"formatted_address"`
+	UserID                   uuid.UUID       `bun:"type:uuid" json:"user_id"`
+	User                     *User           `bun:"rel:belongs-to,join:user_id=id" json:"user"`
+	CategoryID               uuid.UUID       `bun:"type:uuid" json:"category_id"`
+	Category                 *Category       `bun:"rel:belongs-to,join:category_id=id" json:"category"`
+	SubscribedUsers          []*UserTask     `bun:"rel:has-many,join:id=task_id" json:"subscribed_users,omitempty"`
+	IsSubscribed             bool            `json:"is_subscribed" bun:"is_subscribed,scanonly"`
+	RemainingSpots           *int            `json:"remaining_spots" bun:"remaining_spots,scanonly"`
+	WaitlistCount            int             `json:"waitlist_count" bun:"waitlist_count,scanonly"`
+	Blocked                  bool            `json:"blocked"`
+	Status                   string          `bun:",nullzero,notnull,default:'open'" json:"status"`
+	AutoAccept               bool            `json:"auto_accept"`
+	StartsAt                 *time.Time      `json:"starts_at"`
+	EndsAt                   *time.Time      `json:"ends_at"`
+	TimeZone                 string          `bun:",nullzero,notnull,default:'UTC'" json:"time_zone"`
+	Shifts                   []*TaskShift    `bun:"rel:has-many,join:id=task_id" json:"shifts"`
+	RecurrenceRule           string          `json:"recurrence_rule"`
+	ParentTaskID             *uuid.UUID      `bun:"type:uuid" json:"parent_task_id"`
+	RecurrenceGeneratedUntil *time.Time      `json:"-"`
+	CheckInRadiusMeters      int             `json:"check_in_radius_meters"`
+	QRCheckInRequired        bool            `bun:"qr_check_in_required" json:"qr_check_in_required"`
+	MinReliabilityScore      int             `json:"min_reliability_score"`
+	OrganizationID           *uuid.UUID      `bun:"type:uuid" json:"organization_id"`
+	Organization             *Organization   `bun:"rel:belongs-to,join:organization_id=id" json:"organization,omitempty"`
+	VerifiedOrganizer        bool            `bun:"verified_organizer,scanonly" json:"verified_organizer"`
+	SearchVector             string          `bun:"search_vector,scanonly" json:"-"`
+	SearchRank               *float64        `bun:"search_rank,scanonly" json:"search_rank,omitempty"`
+	TitleHighlight           string          `bun:"title_highlight,scanonly" json:"title_highlight,omitempty"`
+	DescriptionHighlight     string          `bun:"description_highlight,scanonly" json:"description_highlight,omitempty"`
+	DistanceKm               *float64        `bun:"distance_km,scanonly" json:"distance_km,omitempty"`
+	MatchScore               *float64        `bun:"match_score,scanonly" json:"match_score,omitempty"`
+	Explanation              []string        `bun:"-" json:"explanation,omitempty"`
 }
 
 type UserTask struct {
 	Base
-	UserID uuid.UUID `bun:"type:uuid" json:"user_id"`
-	User   *User     `bun:"rel:belongs-to,join:user_id=id" json:"user"`
-	TaskID uuid.UUID `bun:"type:uuid" json:"task_id"`
-	Task   *Task     `bun:"rel:belongs-to,join:task_id=id" json:"task"`
+	UserID     uuid.UUID  `bun:"type:uuid" json:"user_id"`
+	User       *User      `bun:"rel:belongs-to,join:user_id=id" json:"user"`
+	TaskID     uuid.UUID  `bun:"type:uuid" json:"task_id"`
+	Task       *Task      `bun:"rel:belongs-to,join:task_id=id" json:"task"`
+	Waitlisted bool       `json:"waitlisted"`
+	Status     string     `bun:",nullzero,notnull,default:'accepted'" json:"status"`
+	ShiftID    *uuid.UUID `bun:"type:uuid" json:"shift_id"`
+	Shift      *TaskShift `bun:"rel:belongs-to,join:shift_id=id" json:"shift"`
+	// attendance: RecordedMinutes is computed on check-out, CorrectedMinutes is set by the
+	// organizer and takes precedence in the hours ledger
+	CheckedInAt       *time.Time `json:"checked_in_at"`
+	CheckedOutAt      *time.Time `json:"checked_out_at"`
+	RecordedMinutes   *int       `json:"recorded_minutes"`
+	CorrectedMinutes  *int       `json:"corrected_minutes"`
+	CorrectionNote    string     `json:"correction_note"`
+	CorrectedByUserID *uuid.UUID `bun:"type:uuid" json:"corrected_by_user_id"`
+	AttendanceOutcome string     `bun:",nullzero" json:"attendance_outcome"`
+}
+
+// CreditedMinutes is the time the volunteer is credited with for this application.
+func (ut *UserTask) CreditedMinutes() int {
+	if ut.CorrectedMinutes != nil {
+		return *ut.CorrectedMinutes
+	}
+
+	if ut.RecordedMinutes != nil {
+		return *ut.RecordedMinutes
+	}
+
+	return 0
 }
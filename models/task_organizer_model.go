@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// permissions a task's owners can give its co-organizers
+const (
+	TaskPermissionEditDetails       = "edit_details"
+	TaskPermissionManageVolunteers  = "manage_volunteers"
+	TaskPermissionMessageVolunteers = "message_volunteers"
+)
+
+// TaskOrganizer is a user invited to help organize a task. The invitation only grants its
+// permissions once AcceptedAt is set.
+type TaskOrganizer struct {
+	Base
+	TaskID               uuid.UUID  `bun:"type:uuid" json:"task_id"`
+	Task                 *Task      `bun:"rel:belongs-to,join:task_id=id" json:"task,omitempty"`
+	UserID               uuid.UUID  `bun:"type:uuid" json:"user_id"`
+	User                 *User      `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+	InvitedByUserID      *uuid.UUID `bun:"type:uuid" json:"invited_by_user_id"`
+	CanEditDetails       bool       `json:"can_edit_details"`
+	CanManageVolunteers  bool       `json:"can_manage_volunteers"`
+	CanMessageVolunteers bool       `json:"can_message_volunteers"`
+	AcceptedAt           *time.Time `json:"accepted_at"`
+}
+
+// Can reports whether the co-organizer has been given the permission.
+func (o *TaskOrganizer) Can(permission string) bool {
+	if o.AcceptedAt == nil {
+		return false
+	}
+
+	switch permission {
+	case TaskPermissionEditDetails:
+		return o.CanEditDetails
+	case TaskPermissionManageVolunteers:
+		return o.CanManageVolunteers
+	case TaskPermissionMessageVolunteers:
+		return o.CanMessageVolunteers
+	}
+
+	return false
+}
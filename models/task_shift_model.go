@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TaskShift struct {
+	Base
+	TaskID   uuid.UUID `bun:"type:uuid" json:"task_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Capacity int       `json:"capacity"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +21,48 @@ type User struct {
 	Blocked                bool         `json:"blocked"`
 	UserLocations          UserLocation `bun:"rel:has-one,join:id=user_id" json:"user_location"`
 	ReceiveSMSNotification bool         `json:"receive_sms_notification"`
+	TimeZone               string       `bun:",nullzero,notnull,default:'UTC'" json:"time_zone"`
+	// users without any availability windows or exceptions count as always available
+	AvailabilityWindows     []*AvailabilityWindow    `bun:"rel:has-many,join:id=user_id" json:"availability_windows,omitempty"`
+	AvailabilityExceptions  []*AvailabilityException `bun:"rel:has-many,join:id=user_id" json:"availability_exceptions,omitempty"`
+	NotificationPreferences *NotificationPreferences `bun:"rel:has-one,join:id=user_id" json:"notification_preferences,omitempty"`
+	CompletedTasksCount     int                      `json:"-"`
+	NoShowCount             int                      `json:"-"`
+	LateWithdrawalCount     int                      `json:"-"`
+	// Reliability is only filled in for the user themselves and for organizers reviewing
+	// their applications, see AttachReliability
+	Reliability *Reliability `bun:"-" json:"reliability,omitempty"`
+}
+
+type Reliability struct {
+	CompletedTasks  int      `json:"completed_tasks"`
+	NoShows         int      `json:"no_shows"`
+	LateWithdrawals int      `json:"late_withdrawals"`
+	Score           *float64 `json:"score"`
+}
+
+// ReliabilityScore rates from 0 to 100 how often the user turns up for tasks they signed up
+// for. A late withdrawal counts half as much against the user as a no-show. Users without
+// any history have no score.
+func (u *User) ReliabilityScore() *float64 {
+	total := u.CompletedTasksCount + u.NoShowCount + u.LateWithdrawalCount
+	if total == 0 {
+		return nil
+	}
+
+	score := 100 * (float64(u.CompletedTasksCount) + 0.5*float64(u.LateWithdrawalCount)) / float64(total)
+	score = math.Round(score*10) / 10
+
+	return &score
+}
+
+func (u *User) AttachReliability() {
+	u.Reliability = &Reliability{
+		CompletedTasks:  u.CompletedTasksCount,
+		NoShows:         u.NoShowCount,
+		LateWithdrawals: u.LateWithdrawalCount,
+		Score:           u.ReliabilityScore(),
+	}
 }
 
 type UserLocation struct {
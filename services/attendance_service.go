@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrNotAttending       = errors.New("only accepted volunteers can check in")
+	ErrAlreadyCheckedIn   = errors.New("volunteer has already checked in")
+	ErrNotCheckedIn       = errors.New("volunteer has not checked in")
+	ErrAlreadyCheckedOut  = errors.New("volunteer has already checked out")
+	ErrCheckInNotOpen     = errors.New("check-in is not open for this task")
+	ErrLocationRequired   = errors.New("this task needs your location to check in")
+	ErrOutsideGeofence    = errors.New("you are too far from the task location to check in")
+	ErrInvalidHours       = errors.New("minutes must not be negative")
+	ErrInvalidCheckInArea = errors.New("check-in radius must not be negative")
+)
+
+type CheckInLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// CheckIn records that the volunteer showed up. Volunteers can only check in from shortly
+// before the task (or shift) starts until it ends. A scanned attendance token proves they
+// are on site; without one they must be within the task's check-in radius when it has one,
+// and tasks that require the QR code cannot be checked in to at all.
+func CheckIn(
+	ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID, location *CheckInLocation,
+	token, tokenSecret string,
+) (*models.UserTask, error) {
+	task, userTask, err := fetchAttendance(ctx, db, taskID, userID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	err = validateCheckInWindow(ctx, db, task, shiftID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case token != "":
+		err = VerifyAttendanceToken(tokenSecret, token, taskID, now)
+		if err != nil {
+			return nil, err
+		}
+	case task.QRCheckInRequired:
+		return nil, ErrAttendanceTokenRequired
+	case task.CheckInRadiusMeters > 0:
+		if location == nil {
+			return nil, ErrLocationRequired
+		}
+
+		within, err := isWithinCheckInRadius(ctx, db, task, location)
+		if err != nil {
+			return nil, err
+		}
+
+		if !within {
+			return nil, ErrOutsideGeofence
+		}
+	}
+
+	return checkIn(ctx, db, userTask, now)
+}
+
+// CheckInOnBehalf lets the organizer check a volunteer in, bypassing the check-in window
+// and radius.
+func CheckInOnBehalf(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, error) {
+	_, userTask, err := fetchAttendance(ctx, db, taskID, userID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkIn(ctx, db, userTask, time.Now())
+}
+
+// CheckOut closes the volunteer's attendance and records the minutes spent on the task, up
+// to CheckOutGraceMinutes after the task (or shift) ends.
+func CheckOut(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, error) {
+	task, userTask, err := fetchAttendance(ctx, db, taskID, userID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userTask.CheckedInAt == nil {
+		return nil, ErrNotCheckedIn
+	}
+
+	if userTask.CheckedOutAt != nil {
+		return nil, ErrAlreadyCheckedOut
+	}
+
+	_, endsAt, err := attendanceWindow(ctx, db, task, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	// a volunteer who forgets to check out is credited until shortly after the task ends
+	creditedUntil := now
+	if endsAt != nil && creditedUntil.After(endsAt.Add(constants.CheckOutGraceMinutes*time.Minute)) {
+		creditedUntil = endsAt.Add(constants.CheckOutGraceMinutes * time.Minute)
+	}
+
+	minutes := int(math.Max(0, math.Round(creditedUntil.Sub(*userTask.CheckedInAt).Minutes())))
+
+	userTask.CheckedOutAt = &now
+	userTask.RecordedMinutes = &minutes
+
+	_, err = db.NewUpdate().
+		Model(userTask).
+		Column("checked_out_at", "recorded_minutes").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return userTask, nil
+}
+
+// CorrectHours overrides the minutes a volunteer is credited with, e.g. when they forgot to
+// check out. The recorded check-in/check-out times are kept as they were.
+func CorrectHours(
+	ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID,
+	minutes int, note string, correctedByUserID uuid.UUID,
+) (*models.UserTask, error) {
+	if minutes < 0 {
+		return nil, ErrInvalidHours
+	}
+
+	_, userTask, err := fetchAttendance(ctx, db, taskID, userID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	userTask.CorrectedMinutes = &minutes
+	userTask.CorrectionNote = note
+	userTask.CorrectedByUserID = &correctedByUserID
+
+	_, err = db.NewUpdate().
+		Model(userTask).
+		Column("corrected_minutes", "correction_note", "corrected_by_user_id").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return userTask, nil
+}
+
+// FetchHoursSummary rolls up the minutes credited to the user by task category and by
+// month, with months taken in loc.
+func FetchHoursSummary(ctx context.Context, db bun.IDB, userID uuid.UUID, loc *time.Location) (*models.HoursSummary, error) {
+	summary := &models.HoursSummary{
+		Categories: []models.CategoryHours{},
+		Months:     []models.MonthHours{},
+	}
+
+	err := hoursLedgerQuery(db, userID).
+		ColumnExpr("category.id AS category_id, category.name AS category_name").
+		ColumnExpr("SUM(COALESCE(ut.corrected_minutes, ut.recorded_minutes)) AS minutes").
+		Join("JOIN categories AS category ON category.id = task.category_id").
+		GroupExpr("category.id, category.name").
+		OrderExpr("minutes DESC").
+		Scan(ctx, &summary.Categories)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	err = hoursLedgerQuery(db, userID).
+		ColumnExpr(
+			"to_char(COALESCE(ut.checked_in_at, task.starts_at, ut.created_at) AT TIME ZONE ?, 'YYYY-MM') AS month",
+			loc.String(),
+		).
+		ColumnExpr("SUM(COALESCE(ut.corrected_minutes, ut.recorded_minutes)) AS minutes").
+		GroupExpr("month").
+		OrderExpr("month ASC").
+		Scan(ctx, &summary.Months)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	for _, category := range summary.Categories {
+		summary.TotalMinutes += category.Minutes
+	}
+
+	return summary, nil
+}
+
+func hoursLedgerQuery(db bun.IDB, userID uuid.UUID) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("user_tasks AS ut").
+		Join("JOIN tasks AS task ON task.id = ut.task_id").
+		Where("ut.user_id = ?", userID).
+		Where("ut.status = ?", models.ApplicationStatusAccepted).
+		Where("COALESCE(ut.corrected_minutes, ut.recorded_minutes) IS NOT NULL")
+}
+
+func checkIn(ctx context.Context, db bun.IDB, userTask *models.UserTask, at time.Time) (*models.UserTask, error) {
+	if userTask.CheckedInAt != nil {
+		return nil, ErrAlreadyCheckedIn
+	}
+
+	userTask.CheckedInAt = &at
+
+	_, err := db.NewUpdate().
+		Model(userTask).
+		Column("checked_in_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	err = recordLateCheckIn(ctx, db, userTask)
+	if err != nil {
+		return nil, err
+	}
+
+	return userTask, nil
+}
+
+// fetchAttendance loads the task and the volunteer's application, which must hold a spot.
+func fetchAttendance(
+	ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID,
+) (*models.Task, *models.UserTask, error) {
+	task, err := FetchTaskByID(ctx, db, taskID, models.QueryParam{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userTask, err := fetchApplication(ctx, db, taskID, userID, shiftID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !holdsSpot(userTask) {
+		return nil, nil, ErrNotAttending
+	}
+
+	return &task, userTask, nil
+}
+
+func validateCheckInWindow(ctx context.Context, db bun.IDB, task *models.Task, shiftID *uuid.UUID, now time.Time) error {
+	if task.Status == models.TaskStatusDraft || task.Status == models.TaskStatusCompleted ||
+		task.Status == models.TaskStatusCancelled {
+		return ErrCheckInNotOpen
+	}
+
+	startsAt, endsAt, err := attendanceWindow(ctx, db, task, shiftID)
+	if err != nil {
+		return err
+	}
+
+	if startsAt != nil && now.Before(startsAt.Add(-constants.CheckInOpensMinutesBefore*time.Minute)) {
+		return ErrCheckInNotOpen
+	}
+
+	if endsAt != nil && now.After(*endsAt) {
+		return ErrCheckInNotOpen
+	}
+
+	return nil
+}
+
+// attendanceWindow returns when the task, or the shift of it, starts and ends.
+func attendanceWindow(
+	ctx context.Context, db bun.IDB, task *models.Task, shiftID *uuid.UUID,
+) (*time.Time, *time.Time, error) {
+	if shiftID == nil {
+		return task.StartsAt, task.EndsAt, nil
+	}
+
+	shift := &models.TaskShift{}
+
+	err := db.NewSelect().Model(shift).Where("id = ?", *shiftID).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrShiftNotFound
+	}
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, err.Error())
+	}
+
+	return &shift.StartsAt, &shift.EndsAt, nil
+}
+
+func isWithinCheckInRadius(ctx context.Context, db bun.IDB, task *models.Task, location *CheckInLocation) (bool, error) {
+	within := false
+
+	err := db.NewSelect().
+		TableExpr("tasks").
+		ColumnExpr(
+			"ST_DWithin(ST_MakePoint(?, ?)::geography, location::geography, ?)",
+			location.Longitude, location.Latitude, task.CheckInRadiusMeters,
+		).
+		Where("id = ?", task.ID).
+		Scan(ctx, &within)
+	if err != nil {
+		return false, errors.Wrap(err, err.Error())
+	}
+
+	return within, nil
+}
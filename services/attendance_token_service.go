@@ -0,0 +1,73 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrAttendanceTokensDisabled = errors.New("attendance codes are not configured")
+	ErrInvalidAttendanceToken   = errors.New("invalid attendance code")
+	ErrAttendanceTokenExpired   = errors.New("attendance code has expired, scan the current one")
+	ErrAttendanceTokenRequired  = errors.New("scan the attendance code at the task location to check in")
+)
+
+// IssueAttendanceToken signs a token proving presence at the task, shown to volunteers as a
+// QR code. Tokens are bound to the task and expire shortly after their rotation, so an old
+// screenshot of the code cannot be used to check in.
+func IssueAttendanceToken(secret string, taskID uuid.UUID, now time.Time) (string, time.Time, error) {
+	if secret == "" {
+		return "", time.Time{}, ErrAttendanceTokensDisabled
+	}
+
+	rotation := constants.AttendanceTokenRotationSeconds * time.Second
+	expiresAt := now.Truncate(rotation).Add(2 * rotation)
+	payload := fmt.Sprintf("%s.%d", taskID, expiresAt.Unix())
+
+	return payload + "." + signAttendancePayload(secret, payload), expiresAt, nil
+}
+
+// VerifyAttendanceToken checks that the token was issued for the task and has not expired.
+func VerifyAttendanceToken(secret, token string, taskID uuid.UUID, now time.Time) error {
+	if secret == "" {
+		return ErrAttendanceTokensDisabled
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != taskID.String() {
+		return ErrInvalidAttendanceToken
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(signAttendancePayload(secret, payload))) {
+		return ErrInvalidAttendanceToken
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrInvalidAttendanceToken
+	}
+
+	if now.Unix() > expiresAt {
+		return ErrAttendanceTokenExpired
+	}
+
+	return nil
+}
+
+func signAttendancePayload(secret, payload string) string {
+	mac := hmac.New(sha256.New, utils.DeriveKey(secret, "attendance"))
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"rashikzaman/api/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var ErrInvalidAvailability = errors.New("invalid availability")
+
+// availableForTaskExpr holds when the user whose id it is given is free for the whole of
+// the task aliased as "task", from its start to its end or to midnight, in the user's time
+// zone. Unscheduled tasks suit everyone, and so does every task for users who never said
+// when they are free. On a date with exceptions only the hours of the available ones count,
+// on other dates the weekly windows do.
+const availableForTaskExpr = `(task.starts_at IS NULL OR (
+	SELECT CASE
+		WHEN NOT EXISTS (SELECT 1 FROM availability_windows aw WHERE aw.user_id = u.id)
+			AND NOT EXISTS (SELECT 1 FROM availability_exceptions ae WHERE ae.user_id = u.id)
+			THEN TRUE
+		WHEN EXISTS (
+			SELECT 1 FROM availability_exceptions ae WHERE ae.user_id = u.id AND ae.date = l.starts_at::date
+		) THEN EXISTS (
+			SELECT 1 FROM availability_exceptions ae
+			WHERE ae.user_id = u.id AND ae.date = l.starts_at::date AND ae.available
+			AND ae.start_time::time <= l.starts_at::time AND ae.end_time::time >= l.ends_at
+		)
+		ELSE EXISTS (
+			SELECT 1 FROM availability_windows aw
+			WHERE aw.user_id = u.id AND aw.weekday = EXTRACT(DOW FROM l.starts_at)
+			AND aw.start_time::time <= l.starts_at::time AND aw.end_time::time >= l.ends_at
+		)
+	END
+	FROM users u, LATERAL (
+		SELECT
+			task.starts_at AT TIME ZONE u.time_zone AS starts_at,
+			CASE
+				WHEN (COALESCE(task.ends_at, task.starts_at) AT TIME ZONE u.time_zone)::date
+					> (task.starts_at AT TIME ZONE u.time_zone)::date
+				THEN '24:00'::time
+				ELSE (COALESCE(task.ends_at, task.starts_at) AT TIME ZONE u.time_zone)::time
+			END AS ends_at
+	) l
+	WHERE u.id = ?
+))`
+
+// SetAvailabilityWindows replaces the weekly availability windows of the user.
+func SetAvailabilityWindows(ctx context.Context, db bun.IDB, userID uuid.UUID, windows []*models.AvailabilityWindow) error {
+	for _, window := range windows {
+		if window.Weekday < 0 || window.Weekday > 6 {
+			return fmt.Errorf("%w: weekday must be from 0 (Sunday) to 6", ErrInvalidAvailability)
+		}
+
+		err := validateClockRange(window.StartTime, window.EndTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := db.NewDelete().
+		Model((*models.AvailabilityWindow)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	for _, window := range windows {
+		window.ID = uuid.Nil
+		window.UserID = userID
+
+		err = models.Create(ctx, db, window)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetAvailabilityExceptions replaces the date-specific availability exceptions of the user.
+func SetAvailabilityExceptions(
+	ctx context.Context, db bun.IDB, userID uuid.UUID, exceptions []*models.AvailabilityException,
+) error {
+	for _, exception := range exceptions {
+		_, err := time.Parse("2006-01-02", exception.Date)
+		if err != nil {
+			return fmt.Errorf("%w: invalid date %q, use YYYY-MM-DD", ErrInvalidAvailability, exception.Date)
+		}
+
+		if !exception.Available {
+			if exception.StartTime != "" || exception.EndTime != "" {
+				return fmt.Errorf("%w: unavailable exceptions last all day", ErrInvalidAvailability)
+			}
+
+			continue
+		}
+
+		err = validateClockRange(exception.StartTime, exception.EndTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := db.NewDelete().
+		Model((*models.AvailabilityException)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	for _, exception := range exceptions {
+		exception.ID = uuid.Nil
+		exception.UserID = userID
+
+		err = models.Create(ctx, db, exception)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateClockRange(start, end string) error {
+	startMinutes, err := parseClock(start)
+	if err != nil {
+		return err
+	}
+
+	endMinutes, err := parseClock(end)
+	if err != nil {
+		return err
+	}
+
+	if startMinutes >= endMinutes {
+		return fmt.Errorf("%w: %s is not before %s", ErrInvalidAvailability, start, end)
+	}
+
+	return nil
+}
+
+// parseClock reads a HH:MM time of day into minutes since midnight, 24:00 included.
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok || len(hours) != 2 || len(minutes) != 2 {
+		return 0, fmt.Errorf("%w: invalid time %q, use HH:MM", ErrInvalidAvailability, value)
+	}
+
+	h, hErr := strconv.Atoi(hours)
+	m, mErr := strconv.Atoi(minutes)
+
+	if hErr != nil || mErr != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%w: invalid time %q, use HH:MM", ErrInvalidAvailability, value)
+	}
+
+	return h*60 + m, nil
+}
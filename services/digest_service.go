@@ -0,0 +1,360 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	htmltemplate "html/template"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// digestTask is a task as a digest email lists it.
+type digestTask struct {
+	Title       string
+	Description string
+	Category    string
+	When        string
+	Address     string
+	DistanceKm  string
+}
+
+// digestData is what digest emails are rendered from.
+type digestData struct {
+	FirstName string
+	Frequency string
+	Tasks     []digestTask
+}
+
+var digestTextTemplate = template.Must(template.New("digest").Parse(
+	`Hi {{if .FirstName}}{{.FirstName}}{{else}}there{{end}},
+
+Here are the new tasks near you from the last {{if eq .Frequency "weekly"}}week{{else}}day{{end}}:
+{{range .Tasks}}
+{{.Title}} ({{.Category}}, {{.DistanceKm}} km away)
+{{- if .When}}
+When: {{.When}}{{end}}
+{{- if .Address}}
+Where: {{.Address}}{{end}}
+{{.Description}}
+{{end}}`,
+))
+
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest").Parse(
+	`<p>Hi {{if .FirstName}}{{.FirstName}}{{else}}there{{end}},</p>
+<p>Here are the new tasks near you from the last {{if eq .Frequency "weekly"}}week{{else}}day{{end}}:</p>
+{{range .Tasks}}<h3>{{.Title}}</h3>
+<p><em>{{.Category}}, {{.DistanceKm}} km away</em></p>
+{{if .When}}<p>When: {{.When}}</p>
+{{end}}{{if .Address}}<p>Where: {{.Address}}</p>
+{{end}}<p>{{.Description}}</p>
+{{end}}`,
+))
+
+// errDigestNotSent rolls back the records of a digest that could not be emailed.
+var errDigestNotSent = errors.New("digest could not be sent")
+
+// SendDueDigests emails every user in digest mode whose digest is due at now the open tasks
+// created near them since their last digest, within their alert radius and following their
+// categories and skills when they narrowed their alerts to some. The tasks sent are recorded
+// so that no task is sent to a user twice. Each user's digest is sent in its own
+// transaction, claiming their preferences with FOR UPDATE SKIP LOCKED so that instances
+// running it at the same time do not both send it. It returns how many digests were sent
+// and how many failed to send, those users get their digest on the next run.
+func SendDueDigests(ctx context.Context, db bun.IDB, smtpConfig SMTPConfig, now time.Time) (int, int, error) {
+	subscriberIDs := []uuid.UUID{}
+
+	err := db.NewSelect().
+		Model((*models.NotificationPreferences)(nil)).
+		Column("id").
+		Where("digest_frequency <> ?", models.DigestFrequencyOff).
+		Scan(ctx, &subscriberIDs)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, err.Error())
+	}
+
+	sent, failed := 0, 0
+
+	for _, preferencesID := range subscriberIDs {
+		delivered := false
+
+		err = models.WithTransaction(ctx, db, func(tx *bun.Tx) error {
+			var err error
+			delivered, err = sendDigest(ctx, tx, smtpConfig, preferencesID, now)
+
+			return err
+		})
+		if errors.Is(err, errDigestNotSent) {
+			failed++
+			continue
+		}
+
+		if err != nil {
+			return sent, failed, err
+		}
+
+		if delivered {
+			sent++
+		}
+	}
+
+	return sent, failed, nil
+}
+
+// sendDigest sends the user with the preferences their digest when it is due, unless another
+// run is sending it. The digest is recorded before it is emailed, and the email is the last
+// thing done before the transaction commits. It reports whether a digest was sent.
+func sendDigest(
+	ctx context.Context, db bun.IDB, smtpConfig SMTPConfig, preferencesID uuid.UUID, now time.Time,
+) (bool, error) {
+	preferences := &models.NotificationPreferences{}
+
+	err := db.NewSelect().
+		Model(preferences).
+		Relation("User").
+		Where("notification_preferences.id = ?", preferencesID).
+		For("UPDATE OF notification_preferences SKIP LOCKED").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, errors.Wrap(err, err.Error())
+	}
+
+	user := preferences.User
+
+	// checked again on the locked row, another run may have just sent it
+	if user == nil || user.Email == nil || *user.Email == "" ||
+		!DigestDue(preferences.DigestFrequency, preferences.LastDigestAt, user.TimeZone, now) {
+		return false, nil
+	}
+
+	tasks, err := fetchDigestTasks(ctx, db, preferences, now)
+	if err != nil {
+		return false, err
+	}
+
+	// a digest with nothing new in it is not sent, but it is not due again until the next one
+	_, err = db.NewUpdate().
+		Model(preferences).
+		Set("last_digest_at = ?", now).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, err.Error())
+	}
+
+	if len(tasks) == 0 {
+		return false, nil
+	}
+
+	err = recordDigest(ctx, db, preferences, tasks)
+	if err != nil {
+		return false, err
+	}
+
+	subject, text, html, err := renderDigest(user, preferences.DigestFrequency, tasks)
+	if err != nil {
+		return false, err
+	}
+
+	err = SendHTMLEmail(smtpConfig, *user.Email, subject, text, html)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", errDigestNotSent, err.Error())
+	}
+
+	return true, nil
+}
+
+// DigestDue reports whether a user whose last digest went out at lastDigestAt is due one at
+// now. Digests go out at DigestHour in the user's time zone, every day or on Mondays.
+func DigestDue(frequency string, lastDigestAt *time.Time, timeZone string, now time.Time) bool {
+	if frequency != models.DigestFrequencyDaily && frequency != models.DigestFrequencyWeekly {
+		return false
+	}
+
+	if lastDigestAt == nil {
+		return true
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+
+	// the last time a digest was to go out
+	slot := time.Date(local.Year(), local.Month(), local.Day(), constants.DigestHour, 0, 0, 0, location)
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+
+	if frequency == models.DigestFrequencyWeekly {
+		for slot.Weekday() != time.Monday {
+			slot = slot.AddDate(0, 0, -1)
+		}
+	}
+
+	return lastDigestAt.Before(slot)
+}
+
+// fetchDigestTasks lists the tasks for the next digest of the user, nearest first.
+func fetchDigestTasks(
+	ctx context.Context, db bun.IDB, preferences *models.NotificationPreferences, now time.Time,
+) ([]models.Task, error) {
+	tasks := []models.Task{}
+
+	location := &models.UserLocation{}
+
+	err := db.NewSelect().Model(location).Where("user_id = ?", preferences.UserID).Limit(1).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tasks, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	since := now.AddDate(0, 0, -1)
+	if preferences.DigestFrequency == models.DigestFrequencyWeekly {
+		since = now.AddDate(0, 0, -7)
+	}
+
+	if preferences.LastDigestAt != nil {
+		since = *preferences.LastDigestAt
+	}
+
+	radiusKm := preferences.AlertRadiusKm
+	if radiusKm == 0 {
+		radiusKm = constants.NearbyTaskNotificationKm
+	}
+
+	query := db.NewSelect().
+		Model(&tasks).
+		Column("task.*").
+		ColumnExpr(taskDistanceExpr+" AS distance_km", location.Longitude, location.Latitude).
+		Relation("Category").
+		Where("task.status = ?", models.TaskStatusOpen).
+		Where("task.blocked = FALSE").
+		Where("task.recurrence_rule = ''").
+		Where("task.ends_at IS NULL OR task.ends_at > ?", now).
+		Where("task.user_id <> ?", preferences.UserID).
+		Where("task.created_at > ?", since).
+		Where(
+			"ST_DWithin(ST_MakePoint(?, ?)::geography, task.location::geography, ?)",
+			location.Longitude, location.Latitude, radiusKm*1000,
+		).
+		Where("NOT EXISTS (SELECT 1 FROM digest_tasks dt WHERE dt.task_id = task.id AND dt.user_id = ?)", preferences.UserID).
+		OrderExpr("distance_km ASC").
+		Limit(constants.DigestMaxTasks)
+
+	if len(preferences.CategoryIDs) > 0 {
+		query.Where("task.category_id IN (?)", bun.In(preferences.CategoryIDs))
+	}
+
+	if len(preferences.Skills) > 0 {
+		query.Where("task.required_skills && ?", pgdialect.Array(preferences.Skills))
+	}
+
+	err = query.Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return tasks, nil
+}
+
+func renderDigest(user *models.User, frequency string, tasks []models.Task) (string, string, string, error) {
+	location, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	data := digestData{FirstName: user.FirstName, Frequency: frequency}
+
+	for _, task := range tasks {
+		listed := digestTask{
+			Title:       task.Title,
+			Description: task.Description,
+			Address:     task.FormattedAddress,
+		}
+
+		if task.Category != nil {
+			listed.Category = task.Category.Name
+		}
+
+		if task.StartsAt != nil {
+			listed.When = task.StartsAt.In(location).Format("Mon Jan 2, 15:04")
+		}
+
+		if task.DistanceKm != nil {
+			listed.DistanceKm = fmt.Sprintf("%.1f", *task.DistanceKm)
+		}
+
+		data.Tasks = append(data.Tasks, listed)
+	}
+
+	text := bytes.Buffer{}
+
+	err = digestTextTemplate.Execute(&text, data)
+	if err != nil {
+		return "", "", "", errors.Wrap(err, err.Error())
+	}
+
+	html := bytes.Buffer{}
+
+	err = digestHTMLTemplate.Execute(&html, data)
+	if err != nil {
+		return "", "", "", errors.Wrap(err, err.Error())
+	}
+
+	subject := fmt.Sprintf("Your %s digest: %d new tasks near you", frequency, len(tasks))
+	if len(tasks) == 1 {
+		subject = fmt.Sprintf("Your %s digest: 1 new task near you", frequency)
+	}
+
+	return subject, text.String(), html.String(), nil
+}
+
+// recordDigest records the digest sent to the user with the tasks it included.
+func recordDigest(
+	ctx context.Context, db bun.IDB, preferences *models.NotificationPreferences, tasks []models.Task,
+) error {
+	digest := &models.Digest{UserID: preferences.UserID, Frequency: preferences.DigestFrequency}
+
+	err := models.Create(ctx, db, digest)
+	if err != nil {
+		return err
+	}
+
+	digestTasks := make([]*models.DigestTask, 0, len(tasks))
+
+	for _, task := range tasks {
+		digestTasks = append(digestTasks, &models.DigestTask{
+			DigestID: digest.ID,
+			UserID:   preferences.UserID,
+			TaskID:   task.ID,
+		})
+	}
+
+	_, err = db.NewInsert().
+		Model(&digestTasks).
+		On("CONFLICT (user_id, task_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
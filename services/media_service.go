@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	S3BucketName = "act-local"
+	S3Region     = "ap-southeast-1"
+	S3FolderPath = "uploads/images"
+	// S3PrivateBucketName holds the documents organizers send in for verification. Unlike
+	// the task images it is not publicly readable, admins reach the documents through
+	// presigned URLs.
+	S3PrivateBucketName   = "act-local-private"
+	S3DocumentsFolderPath = "uploads/documents"
+)
+
+var ErrMediaStorageUnavailable = errors.New("file uploads are not available right now")
+
+// uploadMedia stores a base64 data URI in the public media bucket under folder and returns
+// the URL and mime type of the stored file.
+func uploadMedia(
+	ctx context.Context, awsAccessKey, awsSecretAccessKey, folder, encoded string,
+) (string, string, error) {
+	filename, mimetype, err := putObject(ctx, awsAccessKey, awsSecretAccessKey, S3BucketName, folder, encoded)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Generate the S3 URL for the uploaded file
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", S3BucketName, S3Region, filename), mimetype, nil
+}
+
+// uploadPrivateMedia stores a base64 data URI in the private bucket under folder and
+// returns the key and mime type of the stored file, see PresignMediaURL.
+func uploadPrivateMedia(
+	ctx context.Context, awsAccessKey, awsSecretAccessKey, folder, encoded string,
+) (string, string, error) {
+	return putObject(ctx, awsAccessKey, awsSecretAccessKey, S3PrivateBucketName, folder, encoded)
+}
+
+// PresignMediaURL returns a link to a file of the private bucket that stays valid for
+// DocumentURLExpiryMinutes.
+func PresignMediaURL(ctx context.Context, awsAccessKey, awsSecretAccessKey, key string) (string, error) {
+	if awsAccessKey == "" || awsSecretAccessKey == "" {
+		return "", ErrMediaStorageUnavailable
+	}
+
+	client := s3.NewPresignClient(newS3Client(awsAccessKey, awsSecretAccessKey))
+
+	request, err := client.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(S3PrivateBucketName),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(constants.DocumentURLExpiryMinutes*time.Minute))
+	if err != nil {
+		return "", errors.Wrap(err, err.Error())
+	}
+
+	return request.URL, nil
+}
+
+func putObject(
+	ctx context.Context, awsAccessKey, awsSecretAccessKey, bucket, folder, encoded string,
+) (string, string, error) {
+	decoded, err := utils.DecodeBase64Image(encoded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return "", "", err
+	}
+
+	mimetype, err := utils.GetMimeTypeFromBase64(encoded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return "", "", err
+	}
+
+	fileExt := ""
+	switch mimetype {
+	case "image/jpeg":
+		fileExt = ".jpg"
+	case "image/png":
+		fileExt = ".png"
+	case "image/gif":
+		fileExt = ".gif"
+	case "application/pdf":
+		fileExt = ".pdf"
+	default:
+		fileExt = ".bin"
+	}
+
+	filename := fmt.Sprintf("%s/%s%s", folder, uuid.New().String(), fileExt)
+
+	client := newS3Client(awsAccessKey, awsSecretAccessKey)
+
+	uploadParams := &s3.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(filename),
+		Body:          bytes.NewReader(decoded),
+		ContentType:   aws.String(mimetype),
+		ContentLength: aws.Int64(int64(len(decoded))),
+	}
+
+	// Upload to S3
+	_, err = client.PutObject(ctx, uploadParams)
+	if err != nil {
+		fmt.Printf("Error uploading to S3: %v\n", err)
+		return "", "", errors.Wrap(err, "failed to upload file to S3")
+	}
+
+	return filename, mimetype, nil
+}
+
+func newS3Client(awsAccessKey, awsSecretAccessKey string) *s3.Client {
+	cfg := aws.Config{
+		Region:      S3Region,
+		Credentials: credentials.NewStaticCredentialsProvider(awsAccessKey, awsSecretAccessKey, ""),
+	}
+
+	return s3.NewFromConfig(cfg)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// inAppNotificationExpr keeps the notifications sent to the user's in-app inbox, leaving out
+// those they only wanted on other channels.
+const inAppNotificationExpr = `EXISTS (
+	SELECT 1 FROM notification_deliveries nd WHERE nd.notification_id = notification.id AND nd.channel = ?
+)`
+
+// inboxKeyset orders the inbox newest first.
+var inboxKeyset = []utils.KeysetColumn{
+	{Name: "created_at", Expr: "notification.created_at", Descending: true},
+	{Name: "id", Expr: "notification.id", Descending: true},
+}
+
+// FetchInbox returns a page of the user's in-app inbox, newest first, following the cursor
+// in queryParam. With unreadOnly only the notifications they have not read are listed.
+func FetchInbox(
+	ctx context.Context, db bun.IDB, userID uuid.UUID, queryParam models.QueryParam, unreadOnly bool,
+) ([]models.Notification, int, error) {
+	notifications := []models.Notification{}
+
+	query := inboxQuery(db, &notifications, userID)
+
+	if unreadOnly {
+		query.Where("notification.read_at IS NULL")
+	}
+
+	count, err := queryParam.Pagination.BuildKeysetPaginationQuery(ctx, query, inboxKeyset)
+	if err != nil {
+		return notifications, 0, errors.Wrap(err, err.Error())
+	}
+
+	err = query.Scan(ctx)
+	if err != nil {
+		return notifications, 0, errors.Wrap(err, err.Error())
+	}
+
+	return notifications, count, nil
+}
+
+// NextInboxCursor returns the cursor of the page following notifications, or an empty string
+// when notifications is the last page.
+func NextInboxCursor(pagination utils.PaginationConfig, notifications []models.Notification) (string, error) {
+	if len(notifications) == 0 {
+		return "", nil
+	}
+
+	last := notifications[len(notifications)-1]
+
+	return pagination.NextCursor(len(notifications), inboxKeyset, last.CreatedAt, last.ID)
+}
+
+// CountUnreadNotifications counts the notifications in the user's in-app inbox they have
+// not read.
+func CountUnreadNotifications(ctx context.Context, db bun.IDB, userID uuid.UUID) (int, error) {
+	count, err := inboxQuery(db, (*models.Notification)(nil), userID).
+		Where("notification.read_at IS NULL").
+		Count(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, err.Error())
+	}
+
+	return count, nil
+}
+
+// MarkNotificationRead marks the notification in the user's inbox read, or unread again.
+// Reading a notification twice keeps the time it was first read.
+func MarkNotificationRead(
+	ctx context.Context, db bun.IDB, userID, notificationID uuid.UUID, read bool,
+) (*models.Notification, error) {
+	notification := &models.Notification{}
+
+	query := db.NewUpdate().
+		Model(notification).
+		Where("notification.id = ?", notificationID).
+		Where("notification.user_id = ?", userID).
+		Where(inAppNotificationExpr, models.NotificationChannelInApp).
+		Returning("*")
+
+	if read {
+		query.Set("read_at = COALESCE(notification.read_at, ?)", time.Now())
+	} else {
+		query.Set("read_at = NULL")
+	}
+
+	result, err := query.Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	if affected == 0 {
+		return nil, ErrNotificationNotFound
+	}
+
+	return notification, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification in the user's inbox read and
+// returns how many there were.
+func MarkAllNotificationsRead(ctx context.Context, db bun.IDB, userID uuid.UUID) (int, error) {
+	result, err := db.NewUpdate().
+		Model((*models.Notification)(nil)).
+		Set("read_at = ?", time.Now()).
+		Where("notification.user_id = ?", userID).
+		Where("notification.read_at IS NULL").
+		Where(inAppNotificationExpr, models.NotificationChannelInApp).
+		Exec(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, err.Error())
+	}
+
+	return int(affected), nil
+}
+
+func inboxQuery(db bun.IDB, model interface{}, userID uuid.UUID) *bun.SelectQuery {
+	return db.NewSelect().
+		Model(model).
+		Where("notification.user_id = ?", userID).
+		Where(inAppNotificationExpr, models.NotificationChannelInApp)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+// DeliverPending claims up to limit outbox jobs that are due and sends their deliveries. Each
+// job is claimed with FOR UPDATE SKIP LOCKED and settled in its own transaction, so workers
+// running it at the same time never send the same delivery twice, and a failure to settle
+// one job does not undo the ones already sent. Sent and skipped deliveries have their job
+// deleted. Failed ones are retried with exponential backoff, and dead-lettered once they run
+// out of attempts or fail for good. It stops early when ctx is cancelled and returns how
+// many jobs it claimed.
+func (s *NotificationService) DeliverPending(ctx context.Context, db bun.IDB, limit int) (int, error) {
+	claimed := 0
+
+	for claimed < limit && ctx.Err() == nil {
+		// a job being sent is settled even when ctx is cancelled meanwhile, so that it is not
+		// sent again by the next worker
+		jobCtx := context.WithoutCancel(ctx)
+		found := false
+
+		err := models.WithTransaction(jobCtx, db, func(tx *bun.Tx) error {
+			job, err := claimOutboxJob(jobCtx, tx)
+			if err != nil || job == nil {
+				return err
+			}
+
+			found = true
+
+			recipient, err := fetchOutboxRecipient(jobCtx, tx, job.Delivery.Notification.UserID)
+			if err != nil {
+				return err
+			}
+
+			sendErr := s.deliver(jobCtx, tx, job.Delivery, recipient)
+
+			return settleOutboxJob(jobCtx, tx, job, sendErr)
+		})
+		if err != nil {
+			return claimed, err
+		}
+
+		if !found {
+			break
+		}
+
+		claimed++
+	}
+
+	return claimed, nil
+}
+
+func (s *NotificationService) deliver(
+	ctx context.Context, db bun.IDB, delivery *models.NotificationDelivery, recipient *models.User,
+) error {
+	if recipient == nil {
+		return ErrRecipientUnreachable
+	}
+
+	for _, notifier := range s.notifiers {
+		if notifier.Channel() == delivery.Channel {
+			return notifier.Send(ctx, db, recipient, delivery.Notification)
+		}
+	}
+
+	return fmt.Errorf("%w: the %s channel is not configured", ErrPermanentDeliveryFailure, delivery.Channel)
+}
+
+// claimOutboxJob locks the next due job for the transaction, or returns nil when there is
+// none left.
+func claimOutboxJob(ctx context.Context, db bun.IDB) (*models.NotificationOutboxJob, error) {
+	job := &models.NotificationOutboxJob{}
+
+	err := db.NewSelect().
+		Model(job).
+		Relation("Delivery").
+		Relation("Delivery.Notification").
+		Where("notification_outbox_job.status = ?", models.NotificationOutboxJobStatusPending).
+		Where("notification_outbox_job.run_at <= NOW()").
+		Order("notification_outbox_job.run_at ASC").
+		Limit(1).
+		For("UPDATE OF notification_outbox_job SKIP LOCKED").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return job, nil
+}
+
+// fetchOutboxRecipient loads the user a delivery goes to, or returns nil when the user is
+// gone.
+func fetchOutboxRecipient(ctx context.Context, db bun.IDB, userID uuid.UUID) (*models.User, error) {
+	user := &models.User{}
+
+	err := db.NewSelect().Model(user).Where("id = ?", userID).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return user, nil
+}
+
+// settleOutboxJob records on the job and its delivery how sending went.
+func settleOutboxJob(ctx context.Context, db bun.IDB, job *models.NotificationOutboxJob, sendErr error) error {
+	delivery := job.Delivery
+	job.Attempts++
+
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		delivery.Status = models.NotificationDeliveryStatusSent
+		delivery.Error = ""
+		delivery.SentAt = &now
+	case errors.Is(sendErr, ErrRecipientUnreachable):
+		delivery.Status = models.NotificationDeliveryStatusSkipped
+		delivery.Error = ""
+	case errors.Is(sendErr, ErrPermanentDeliveryFailure) || job.Attempts >= constants.NotificationMaxAttempts:
+		delivery.Status = models.NotificationDeliveryStatusFailed
+		delivery.Error = sendErr.Error()
+		job.Status = models.NotificationOutboxJobStatusDead
+		job.LastError = sendErr.Error()
+	default:
+		delivery.Error = sendErr.Error()
+		job.RunAt = time.Now().Add(NotificationRetryDelay(job.Attempts))
+		job.LastError = sendErr.Error()
+	}
+
+	_, err := db.NewUpdate().
+		Model(delivery).
+		Column("status", "error", "sent_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	if delivery.Status == models.NotificationDeliveryStatusSent || delivery.Status == models.NotificationDeliveryStatusSkipped {
+		_, err = db.NewDelete().Model(job).WherePK().Exec(ctx)
+	} else {
+		_, err = db.NewUpdate().
+			Model(job).
+			Column("status", "attempts", "run_at", "last_error").
+			WherePK().
+			Exec(ctx)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// NotificationRetryDelay is how long to wait before trying a notification again after it
+// failed attempts times.
+func NotificationRetryDelay(attempts int) time.Duration {
+	delay := constants.NotificationRetryBaseSeconds * time.Second
+
+	for i := 1; i < attempts && delay < constants.NotificationRetryMaxSeconds*time.Second; i++ {
+		delay *= 2
+	}
+
+	return min(delay, constants.NotificationRetryMaxSeconds*time.Second)
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var ErrInvalidNotificationPreferences = errors.New("invalid notification preferences")
+
+// SetNotificationPreferences replaces the notification preferences of the user. Skills are
+// resolved against the taxonomy and stored by their canonical names.
+func SetNotificationPreferences(
+	ctx context.Context, db bun.IDB, userID uuid.UUID, preferences *models.NotificationPreferences,
+) error {
+	if preferences.AlertRadiusKm == 0 {
+		preferences.AlertRadiusKm = constants.NearbyTaskNotificationKm
+	}
+
+	if preferences.AlertRadiusKm < 1 || preferences.AlertRadiusKm > constants.NotificationMaxRadiusKm {
+		return fmt.Errorf(
+			"%w: alert radius must be from 1 to %d km", ErrInvalidNotificationPreferences, constants.NotificationMaxRadiusKm,
+		)
+	}
+
+	if preferences.DailyCap != nil && *preferences.DailyCap < 1 {
+		return fmt.Errorf("%w: daily cap must be at least 1", ErrInvalidNotificationPreferences)
+	}
+
+	switch preferences.DigestFrequency {
+	case "":
+		preferences.DigestFrequency = models.DigestFrequencyOff
+	case models.DigestFrequencyOff, models.DigestFrequencyDaily, models.DigestFrequencyWeekly:
+	default:
+		return fmt.Errorf("%w: digest frequency must be off, daily or weekly", ErrInvalidNotificationPreferences)
+	}
+
+	err := validateQuietHours(preferences.QuietHoursStart, preferences.QuietHoursEnd)
+	if err != nil {
+		return err
+	}
+
+	err = validateEventChannels(preferences.EventChannels)
+	if err != nil {
+		return err
+	}
+
+	if preferences.EventChannels == nil {
+		preferences.EventChannels = map[string][]string{}
+	}
+
+	if preferences.CategoryIDs == nil {
+		preferences.CategoryIDs = []uuid.UUID{}
+	}
+
+	if len(preferences.CategoryIDs) > 0 {
+		count, err := db.NewSelect().
+			Model((*models.Category)(nil)).
+			Where("id IN (?)", bun.In(preferences.CategoryIDs)).
+			Count(ctx)
+		if err != nil {
+			return errors.Wrap(err, err.Error())
+		}
+
+		if count != len(preferences.CategoryIDs) {
+			return fmt.Errorf("%w: unknown or repeated category", ErrInvalidNotificationPreferences)
+		}
+	}
+
+	preferences.Skills, err = ResolveSkillNames(ctx, db, preferences.Skills)
+	if err != nil {
+		return err
+	}
+
+	preferences.ID = uuid.Nil
+	preferences.UserID = userID
+	// only sending a digest moves it on
+	preferences.LastDigestAt = nil
+
+	_, err = db.NewInsert().
+		Model(preferences).
+		On("CONFLICT (user_id) DO UPDATE").
+		Set("alert_radius_km = EXCLUDED.alert_radius_km").
+		Set("category_ids = EXCLUDED.category_ids").
+		Set("skills = EXCLUDED.skills").
+		Set("event_channels = EXCLUDED.event_channels").
+		Set("quiet_hours_start = EXCLUDED.quiet_hours_start").
+		Set("quiet_hours_end = EXCLUDED.quiet_hours_end").
+		Set("daily_cap = EXCLUDED.daily_cap").
+		Set("digest_frequency = EXCLUDED.digest_frequency").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// FetchNotificationPreferences returns the notification preferences of the users who set
+// them, by user id.
+func FetchNotificationPreferences(
+	ctx context.Context, db bun.IDB, userIDs []uuid.UUID,
+) (map[uuid.UUID]*models.NotificationPreferences, error) {
+	byUser := map[uuid.UUID]*models.NotificationPreferences{}
+
+	if len(userIDs) == 0 {
+		return byUser, nil
+	}
+
+	preferences := []*models.NotificationPreferences{}
+
+	err := db.NewSelect().
+		Model(&preferences).
+		Where("user_id IN (?)", bun.In(userIDs)).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	for _, preference := range preferences {
+		byUser[preference.UserID] = preference
+	}
+
+	return byUser, nil
+}
+
+// QuietUntil returns when the quiet hours the user is in at now end, in their time zone,
+// and false when they are not in quiet hours.
+func QuietUntil(preferences *models.NotificationPreferences, timeZone string, now time.Time) (time.Time, bool) {
+	if preferences == nil || preferences.QuietHoursStart == "" {
+		return time.Time{}, false
+	}
+
+	start, startErr := parseClock(preferences.QuietHoursStart)
+	end, endErr := parseClock(preferences.QuietHoursEnd)
+
+	if startErr != nil || endErr != nil {
+		return time.Time{}, false
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	minutes := local.Hour()*60 + local.Minute()
+
+	// quiet hours such as 22:00 to 07:00 run over midnight
+	quiet := start <= minutes && minutes < end
+	if start > end {
+		quiet = minutes >= start || minutes < end
+	}
+
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, location)
+	if !until.After(now) {
+		until = time.Date(local.Year(), local.Month(), local.Day()+1, end/60, end%60, 0, 0, location)
+	}
+
+	return until, true
+}
+
+func validateQuietHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+
+	startMinutes, startErr := parseClock(start)
+	endMinutes, endErr := parseClock(end)
+
+	if startErr != nil || endErr != nil || startMinutes == endMinutes || startMinutes == 24*60 {
+		return fmt.Errorf(
+			"%w: quiet hours need a start and a different end, written HH:MM", ErrInvalidNotificationPreferences,
+		)
+	}
+
+	return nil
+}
+
+func validateEventChannels(eventChannels map[string][]string) error {
+	for event, channels := range eventChannels {
+		if _, ok := notificationTemplates[event]; !ok {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidNotificationPreferences, event)
+		}
+
+		for _, channel := range channels {
+			switch channel {
+			case models.NotificationChannelSMS, models.NotificationChannelEmail,
+				models.NotificationChannelInApp, models.NotificationChannelPush:
+			default:
+				return fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationPreferences, channel)
+			}
+		}
+
+		if channels == nil {
+			eventChannels[event] = []string{}
+		}
+	}
+
+	return nil
+}
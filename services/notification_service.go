@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+// events users are notified of
+const (
+	NotificationEventTaskCreatedNearby     = "task_created_nearby"
+	NotificationEventApplicationAccepted   = "application_accepted"
+	NotificationEventApplicationWaitlisted = "application_waitlisted"
+	NotificationEventApplicationRejected   = "application_rejected"
+	NotificationEventApplicationReceived   = "application_received"
+	NotificationEventPromotedFromWaitlist  = "promoted_from_waitlist"
+	NotificationEventTaskStarted           = "task_started"
+	NotificationEventTaskCompleted         = "task_completed"
+	NotificationEventTaskCancelled         = "task_cancelled"
+	NotificationEventTaskMessage           = "task_message"
+)
+
+// defaultEventChannels are the channels of the event types that do not go out on every
+// channel to users who did not pick their channels. Alerts of new tasks are only emailed
+// and pushed to users who ask for them, texts still need ReceiveSMSNotification.
+var defaultEventChannels = map[string][]string{
+	NotificationEventTaskCreatedNearby: {models.NotificationChannelInApp, models.NotificationChannelSMS},
+}
+
+var (
+	ErrUnknownNotificationEvent = errors.New("unknown notification event")
+	// ErrRecipientUnreachable is returned by notifiers for recipients who cannot get
+	// notifications on their channel. The delivery is recorded as skipped.
+	ErrRecipientUnreachable = errors.New("recipient cannot be reached on this channel")
+	// ErrPermanentDeliveryFailure is wrapped by notifiers around failures that trying again
+	// will not fix, such as an invalid phone number. The delivery is dead-lettered right away.
+	ErrPermanentDeliveryFailure = errors.New("permanent delivery failure")
+)
+
+// Notifier sends notifications on a channel.
+type Notifier interface {
+	Channel() string
+	Send(ctx context.Context, db bun.IDB, recipient *models.User, notification *models.Notification) error
+}
+
+// NotificationData is what the templates of an event are rendered with.
+type NotificationData struct {
+	Task    *models.Task
+	Message string
+}
+
+type notificationTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func newNotificationTemplate(event, subject, body string) notificationTemplate {
+	return notificationTemplate{
+		subject: template.Must(template.New(event + "_subject").Parse(subject)),
+		body:    template.Must(template.New(event + "_body").Parse(body)),
+	}
+}
+
+// notificationTemplates holds the subject and body of every event. Bodies are kept short
+// enough to fit in a text message.
+var notificationTemplates = map[string]notificationTemplate{
+	NotificationEventTaskCreatedNearby: newNotificationTemplate(NotificationEventTaskCreatedNearby,
+		"New task near you: {{.Task.Title}}",
+		"{{.Task.Title}} needs volunteers near you",
+	),
+	NotificationEventApplicationAccepted: newNotificationTemplate(NotificationEventApplicationAccepted,
+		"You have been accepted for {{.Task.Title}}",
+		"You have been accepted for {{.Task.Title}}",
+	),
+	NotificationEventApplicationWaitlisted: newNotificationTemplate(NotificationEventApplicationWaitlisted,
+		"You are on the waitlist for {{.Task.Title}}",
+		"You have been accepted for {{.Task.Title}} and are on the waitlist",
+	),
+	NotificationEventApplicationRejected: newNotificationTemplate(NotificationEventApplicationRejected,
+		"Your application for {{.Task.Title}}",
+		"Your application for {{.Task.Title}} was not accepted",
+	),
+	NotificationEventApplicationReceived: newNotificationTemplate(NotificationEventApplicationReceived,
+		"New application for {{.Task.Title}}",
+		"{{.Message}} applied to {{.Task.Title}} and is waiting for your review",
+	),
+	NotificationEventPromotedFromWaitlist: newNotificationTemplate(NotificationEventPromotedFromWaitlist,
+		"You are now signed up for {{.Task.Title}}",
+		"A spot opened up and you are now signed up for {{.Task.Title}}",
+	),
+	NotificationEventTaskStarted: newNotificationTemplate(NotificationEventTaskStarted,
+		"{{.Task.Title}} has started",
+		"{{.Task.Title}} has started",
+	),
+	NotificationEventTaskCompleted: newNotificationTemplate(NotificationEventTaskCompleted,
+		"{{.Task.Title}} is complete",
+		"{{.Task.Title}} is complete, thank you for volunteering",
+	),
+	NotificationEventTaskCancelled: newNotificationTemplate(NotificationEventTaskCancelled,
+		"{{.Task.Title}} has been cancelled",
+		"{{.Task.Title}} has been cancelled",
+	),
+	NotificationEventTaskMessage: newNotificationTemplate(NotificationEventTaskMessage,
+		"Message about {{.Task.Title}}",
+		"{{.Task.Title}}: {{.Message}}",
+	),
+}
+
+// NotificationService notifies users of events on every channel it has a notifier for.
+type NotificationService struct {
+	notifiers []Notifier
+}
+
+func NewNotificationService(notifiers ...Notifier) *NotificationService {
+	return &NotificationService{notifiers: notifiers}
+}
+
+// Notify renders the templates of the event for every recipient and queues it on the
+// channels they want it on, recording a notification per recipient with a pending delivery
+// and an outbox job per channel. Deliveries falling in a recipient's quiet hours are held
+// until they end. Nothing is sent until the worker picks up the jobs, so calling Notify in
+// the transaction of a change only notifies of the change once it is committed.
+func (s *NotificationService) Notify(
+	ctx context.Context, db bun.IDB, event string, data NotificationData, recipients []*models.User,
+) ([]*models.Notification, error) {
+	subject, body, err := renderNotification(event, data)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []uuid.UUID{}
+	for _, recipient := range recipients {
+		userIDs = append(userIDs, recipient.ID)
+	}
+
+	preferences, err := FetchNotificationPreferences(ctx, db, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := []*models.Notification{}
+
+	for _, recipient := range recipients {
+		notifiers := []Notifier{}
+
+		for _, notifier := range s.notifiers {
+			if preferences[recipient.ID].WantsChannel(event, notifier.Channel(), defaultEventChannels[event]) {
+				notifiers = append(notifiers, notifier)
+			}
+		}
+
+		if len(notifiers) == 0 {
+			continue
+		}
+
+		quietUntil, quiet := QuietUntil(preferences[recipient.ID], recipient.TimeZone, time.Now())
+
+		notification := &models.Notification{
+			UserID:    recipient.ID,
+			EventType: event,
+			Subject:   subject,
+			Body:      body,
+		}
+
+		if data.Task != nil {
+			notification.TaskID = &data.Task.ID
+		}
+
+		err = models.Create(ctx, db, notification)
+		if err != nil {
+			return notifications, err
+		}
+
+		for _, notifier := range notifiers {
+			delivery := &models.NotificationDelivery{
+				NotificationID: notification.ID,
+				Channel:        notifier.Channel(),
+				Status:         models.NotificationDeliveryStatusPending,
+			}
+
+			err = models.Create(ctx, db, delivery)
+			if err != nil {
+				return notifications, err
+			}
+
+			job := &models.NotificationOutboxJob{
+				DeliveryID: delivery.ID,
+				Status:     models.NotificationOutboxJobStatusPending,
+			}
+
+			// the inbox is never intrusive, the other channels wait for the quiet hours to end
+			if quiet && notifier.Channel() != models.NotificationChannelInApp {
+				job.RunAt = quietUntil
+			}
+
+			err = models.Create(ctx, db, job)
+			if err != nil {
+				return notifications, err
+			}
+
+			notification.Deliveries = append(notification.Deliveries, delivery)
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+// NotifyNearbyUsers tells the users around a new task, who are free at its time, about it.
+func (s *NotificationService) NotifyNearbyUsers(ctx context.Context, db bun.IDB, task *models.Task) error {
+	userLocations, err := FetchNearbyUsersOfTask(
+		ctx, db, task.ID, float32(task.Latitude), float32(task.Longitude), constants.NearbyTaskNotificationKm,
+	)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	recipients := []*models.User{}
+
+	for _, location := range userLocations {
+		if location.User != nil {
+			recipients = append(recipients, location.User)
+		}
+	}
+
+	_, err = s.Notify(ctx, db, NotificationEventTaskCreatedNearby, NotificationData{Task: task}, recipients)
+
+	return err
+}
+
+// NotifyTaskSubscribers notifies everyone with an active application to the task of the
+// event.
+func (s *NotificationService) NotifyTaskSubscribers(
+	ctx context.Context, db bun.IDB, event string, task *models.Task, message string,
+) error {
+	userTasks, err := FetchSubscribersForTask(ctx, db, task.ID, ActiveApplicationStatuses...)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	recipients := []*models.User{}
+
+	for _, userTask := range userTasks {
+		if userTask.User != nil {
+			recipients = append(recipients, userTask.User)
+		}
+	}
+
+	_, err = s.Notify(ctx, db, event, NotificationData{Task: task, Message: message}, recipients)
+
+	return err
+}
+
+// NotifyApplicant notifies the applicant of userTask of the event.
+func (s *NotificationService) NotifyApplicant(ctx context.Context, db bun.IDB, event string, userTask *models.UserTask) error {
+	task, err := FetchTaskByID(ctx, db, userTask.TaskID, models.QueryParam{})
+	if err != nil {
+		return err
+	}
+
+	user, err := GetUserByID(ctx, db, userTask.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Notify(ctx, db, event, NotificationData{Task: &task}, []*models.User{user})
+
+	return err
+}
+
+// NotifyApplication tells the applicant of a new application whether they got a spot and,
+// when it waits for review, the organizers who manage the task's volunteers.
+func (s *NotificationService) NotifyApplication(ctx context.Context, db bun.IDB, userTask *models.UserTask) error {
+	event := ApplicationStatusEvent(userTask)
+	if event != "" {
+		return s.NotifyApplicant(ctx, db, event, userTask)
+	}
+
+	if userTask.Status != models.ApplicationStatusPending {
+		return nil
+	}
+
+	task, err := FetchTaskByID(ctx, db, userTask.TaskID, models.QueryParam{})
+	if err != nil {
+		return err
+	}
+
+	applicant, err := GetUserByID(ctx, db, userTask.UserID)
+	if err != nil {
+		return err
+	}
+
+	organizers, err := FetchUsersWithTaskPermission(ctx, db, task, models.TaskPermissionManageVolunteers)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(applicant.FirstName + " " + applicant.LastName)
+	if name == "" {
+		name = "A volunteer"
+	}
+
+	_, err = s.Notify(ctx, db, NotificationEventApplicationReceived, NotificationData{Task: &task, Message: name}, organizers)
+
+	return err
+}
+
+// ApplicationStatusEvent returns the event the applicant of userTask is notified of for its
+// status, or an empty string when there is nothing to tell.
+func ApplicationStatusEvent(userTask *models.UserTask) string {
+	switch {
+	case userTask.Status == models.ApplicationStatusAccepted && userTask.Waitlisted:
+		return NotificationEventApplicationWaitlisted
+	case userTask.Status == models.ApplicationStatusAccepted:
+		return NotificationEventApplicationAccepted
+	case userTask.Status == models.ApplicationStatusRejected:
+		return NotificationEventApplicationRejected
+	}
+
+	return ""
+}
+
+// TaskStatusEvent returns the event the volunteers of a task are notified of when it moves
+// to status, or an empty string when there is nothing to tell.
+func TaskStatusEvent(status string) string {
+	switch status {
+	case models.TaskStatusInProgress:
+		return NotificationEventTaskStarted
+	case models.TaskStatusCompleted:
+		return NotificationEventTaskCompleted
+	case models.TaskStatusCancelled:
+		return NotificationEventTaskCancelled
+	}
+
+	return ""
+}
+
+func renderNotification(event string, data NotificationData) (string, string, error) {
+	tmpl, ok := notificationTemplates[event]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrUnknownNotificationEvent, event)
+	}
+
+	subject := bytes.Buffer{}
+
+	err := tmpl.subject.Execute(&subject, data)
+	if err != nil {
+		return "", "", errors.Wrap(err, err.Error())
+	}
+
+	body := bytes.Buffer{}
+
+	err = tmpl.body.Execute(&body, data)
+	if err != nil {
+		return "", "", errors.Wrap(err, err.Error())
+	}
+
+	return subject.String(), body.String(), nil
+}
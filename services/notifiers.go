@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+// SMSNotifier texts the body of notifications through Twilio to users who opted in to SMS
+// notifications.
+type SMSNotifier struct {
+	TwilioAccountSID  string
+	TwilioPhoneNumber string
+	TwilioAuthToken   string
+}
+
+func (n SMSNotifier) Channel() string {
+	return models.NotificationChannelSMS
+}
+
+func (n SMSNotifier) Send(_ context.Context, _ bun.IDB, recipient *models.User, notification *models.Notification) error {
+	if !recipient.ReceiveSMSNotification || recipient.PhoneNumber == nil || *recipient.PhoneNumber == "" {
+		return ErrRecipientUnreachable
+	}
+
+	return SendSMS(*recipient.PhoneNumber, notification.Body, n.TwilioAccountSID, n.TwilioPhoneNumber, n.TwilioAuthToken)
+}
+
+// EmailNotifier emails notifications over SMTP.
+type EmailNotifier struct {
+	SMTP SMTPConfig
+}
+
+func (n EmailNotifier) Channel() string {
+	return models.NotificationChannelEmail
+}
+
+func (n EmailNotifier) Send(_ context.Context, _ bun.IDB, recipient *models.User, notification *models.Notification) error {
+	if recipient.Email == nil || *recipient.Email == "" {
+		return ErrRecipientUnreachable
+	}
+
+	return SendEmail(n.SMTP, *recipient.Email, notification.Subject, notification.Body)
+}
+
+// InAppNotifier puts notifications in the user's in-app inbox. The notification record is
+// the inbox entry, so there is nothing more to send.
+type InAppNotifier struct{}
+
+func (n InAppNotifier) Channel() string {
+	return models.NotificationChannelInApp
+}
+
+func (n InAppNotifier) Send(context.Context, bun.IDB, *models.User, *models.Notification) error {
+	return nil
+}
+
+// PushNotifier sends notifications to the browsers the user subscribed to web push
+// notifications, signed with the VAPID keys of the application. Subject is the contact
+// of the sender, a mailto: or https: URL.
+type PushNotifier struct {
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	Subject         string
+}
+
+func (n PushNotifier) Channel() string {
+	return models.NotificationChannelPush
+}
+
+// Send pushes the notification to every browser of the recipient, forgetting the ones the
+// push service says are gone. It succeeds when any browser got it.
+func (n PushNotifier) Send(ctx context.Context, db bun.IDB, recipient *models.User, notification *models.Notification) error {
+	subscriptions, err := FetchPushSubscriptions(ctx, db, recipient.ID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"notification_id": notification.ID,
+		"event_type":      notification.EventType,
+		"task_id":         notification.TaskID,
+		"title":           notification.Subject,
+		"body":            notification.Body,
+	})
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	delivered := 0
+	var sendErr error
+
+	for _, subscription := range subscriptions {
+		resp, err := webpush.SendNotificationWithContext(ctx, payload, &webpush.Subscription{
+			Endpoint: subscription.Endpoint,
+			Keys:     webpush.Keys{P256dh: subscription.P256dh, Auth: subscription.Auth},
+		}, &webpush.Options{
+			Subscriber:      n.Subject,
+			VAPIDPublicKey:  n.VAPIDPublicKey,
+			VAPIDPrivateKey: n.VAPIDPrivateKey,
+			TTL:             constants.PushNotificationTTLSeconds,
+		})
+		if err != nil {
+			sendErr = errors.Wrap(err, err.Error())
+			continue
+		}
+
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+			err = DeletePushSubscription(ctx, db, recipient.ID, subscription.Endpoint)
+			if err != nil {
+				return err
+			}
+		case isPermanentHTTPFailure(resp.StatusCode):
+			sendErr = fmt.Errorf("%w: unexpected status code: %d", ErrPermanentDeliveryFailure, resp.StatusCode)
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			sendErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		default:
+			delivered++
+		}
+	}
+
+	if delivered > 0 {
+		return nil
+	}
+
+	if sendErr != nil {
+		return sendErr
+	}
+
+	return ErrRecipientUnreachable
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"rashikzaman/api/models"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrInvalidOrganization       = errors.New("organization name is required")
+	ErrInvalidOrganizationRole   = errors.New("invalid organization role")
+	ErrNotOrganizationMember     = errors.New("user is not a member of this organization")
+	ErrNotOrganizationManager    = errors.New("only owners and managers of the organization can post its tasks")
+	ErrAlreadyOrganizationMember = errors.New("user is already a member of this organization")
+	ErrLastOrganizationOwner     = errors.New("an organization needs at least one owner")
+	ErrUserNotFound              = errors.New("user not found")
+)
+
+func IsValidOrganizationRole(role string) bool {
+	switch role {
+	case models.OrganizationRoleOwner, models.OrganizationRoleManager, models.OrganizationRoleMember:
+		return true
+	}
+
+	return false
+}
+
+// CreateOrganization creates the organization with the user creating it as its first owner.
+func CreateOrganization(ctx context.Context, db bun.IDB, organization *models.Organization, userID uuid.UUID) error {
+	organization.Name = strings.TrimSpace(organization.Name)
+	if organization.Name == "" {
+		return ErrInvalidOrganization
+	}
+
+	err := models.Create(ctx, db, organization)
+	if err != nil {
+		return err
+	}
+
+	return models.Create(ctx, db, &models.OrganizationMember{
+		OrganizationID: organization.ID,
+		UserID:         userID,
+		Role:           models.OrganizationRoleOwner,
+	})
+}
+
+func UpdateOrganization(
+	ctx context.Context, db bun.IDB, organization models.Organization, organizationBody models.Organization,
+) (*models.Organization, error) {
+	organization.Name = strings.TrimSpace(organizationBody.Name)
+	if organization.Name == "" {
+		return nil, ErrInvalidOrganization
+	}
+
+	organization.Description = organizationBody.Description
+	organization.Website = organizationBody.Website
+	organization.LogoURL = organizationBody.LogoURL
+
+	_, err := db.NewUpdate().
+		Model(&organization).
+		Column("name", "description", "website", "logo_url").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return &organization, nil
+}
+
+// FetchOrganizationByID returns the organization along with its members.
+func FetchOrganizationByID(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.Organization, error) {
+	organization := &models.Organization{}
+
+	err := db.NewSelect().
+		Model(organization).
+		Where("organization.id = ?", id).
+		Relation("Members", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("organization_member.created_at ASC")
+		}).
+		Relation("Members.User").
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return organization, nil
+}
+
+// FetchOrganizationsOfUser lists the memberships of the user, with their organizations.
+func FetchOrganizationsOfUser(ctx context.Context, db bun.IDB, userID uuid.UUID) ([]models.OrganizationMember, error) {
+	memberships := []models.OrganizationMember{}
+
+	err := db.NewSelect().
+		Model(&memberships).
+		Where("organization_member.user_id = ?", userID).
+		Relation("Organization").
+		Order("organization.name ASC").
+		Scan(ctx)
+	if err != nil {
+		return memberships, errors.Wrap(err, err.Error())
+	}
+
+	return memberships, nil
+}
+
+// FetchOrganizationMember returns the membership of the user in the organization, or
+// ErrNotOrganizationMember.
+func FetchOrganizationMember(
+	ctx context.Context, db bun.IDB, organizationID, userID uuid.UUID,
+) (*models.OrganizationMember, error) {
+	member := &models.OrganizationMember{}
+
+	err := db.NewSelect().
+		Model(member).
+		Where("organization_id = ?", organizationID).
+		Where("user_id = ?", userID).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotOrganizationMember
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return member, nil
+}
+
+// AddOrganizationMember adds the user with the given email to the organization.
+func AddOrganizationMember(
+	ctx context.Context, db bun.IDB, organizationID uuid.UUID, email, role string,
+) (*models.OrganizationMember, error) {
+	if !IsValidOrganizationRole(role) {
+		return nil, ErrInvalidOrganizationRole
+	}
+
+	user, err := fetchUserByEmail(ctx, db, email)
+	if err != nil {
+		return nil, err
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: organizationID,
+		UserID:         user.ID,
+		Role:           role,
+	}
+
+	result, err := db.NewInsert().
+		Model(member).
+		On("CONFLICT (organization_id, user_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	if affected == 0 {
+		return nil, ErrAlreadyOrganizationMember
+	}
+
+	member.User = user
+
+	return member, nil
+}
+
+// ChangeOrganizationMemberRole changes the role of a member, keeping at least one owner.
+func ChangeOrganizationMemberRole(
+	ctx context.Context, db bun.IDB, organizationID, userID uuid.UUID, role string,
+) (*models.OrganizationMember, error) {
+	if !IsValidOrganizationRole(role) {
+		return nil, ErrInvalidOrganizationRole
+	}
+
+	// locked so that two owners demoting or removing each other at the same time cannot
+	// both count the other one as the remaining owner
+	err := lockOrganization(ctx, db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := FetchOrganizationMember(ctx, db, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member.Role == models.OrganizationRoleOwner && role != models.OrganizationRoleOwner {
+		err = ensureAnotherOwner(ctx, db, organizationID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	member.Role = role
+
+	_, err = db.NewUpdate().
+		Model(member).
+		Column("role").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return member, nil
+}
+
+// RemoveOrganizationMember takes the user out of the organization, keeping at least one
+// owner. The tasks the user posted for the organization stay with it.
+func RemoveOrganizationMember(ctx context.Context, db bun.IDB, organizationID, userID uuid.UUID) error {
+	// locked so that two owners demoting or removing each other at the same time cannot
+	// both count the other one as the remaining owner
+	err := lockOrganization(ctx, db, organizationID)
+	if err != nil {
+		return err
+	}
+
+	member, err := FetchOrganizationMember(ctx, db, organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == models.OrganizationRoleOwner {
+		err = ensureAnotherOwner(ctx, db, organizationID, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = db.NewDelete().
+		Model(member).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// validateTaskOrganization checks that the user may post tasks as the organization.
+func validateTaskOrganization(ctx context.Context, db bun.IDB, organizationID uuid.UUID, userID uuid.UUID) error {
+	member, err := FetchOrganizationMember(ctx, db, organizationID, userID)
+	if errors.Is(err, ErrNotOrganizationMember) {
+		return ErrNotOrganizationManager
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !member.CanManageTasks() {
+		return ErrNotOrganizationManager
+	}
+
+	return nil
+}
+
+func lockOrganization(ctx context.Context, db bun.IDB, organizationID uuid.UUID) error {
+	_, err := db.NewSelect().
+		Model((*models.Organization)(nil)).
+		Column("id").
+		Where("id = ?", organizationID).
+		For("UPDATE").
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+func ensureAnotherOwner(ctx context.Context, db bun.IDB, organizationID, userID uuid.UUID) error {
+	owners, err := db.NewSelect().
+		Model((*models.OrganizationMember)(nil)).
+		Where("organization_id = ?", organizationID).
+		Where("user_id <> ?", userID).
+		Where("role = ?", models.OrganizationRoleOwner).
+		Count(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	if owners == 0 {
+		return ErrLastOrganizationOwner
+	}
+
+	return nil
+}
+
+// fetchUserByEmail looks the user up by email, ignoring case, or returns ErrUserNotFound.
+func fetchUserByEmail(ctx context.Context, db bun.IDB, email string) (*models.User, error) {
+	user := &models.User{}
+
+	err := db.NewSelect().
+		Model(user).
+		Where("LOWER(email) = LOWER(?)", strings.TrimSpace(email)).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return user, nil
+}
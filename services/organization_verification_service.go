@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"rashikzaman/api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrOrganizationAlreadyVerified    = errors.New("organization is already verified")
+	ErrVerificationPending            = errors.New("organization is already waiting for verification")
+	ErrVerificationDocumentsRequired  = errors.New("attach at least one supporting document")
+	ErrNoPendingVerification          = errors.New("organization has no pending verification request")
+	ErrInvalidVerificationAction      = errors.New("invalid verification action")
+	ErrOrganizationVerificationAbsent = errors.New("organization has not asked for verification")
+)
+
+// RequestOrganizationVerification submits the organization for review by the admins, with
+// its supporting documents uploaded to the private bucket.
+func RequestOrganizationVerification(
+	ctx context.Context, db bun.IDB, organizationID, userID uuid.UUID,
+	request *models.OrganizationVerificationRequest, awsAccessKey, awsSecretAccessKey string,
+) error {
+	organization, err := FetchOrganizationByID(ctx, db, organizationID)
+	if err != nil {
+		return err
+	}
+
+	if organization.VerifiedAt != nil {
+		return ErrOrganizationAlreadyVerified
+	}
+
+	if len(request.Documents) == 0 {
+		return ErrVerificationDocumentsRequired
+	}
+
+	if awsAccessKey == "" || awsSecretAccessKey == "" {
+		return ErrMediaStorageUnavailable
+	}
+
+	request.OrganizationID = organizationID
+	request.RequestedByUserID = &userID
+	request.Status = models.VerificationStatusPending
+	request.ReviewNote = ""
+	request.ReviewedByUserID = nil
+	request.ReviewedAt = nil
+
+	result, err := db.NewInsert().
+		Model(request).
+		On("CONFLICT (organization_id) WHERE status = 'pending' DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	if affected == 0 {
+		return ErrVerificationPending
+	}
+
+	for _, document := range request.Documents {
+		document.RequestID = request.ID
+
+		document.ObjectKey, document.MimeType, err = uploadPrivateMedia(
+			ctx, awsAccessKey, awsSecretAccessKey, S3DocumentsFolderPath, document.Base64,
+		)
+		if err != nil {
+			return err
+		}
+
+		document.Base64 = ""
+
+		err = models.Create(ctx, db, document)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FetchLatestVerificationRequest returns the organization's most recent verification
+// request, or ErrOrganizationVerificationAbsent.
+func FetchLatestVerificationRequest(
+	ctx context.Context, db bun.IDB, organizationID uuid.UUID,
+) (*models.OrganizationVerificationRequest, error) {
+	request := &models.OrganizationVerificationRequest{}
+
+	err := db.NewSelect().
+		Model(request).
+		Where("organization_verification_request.organization_id = ?", organizationID).
+		Relation("Documents").
+		Order("organization_verification_request.created_at DESC").
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrganizationVerificationAbsent
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return request, nil
+}
+
+// FetchVerificationRequests is the admins' review queue, oldest requests first. Only
+// pending requests are listed unless other statuses are asked for. The documents come with
+// short-lived URLs to them.
+func FetchVerificationRequests(
+	ctx context.Context, db bun.IDB, queryParam models.QueryParam, awsAccessKey, awsSecretAccessKey string,
+	statuses ...string,
+) ([]models.OrganizationVerificationRequest, int, error) {
+	requests := []models.OrganizationVerificationRequest{}
+
+	if len(statuses) == 0 {
+		statuses = []string{models.VerificationStatusPending}
+	}
+
+	query := db.NewSelect().
+		Model(&requests).
+		Where("organization_verification_request.status IN (?)", bun.In(statuses)).
+		Relation("Organization").
+		Relation("RequestedBy").
+		Relation("Documents")
+
+	count, err := queryParam.Pagination.BuildPaginationQuery(ctx, query)
+	if err != nil {
+		return requests, 0, errors.Wrap(err, err.Error())
+	}
+
+	err = query.Order("organization_verification_request.created_at ASC").Scan(ctx)
+	if err != nil {
+		return requests, 0, errors.Wrap(err, err.Error())
+	}
+
+	for _, request := range requests {
+		for _, document := range request.Documents {
+			document.URL, err = PresignMediaURL(ctx, awsAccessKey, awsSecretAccessKey, document.ObjectKey)
+			if err != nil {
+				return requests, 0, err
+			}
+		}
+	}
+
+	return requests, count, nil
+}
+
+// ReviewOrganizationVerification applies an admin's decision to the organization: approve
+// or reject its pending request, or revoke a verification granted before.
+func ReviewOrganizationVerification(
+	ctx context.Context, db bun.IDB, organizationID, reviewerID uuid.UUID, action, note string,
+) (*models.Organization, error) {
+	organization, err := FetchOrganizationByID(ctx, db, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	switch action {
+	case "approve", "reject":
+		request := &models.OrganizationVerificationRequest{}
+
+		err = db.NewSelect().
+			Model(request).
+			Where("organization_id = ?", organizationID).
+			Where("status = ?", models.VerificationStatusPending).
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoPendingVerification
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, err.Error())
+		}
+
+		request.Status = models.VerificationStatusRejected
+		if action == "approve" {
+			request.Status = models.VerificationStatusApproved
+			organization.VerifiedAt = &now
+		}
+
+		request.ReviewNote = note
+		request.ReviewedByUserID = &reviewerID
+		request.ReviewedAt = &now
+
+		_, err = db.NewUpdate().
+			Model(request).
+			Column("status", "review_note", "reviewed_by_user_id", "reviewed_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, err.Error())
+		}
+	case "revoke":
+		organization.VerifiedAt = nil
+	default:
+		return nil, ErrInvalidVerificationAction
+	}
+
+	_, err = db.NewUpdate().
+		Model(organization).
+		Column("verified_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return organization, nil
+}
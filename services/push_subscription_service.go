@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"rashikzaman/api/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var ErrInvalidPushSubscription = errors.New("push subscription needs an endpoint and its p256dh and auth keys")
+
+// SavePushSubscription subscribes the user's browser to push notifications. Browsers keep
+// their endpoint when another user signs in on them, so the endpoint moves to the user.
+func SavePushSubscription(ctx context.Context, db bun.IDB, userID uuid.UUID, subscription *models.PushSubscription) error {
+	if subscription.Endpoint == "" || subscription.P256dh == "" || subscription.Auth == "" {
+		return ErrInvalidPushSubscription
+	}
+
+	subscription.ID = uuid.Nil
+	subscription.UserID = userID
+
+	_, err := db.NewInsert().
+		Model(subscription).
+		On("CONFLICT (endpoint) DO UPDATE").
+		Set("user_id = EXCLUDED.user_id").
+		Set("p256dh = EXCLUDED.p256dh").
+		Set("auth = EXCLUDED.auth").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// DeletePushSubscription unsubscribes the user's browser from push notifications.
+func DeletePushSubscription(ctx context.Context, db bun.IDB, userID uuid.UUID, endpoint string) error {
+	_, err := db.NewDelete().
+		Model((*models.PushSubscription)(nil)).
+		Where("user_id = ?", userID).
+		Where("endpoint = ?", endpoint).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+func FetchPushSubscriptions(ctx context.Context, db bun.IDB, userID uuid.UUID) ([]models.PushSubscription, error) {
+	subscriptions := []models.PushSubscription{}
+
+	err := db.NewSelect().
+		Model(&subscriptions).
+		Where("user_id = ?", userID).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return subscriptions, nil
+}
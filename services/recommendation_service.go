@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+// recommendationProfile is what the recommendations for a volunteer are based on.
+type recommendationProfile struct {
+	location *models.UserLocation
+	skills   map[string]bool
+	// categoryShares is the share of the tasks the volunteer joined before in each category
+	categoryShares map[uuid.UUID]float64
+}
+
+// scoredTask is a candidate for the recommendations feed with its score and the reasons
+// for it.
+type scoredTask struct {
+	task    models.Task
+	score   float64
+	reasons []string
+}
+
+// FetchRecommendedTasks returns up to limit open tasks picked for the user, best first,
+// each with an explanation of why it was picked. Tasks are scored on the user's declared
+// skills, their distance from the user's location and the categories of the tasks the user
+// joined before, with the score of a task lowered for every task of its category ranked
+// above it. Tasks the user already applied to, is not available for or that clash with
+// tasks they were accepted for are left out.
+func FetchRecommendedTasks(ctx context.Context, db bun.IDB, userID uuid.UUID, limit int) ([]models.Task, error) {
+	profile, err := fetchRecommendationProfile(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []models.Task{}
+
+	query := db.NewSelect().
+		Model(&candidates).
+		Column("task.*").
+		Relation("Category").
+		Relation("Organization").
+		Where("task.status = ?", models.TaskStatusOpen).
+		Where("task.blocked = FALSE").
+		Where("task.recurrence_rule = ''").
+		Where("task.ends_at IS NULL OR task.ends_at > NOW()").
+		Where("task.user_id <> ?", userID).
+		Where("NOT EXISTS (SELECT 1 FROM user_tasks ut WHERE ut.task_id = task.id AND ut.user_id = ?)", userID).
+		// a volunteer cannot be at two tasks at once
+		Where(
+			`task.starts_at IS NULL OR NOT EXISTS (
+			SELECT 1 FROM user_tasks ut JOIN tasks joined ON joined.id = ut.task_id
+			WHERE ut.user_id = ? AND ut.status = ? AND joined.starts_at IS NOT NULL
+			AND tstzrange(joined.starts_at, COALESCE(joined.ends_at, joined.starts_at), '[]')
+				&& tstzrange(task.starts_at, COALESCE(task.ends_at, task.starts_at), '[]')
+		)`, userID, models.ApplicationStatusAccepted,
+		).
+		Where(availableForTaskExpr, userID).
+		Limit(constants.RecommendationCandidates)
+
+	if profile.location != nil {
+		query.ColumnExpr(taskDistanceExpr+" AS distance_km", profile.location.Longitude, profile.location.Latitude).
+			Where(
+				"ST_DWithin(ST_MakePoint(?, ?)::geography, task.location::geography, ?)",
+				profile.location.Longitude, profile.location.Latitude, constants.DefaultSearchRadiusKm*1000,
+			).
+			OrderExpr("distance_km ASC")
+	} else {
+		query.Order("task.created_at DESC")
+	}
+
+	err = query.Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	scored := make([]scoredTask, 0, len(candidates))
+
+	for _, task := range candidates {
+		score, reasons := profile.score(task)
+		scored = append(scored, scoredTask{task: task, score: score, reasons: reasons})
+	}
+
+	tasks := []models.Task{}
+
+	for _, pick := range diversify(scored, limit) {
+		pick.task.Explanation = pick.reasons
+		tasks = append(tasks, pick.task)
+	}
+
+	return tasks, nil
+}
+
+func fetchRecommendationProfile(ctx context.Context, db bun.IDB, userID uuid.UUID) (recommendationProfile, error) {
+	profile := recommendationProfile{
+		skills:         map[string]bool{},
+		categoryShares: map[uuid.UUID]float64{},
+	}
+
+	location := &models.UserLocation{}
+
+	err := db.NewSelect().Model(location).Where("user_id = ?", userID).Limit(1).Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return profile, errors.Wrap(err, err.Error())
+	}
+
+	if err == nil {
+		profile.location = location
+	}
+
+	userSkills, err := FetchUserSkills(ctx, db, userID)
+	if err != nil {
+		return profile, err
+	}
+
+	for _, userSkill := range userSkills {
+		profile.skills[userSkill.Skill.Name] = true
+	}
+
+	joined := []struct {
+		CategoryID uuid.UUID
+		Count      int
+	}{}
+
+	err = db.NewSelect().
+		TableExpr("user_tasks AS ut").
+		Join("JOIN tasks AS t ON t.id = ut.task_id").
+		ColumnExpr("t.category_id, COUNT(*) AS count").
+		Where("ut.user_id = ?", userID).
+		Where("ut.status = ?", models.ApplicationStatusAccepted).
+		Group("t.category_id").
+		Scan(ctx, &joined)
+	if err != nil {
+		return profile, errors.Wrap(err, err.Error())
+	}
+
+	total := 0
+	for _, category := range joined {
+		total += category.Count
+	}
+
+	for _, category := range joined {
+		profile.categoryShares[category.CategoryID] = float64(category.Count) / float64(total)
+	}
+
+	return profile, nil
+}
+
+// score rates from 0 to 1 how well the task suits the volunteer and explains why.
+func (p recommendationProfile) score(task models.Task) (float64, []string) {
+	score := 0.0
+	reasons := []string{}
+
+	matched := []string{}
+
+	for _, skill := range task.RequiredSkills {
+		if p.skills[skill] {
+			matched = append(matched, skill)
+		}
+	}
+
+	if len(matched) > 0 {
+		score += constants.RecommendationSkillWeight * float64(len(matched)) / float64(len(task.RequiredSkills))
+
+		noun := "skill"
+		if len(matched) > 1 {
+			noun = "skills"
+		}
+
+		reasons = append(reasons, fmt.Sprintf("matches your %s %s", joinWords(matched), noun))
+	}
+
+	if task.DistanceKm != nil {
+		score += constants.RecommendationDistanceWeight *
+			constants.MatchDistanceHalfScoreKm / (constants.MatchDistanceHalfScoreKm + *task.DistanceKm)
+
+		if *task.DistanceKm <= constants.RecommendationNearbyKm {
+			reasons = append(reasons, "near you")
+		}
+	}
+
+	if share := p.categoryShares[task.CategoryID]; share > 0 {
+		score += constants.RecommendationCategoryWeight * share
+
+		if task.Category != nil {
+			reasons = append(reasons, fmt.Sprintf("like the %s tasks you joined before", task.Category.Name))
+		}
+	}
+
+	return score, reasons
+}
+
+// diversify picks up to limit tasks best score first, multiplying the score of a task by
+// RecommendationCategoryDecay for every task of its category picked before it.
+func diversify(scored []scoredTask, limit int) []scoredTask {
+	// ties keep the order of the candidates, nearest or newest first
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	picked := []scoredTask{}
+	perCategory := map[uuid.UUID]int{}
+	taken := make([]bool, len(scored))
+
+	for len(picked) < limit {
+		best := -1
+		bestScore := 0.0
+
+		for i, candidate := range scored {
+			if taken[i] {
+				continue
+			}
+
+			adjusted := candidate.score * math.Pow(constants.RecommendationCategoryDecay, float64(perCategory[candidate.task.CategoryID]))
+			if best == -1 || adjusted > bestScore {
+				best = i
+				bestScore = adjusted
+			}
+		}
+
+		if best == -1 {
+			break
+		}
+
+		taken[best] = true
+		perCategory[scored[best].task.CategoryID]++
+		picked = append(picked, scored[best])
+	}
+
+	return picked
+}
+
+// joinWords joins words as in "a, b and c".
+func joinWords(words []string) string {
+	if len(words) == 1 {
+		return words[0]
+	}
+
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
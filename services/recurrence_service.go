@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/teambition/rrule-go"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrInvalidRecurrenceRule   = errors.New("invalid recurrence rule")
+	ErrRecurrenceNeedsSchedule = errors.New("recurring tasks need a start and end time")
+	ErrRecurringTaskTemplate   = errors.New("apply to an occurrence of this recurring task instead")
+	ErrNotAnOccurrence         = errors.New("task is not an occurrence of a recurring task")
+)
+
+// supportedRecurrenceParts is the subset of RFC 5545 RRULE parts organizers may use.
+var supportedRecurrenceParts = map[string]bool{
+	"FREQ":       true,
+	"INTERVAL":   true,
+	"COUNT":      true,
+	"UNTIL":      true,
+	"BYDAY":      true,
+	"BYMONTHDAY": true,
+}
+
+var supportedRecurrenceFrequencies = map[string]bool{
+	"DAILY":   true,
+	"WEEKLY":  true,
+	"MONTHLY": true,
+}
+
+// ParseRecurrenceRule validates the task's recurrence rule and anchors it at the task's
+// start time in the task's time zone, so occurrences keep their local time across DST.
+func ParseRecurrenceRule(task *models.Task) (*rrule.RRule, error) {
+	if task.StartsAt == nil || task.EndsAt == nil {
+		return nil, ErrRecurrenceNeedsSchedule
+	}
+
+	rule := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(task.RecurrenceRule)), "RRULE:")
+
+	for _, part := range strings.Split(rule, ";") {
+		key, value, found := strings.Cut(part, "=")
+		if !found || !supportedRecurrenceParts[key] {
+			return nil, ErrInvalidRecurrenceRule
+		}
+
+		if key == "FREQ" && !supportedRecurrenceFrequencies[value] {
+			return nil, ErrInvalidRecurrenceRule
+		}
+	}
+
+	location, err := LoadTimeZone(task.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	option, err := rrule.StrToROptionInLocation(rule, location)
+	if err != nil {
+		return nil, ErrInvalidRecurrenceRule
+	}
+
+	option.Dtstart = task.StartsAt.In(location)
+
+	recurrence, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, ErrInvalidRecurrenceRule
+	}
+
+	return recurrence, nil
+}
+
+// GenerateOccurrences materializes the occurrences of a recurring task that start after the
+// last generated one and no later than until. Each occurrence is a copy of the template
+// task, including its shifts and media, that volunteers apply to individually.
+func GenerateOccurrences(ctx context.Context, db bun.IDB, template *models.Task, until time.Time) ([]models.Task, error) {
+	occurrences := []models.Task{}
+
+	recurrence, err := ParseRecurrenceRule(template)
+	if err != nil {
+		return occurrences, err
+	}
+
+	// the first occurrence is the template's own start time
+	after := template.StartsAt.Add(-time.Second)
+	if template.RecurrenceGeneratedUntil != nil {
+		after = *template.RecurrenceGeneratedUntil
+	}
+
+	if !until.After(after) {
+		return occurrences, nil
+	}
+
+	shifts, err := FetchTaskShifts(ctx, db, template.ID)
+	if err != nil {
+		return occurrences, err
+	}
+
+	media := []models.TaskMedia{}
+
+	err = db.NewSelect().Model(&media).Where("task_id = ?", template.ID).Scan(ctx)
+	if err != nil {
+		return occurrences, errors.Wrap(err, err.Error())
+	}
+
+	duration := template.EndsAt.Sub(*template.StartsAt)
+
+	for _, startsAt := range recurrence.Between(after, until, true) {
+		if !startsAt.After(after) {
+			continue
+		}
+
+		occurrence, created, err := createOccurrence(ctx, db, template, startsAt, duration)
+		if err != nil {
+			return occurrences, err
+		}
+
+		// already materialized by an earlier run
+		if !created {
+			continue
+		}
+
+		offset := startsAt.Sub(*template.StartsAt)
+
+		for _, shift := range shifts {
+			occurrenceShift := &models.TaskShift{
+				TaskID:   occurrence.ID,
+				StartsAt: shift.StartsAt.Add(offset),
+				EndsAt:   shift.EndsAt.Add(offset),
+				Capacity: shift.Capacity,
+			}
+
+			err = models.Create(ctx, db, occurrenceShift)
+			if err != nil {
+				return occurrences, err
+			}
+		}
+
+		for _, item := range media {
+			occurrenceMedia := &models.TaskMedia{
+				MimeType: item.MimeType,
+				Link:     item.Link,
+				TaskID:   occurrence.ID,
+			}
+
+			err = models.Create(ctx, db, occurrenceMedia)
+			if err != nil {
+				return occurrences, err
+			}
+		}
+
+		occurrences = append(occurrences, *occurrence)
+	}
+
+	template.RecurrenceGeneratedUntil = &until
+
+	_, err = db.NewUpdate().
+		Model(template).
+		Column("recurrence_generated_until").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return occurrences, errors.Wrap(err, err.Error())
+	}
+
+	return occurrences, nil
+}
+
+// GenerateUpcomingOccurrences tops up every active recurring task so that its occurrences
+// cover the next RecurrenceWindowDays days. It returns the number of occurrences created.
+func GenerateUpcomingOccurrences(ctx context.Context, db bun.IDB, now time.Time) (int, error) {
+	until := now.AddDate(0, 0, constants.RecurrenceWindowDays)
+	templates := []models.Task{}
+
+	err := db.NewSelect().
+		Model(&templates).
+		Where("recurrence_rule <> ''").
+		Where("status IN (?)", bun.In([]string{models.TaskStatusDraft, models.TaskStatusOpen, models.TaskStatusFilled})).
+		Where("blocked = FALSE").
+		Where("recurrence_generated_until IS NULL OR recurrence_generated_until < ?", until).
+		Scan(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, err.Error())
+	}
+
+	created := 0
+
+	for i := range templates {
+		occurrences, err := GenerateOccurrences(ctx, db, &templates[i], until)
+		if err != nil {
+			return created, err
+		}
+
+		created += len(occurrences)
+	}
+
+	return created, nil
+}
+
+// UpdateFutureOccurrences applies taskBody to the given occurrence, every later occurrence of
+// the same series that has not finished, and the series template so that occurrences
+// generated from now on pick up the change. A change of start time is applied to every
+// occurrence as the same shift. The user needs to be allowed to edit the template, not
+// only the occurrence. The applications promoted off the waitlists of the occurrences are
+// returned, see UpdateTask.
+func UpdateFutureOccurrences(
+	ctx context.Context, db bun.IDB, userID uuid.UUID, occurrence models.Task, taskBody models.Task,
+) ([]*models.UserTask, error) {
+	if occurrence.ParentTaskID == nil || occurrence.StartsAt == nil {
+		return nil, ErrNotAnOccurrence
+	}
+
+	if occurrence.EndsAt == nil {
+		return nil, ErrRecurrenceNeedsSchedule
+	}
+
+	template, err := FetchTaskByID(ctx, db, *occurrence.ParentTaskID, models.QueryParam{})
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := HasTaskPermission(ctx, db, template, userID, models.TaskPermissionEditDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, ErrNotTaskOrganizer
+	}
+
+	shift := time.Duration(0)
+	duration := occurrence.EndsAt.Sub(*occurrence.StartsAt)
+
+	if taskBody.StartsAt != nil && taskBody.EndsAt != nil {
+		shift = taskBody.StartsAt.Sub(*occurrence.StartsAt)
+		duration = taskBody.EndsAt.Sub(*taskBody.StartsAt)
+	}
+
+	futureOccurrences := []models.Task{}
+
+	err = db.NewSelect().
+		Model(&futureOccurrences).
+		Where("parent_task_id = ?", template.ID).
+		Where("starts_at >= ?", *occurrence.StartsAt).
+		Where("status NOT IN (?)", bun.In([]string{models.TaskStatusCompleted, models.TaskStatusCancelled})).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	promoted := []*models.UserTask{}
+
+	for _, task := range append(futureOccurrences, template) {
+		if task.StartsAt == nil {
+			return nil, ErrRecurrenceNeedsSchedule
+		}
+
+		body := taskBody
+		startsAt := task.StartsAt.Add(shift)
+		endsAt := startsAt.Add(duration)
+
+		body.StartsAt = &startsAt
+		body.EndsAt = &endsAt
+		body.Status = ""
+
+		_, promotedOnTask, err := UpdateTask(ctx, db, task, body)
+		if err != nil {
+			return nil, err
+		}
+
+		promoted = append(promoted, promotedOnTask...)
+	}
+
+	return promoted, nil
+}
+
+func createOccurrence(
+	ctx context.Context, db bun.IDB, template *models.Task, startsAt time.Time, duration time.Duration,
+) (*models.Task, bool, error) {
+	endsAt := startsAt.Add(duration)
+
+	occurrence := &models.Task{
+		Title:                   template.Title,
+		Description:             template.Description,
+		RequiredVolunteersCount: template.RequiredVolunteersCount,
+		RequiredSkills:          template.RequiredSkills,
+		Latitude:                template.Latitude,
+		Longitude:               template.Longitude,
+		Location:                template.Location,
+		FormattedAddress:        template.FormattedAddress,
+		UserID:                  template.UserID,
+		CategoryID:              template.CategoryID,
+		Status:                  template.Status,
+		AutoAccept:              template.AutoAccept,
+		StartsAt:                &startsAt,
+		EndsAt:                  &endsAt,
+		TimeZone:                template.TimeZone,
+		ParentTaskID:            &template.ID,
+		CheckInRadiusMeters:     template.CheckInRadiusMeters,
+		QRCheckInRequired:       template.QRCheckInRequired,
+		MinReliabilityScore:     template.MinReliabilityScore,
+		OrganizationID:          template.OrganizationID,
+	}
+
+	// a filled template says nothing about how full a new occurrence is
+	if occurrence.Status == models.TaskStatusFilled {
+		occurrence.Status = models.TaskStatusOpen
+	}
+
+	result, err := db.NewInsert().
+		Model(occurrence).
+		On("CONFLICT (parent_task_id, starts_at) WHERE parent_task_id IS NOT NULL DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, errors.Wrap(err, err.Error())
+	}
+
+	return occurrence, affected > 0, nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrReliabilityTooLow     = errors.New("your reliability score is below what this task requires")
+	ErrInvalidMinReliability = errors.New("minimum reliability score must be between 0 and 100")
+)
+
+// recordOutcomesQuery settles the attendance of every application holding a spot that
+// matches the condition: volunteers who checked in completed the task, everyone else was a
+// no-show. The users' counters are updated in the same statement.
+const recordOutcomesQuery = `
+WITH outcomes AS (
+	UPDATE user_tasks AS ut
+	SET attendance_outcome = CASE WHEN ut.checked_in_at IS NOT NULL THEN ? ELSE ? END
+	WHERE ut.status = ? AND ut.waitlisted = FALSE AND ut.attendance_outcome IS NULL AND (%s)
+	RETURNING ut.user_id, ut.attendance_outcome
+)
+UPDATE users
+SET completed_tasks_count = completed_tasks_count + o.completed,
+	no_show_count = no_show_count + o.no_shows
+FROM (
+	SELECT user_id,
+		COUNT(*) FILTER (WHERE attendance_outcome = ?) AS completed,
+		COUNT(*) FILTER (WHERE attendance_outcome = ?) AS no_shows
+	FROM outcomes
+	GROUP BY user_id
+) AS o
+WHERE users.id = o.user_id`
+
+// RecordTaskOutcomes settles the attendance of all volunteers of a task, e.g. when it is
+// marked completed.
+func RecordTaskOutcomes(ctx context.Context, db bun.IDB, taskID uuid.UUID) (int64, error) {
+	return recordOutcomes(ctx, db, "ut.task_id = ?", taskID)
+}
+
+// RecordEndedTaskOutcomes settles the attendance of volunteers of tasks and shifts that
+// ended before now and have not been settled yet.
+func RecordEndedTaskOutcomes(ctx context.Context, db bun.IDB, now time.Time) (int64, error) {
+	return recordOutcomes(
+		ctx, db,
+		`ut.task_id IN (SELECT id FROM tasks WHERE status IN (?)) AND COALESCE(
+			(SELECT ends_at FROM task_shifts WHERE id = ut.shift_id),
+			(SELECT ends_at FROM tasks WHERE id = ut.task_id)
+		) < ?`,
+		bun.In([]string{models.TaskStatusOpen, models.TaskStatusFilled, models.TaskStatusInProgress}), now,
+	)
+}
+
+func recordOutcomes(ctx context.Context, db bun.IDB, condition string, args ...interface{}) (int64, error) {
+	query := fmt.Sprintf(recordOutcomesQuery, condition)
+
+	queryArgs := []interface{}{
+		models.AttendanceOutcomeCompleted, models.AttendanceOutcomeNoShow, models.ApplicationStatusAccepted,
+	}
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, models.AttendanceOutcomeCompleted, models.AttendanceOutcomeNoShow)
+
+	result, err := db.NewRaw(query, queryArgs...).Exec(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, err.Error())
+	}
+
+	return affected, nil
+}
+
+// recordLateWithdrawal counts the withdrawal against the volunteer if it came less than
+// LateWithdrawalHours before the task or shift starts.
+func recordLateWithdrawal(ctx context.Context, db bun.IDB, task *models.Task, userTask *models.UserTask, now time.Time) error {
+	// an attendance is only ever counted once
+	if userTask.AttendanceOutcome != "" {
+		return nil
+	}
+
+	startsAt := task.StartsAt
+
+	if userTask.ShiftID != nil {
+		shift := &models.TaskShift{}
+
+		err := db.NewSelect().Model(shift).Where("id = ?", *userTask.ShiftID).Scan(ctx)
+		if err != nil {
+			return errors.Wrap(err, err.Error())
+		}
+
+		startsAt = &shift.StartsAt
+	}
+
+	if startsAt == nil || now.Before(startsAt.Add(-constants.LateWithdrawalHours*time.Hour)) {
+		return nil
+	}
+
+	userTask.AttendanceOutcome = models.AttendanceOutcomeLateWithdrawal
+
+	_, err := db.NewUpdate().
+		Model(userTask).
+		Column("attendance_outcome").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	_, err = db.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("late_withdrawal_count = late_withdrawal_count + 1").
+		Where("id = ?", userTask.UserID).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// recordLateCheckIn turns a no-show into a completion when the organizer checks the
+// volunteer in after their attendance was settled.
+func recordLateCheckIn(ctx context.Context, db bun.IDB, userTask *models.UserTask) error {
+	if userTask.AttendanceOutcome != models.AttendanceOutcomeNoShow {
+		return nil
+	}
+
+	userTask.AttendanceOutcome = models.AttendanceOutcomeCompleted
+
+	_, err := db.NewUpdate().
+		Model(userTask).
+		Column("attendance_outcome").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	_, err = db.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("completed_tasks_count = completed_tasks_count + 1").
+		Set("no_show_count = GREATEST(no_show_count - 1, 0)").
+		Where("id = ?", userTask.UserID).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// validateReliability rejects applicants whose score is below the task's minimum. Users
+// without any history are given the benefit of the doubt.
+func validateReliability(ctx context.Context, db bun.IDB, task *models.Task, userID uuid.UUID) error {
+	if task.MinReliabilityScore == 0 {
+		return nil
+	}
+
+	user, err := GetUserByID(ctx, db, userID)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	score := user.ReliabilityScore()
+	if score != nil && *score < float64(task.MinReliabilityScore) {
+		return ErrReliabilityTooLow
+	}
+
+	return nil
+}
@@ -2,20 +2,392 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"rashikzaman/api/models"
-	"rashikzaman/api/utils"
+	"strings"
 
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/uptrace/bun"
 )
 
-func FetchSkills(ctx context.Context, db bun.IDB) ([]string, error) {
-	var uniqueSkills []string
+var (
+	ErrInvalidSkill            = errors.New("skill name is required")
+	ErrUnknownSkill            = errors.New("unknown skill")
+	ErrSkillExists             = errors.New("a skill or synonym with this name already exists")
+	ErrSkillNotFound           = errors.New("skill not found")
+	ErrInvalidSkillProficiency = errors.New("invalid skill proficiency")
+)
+
+// UserSkillInput is a skill a user declares, by its name or one of its synonyms.
+type UserSkillInput struct {
+	Name        string `json:"name" binding:"required"`
+	Proficiency string `json:"proficiency"`
+}
+
+func IsValidSkillProficiency(proficiency string) bool {
+	switch proficiency {
+	case "", models.SkillProficiencyBeginner, models.SkillProficiencyIntermediate, models.SkillProficiencyExpert:
+		return true
+	}
+
+	return false
+}
+
+// FetchSkills lists the skill taxonomy with its synonyms, optionally only the skills whose
+// name or one of whose synonyms contains search.
+func FetchSkills(ctx context.Context, db bun.IDB, search string) ([]models.Skill, error) {
+	skills := []models.Skill{}
+
+	query := db.NewSelect().
+		Model(&skills).
+		Relation("Synonyms", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("skill_synonym.name ASC")
+		}).
+		Order("skill.name ASC")
+
+	if search = strings.TrimSpace(search); search != "" {
+		pattern := "%" + search + "%"
+
+		query.Where(
+			"skill.name ILIKE ? OR skill.id IN (SELECT skill_id FROM skill_synonyms WHERE name ILIKE ?)",
+			pattern, pattern,
+		)
+	}
+
+	err := query.Scan(ctx)
+	if err != nil {
+		return skills, errors.Wrap(err, err.Error())
+	}
+
+	return skills, nil
+}
+
+func FetchSkillByID(ctx context.Context, db bun.IDB, id uuid.UUID) (*models.Skill, error) {
+	skill := &models.Skill{}
 
-	// Query to select distinct values from the RequiredSkills array
 	err := db.NewSelect().
-		ColumnExpr("DISTINCT unnest(required_skills) AS skill").
+		Model(skill).
+		Where("skill.id = ?", id).
+		Relation("Synonyms").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSkillNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return skill, nil
+}
+
+// CreateSkill adds the skill to the taxonomy along with the synonyms set on it.
+func CreateSkill(ctx context.Context, db bun.IDB, skill *models.Skill) error {
+	skill.Name = strings.TrimSpace(skill.Name)
+
+	err := ensureSkillNameFree(ctx, db, skill.Name)
+	if err != nil {
+		return err
+	}
+
+	err = models.Create(ctx, db, skill)
+	if err != nil {
+		return err
+	}
+
+	for _, synonym := range skill.Synonyms {
+		synonym.Name = strings.TrimSpace(synonym.Name)
+		synonym.SkillID = skill.ID
+
+		err = ensureSkillNameFree(ctx, db, synonym.Name)
+		if err != nil {
+			return err
+		}
+
+		err = models.Create(ctx, db, synonym)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RenameSkill renames the skill, along with every task requiring it and the notification
+// preferences following it.
+func RenameSkill(ctx context.Context, db bun.IDB, id uuid.UUID, name string) (*models.Skill, error) {
+	skill, err := FetchSkillByID(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == skill.Name {
+		return skill, nil
+	}
+
+	// changing only the case of the name clashes with nothing but the skill itself
+	if !strings.EqualFold(name, skill.Name) {
+		err = ensureSkillNameFree(ctx, db, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = db.NewUpdate().
 		Model((*models.Task)(nil)).
-		Scan(ctx, &uniqueSkills)
+		Set("required_skills = array_replace(required_skills, ?, ?)", skill.Name, name).
+		Where("? = ANY(required_skills)", skill.Name).
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	_, err = db.NewUpdate().
+		Model((*models.NotificationPreferences)(nil)).
+		Set("skills = array_replace(skills, ?, ?)", skill.Name, name).
+		Where("? = ANY(skills)", skill.Name).
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	skill.Name = name
+
+	_, err = db.NewUpdate().
+		Model(skill).
+		Column("name").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return skill, nil
+}
+
+// DeleteSkill takes the skill out of the taxonomy, off the tasks requiring it, the profiles
+// of the users who declared it and the notification preferences following it.
+func DeleteSkill(ctx context.Context, db bun.IDB, id uuid.UUID) error {
+	skill, err := FetchSkillByID(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewUpdate().
+		Model((*models.Task)(nil)).
+		Set("required_skills = array_remove(required_skills, ?)", skill.Name).
+		Where("? = ANY(required_skills)", skill.Name).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	_, err = db.NewUpdate().
+		Model((*models.NotificationPreferences)(nil)).
+		Set("skills = array_remove(skills, ?)", skill.Name).
+		Where("? = ANY(skills)", skill.Name).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	_, err = db.NewDelete().
+		Model(skill).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+func AddSkillSynonym(ctx context.Context, db bun.IDB, skillID uuid.UUID, name string) (*models.SkillSynonym, error) {
+	_, err := FetchSkillByID(ctx, db, skillID)
+	if err != nil {
+		return nil, err
+	}
+
+	synonym := &models.SkillSynonym{SkillID: skillID, Name: strings.TrimSpace(name)}
+
+	err = ensureSkillNameFree(ctx, db, synonym.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = models.Create(ctx, db, synonym)
+	if err != nil {
+		return nil, err
+	}
+
+	return synonym, nil
+}
+
+func RemoveSkillSynonym(ctx context.Context, db bun.IDB, skillID, synonymID uuid.UUID) error {
+	result, err := db.NewDelete().
+		Model((*models.SkillSynonym)(nil)).
+		Where("id = ?", synonymID).
+		Where("skill_id = ?", skillID).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	if affected == 0 {
+		return ErrSkillNotFound
+	}
+
+	return nil
+}
+
+// ResolveSkills maps skill names and synonyms, in any case, to the skills of the taxonomy.
+// It fails with ErrUnknownSkill on the first name the taxonomy does not know.
+func ResolveSkills(ctx context.Context, db bun.IDB, names []string) ([]models.Skill, error) {
+	skills := []models.Skill{}
+	seen := map[uuid.UUID]bool{}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		skill := models.Skill{}
+
+		err := db.NewSelect().
+			Model(&skill).
+			Where("LOWER(skill.name) = LOWER(?)", name).
+			WhereOr("skill.id IN (SELECT skill_id FROM skill_synonyms WHERE LOWER(name) = LOWER(?))", name).
+			Limit(1).
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSkill, name)
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, err.Error())
+		}
+
+		if !seen[skill.ID] {
+			seen[skill.ID] = true
+			skills = append(skills, skill)
+		}
+	}
+
+	return skills, nil
+}
+
+// ResolveSkillNames is ResolveSkills returning the canonical names, as stored on tasks.
+func ResolveSkillNames(ctx context.Context, db bun.IDB, names []string) ([]string, error) {
+	skills, err := ResolveSkills(ctx, db, names)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]string, 0, len(skills))
+
+	for _, skill := range skills {
+		resolved = append(resolved, skill.Name)
+	}
+
+	return resolved, nil
+}
+
+// FetchUserSkills lists the skills the user declared.
+func FetchUserSkills(ctx context.Context, db bun.IDB, userID uuid.UUID) ([]models.UserSkill, error) {
+	userSkills := []models.UserSkill{}
+
+	err := db.NewSelect().
+		Model(&userSkills).
+		Where("user_skill.user_id = ?", userID).
+		Relation("Skill").
+		Order("skill.name ASC").
+		Scan(ctx)
+	if err != nil {
+		return userSkills, errors.Wrap(err, err.Error())
+	}
+
+	return userSkills, nil
+}
+
+// SetUserSkills replaces the skills the user declared with inputs.
+func SetUserSkills(ctx context.Context, db bun.IDB, userID uuid.UUID, inputs []UserSkillInput) ([]models.UserSkill, error) {
+	names := make([]string, 0, len(inputs))
+
+	for _, input := range inputs {
+		if !IsValidSkillProficiency(input.Proficiency) {
+			return nil, ErrInvalidSkillProficiency
+		}
+
+		names = append(names, input.Name)
+	}
+
+	_, err := db.NewDelete().
+		Model((*models.UserSkill)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	// a skill declared twice, maybe under a synonym, keeps the last proficiency given
+	proficiencies := map[uuid.UUID]string{}
+	order := []uuid.UUID{}
+
+	for _, input := range inputs {
+		skills, err := ResolveSkills(ctx, db, []string{input.Name})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(skills) == 0 {
+			continue
+		}
+
+		if _, ok := proficiencies[skills[0].ID]; !ok {
+			order = append(order, skills[0].ID)
+		}
+
+		proficiencies[skills[0].ID] = input.Proficiency
+	}
+
+	for _, skillID := range order {
+		err = models.Create(ctx, db, &models.UserSkill{
+			UserID:      userID,
+			SkillID:     skillID,
+			Proficiency: proficiencies[skillID],
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return FetchUserSkills(ctx, db, userID)
+}
+
+// ensureSkillNameFree checks that name is not empty and not yet taken by a skill or a
+// synonym.
+func ensureSkillNameFree(ctx context.Context, db bun.IDB, name string) error {
+	if name == "" {
+		return ErrInvalidSkill
+	}
+
+	_, err := ResolveSkills(ctx, db, []string{name})
+	if err == nil {
+		return ErrSkillExists
+	}
+
+	if errors.Is(err, ErrUnknownSkill) {
+		return nil
+	}
 
-	return utils.DeleteEmptyFromSlice(uniqueSkills), err
+	return err
 }
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SendEmail sends a plain text email.
+func SendEmail(config SMTPConfig, to, subject, body string) error {
+	return sendMail(config, to, subject, "text/plain; charset=UTF-8", []byte(body))
+}
+
+// SendHTMLEmail sends an email with both an HTML and a plain text version of its body, for
+// mail clients to show the one they can.
+func SendHTMLEmail(config SMTPConfig, to, subject, text, html string) error {
+	body := bytes.Buffer{}
+	writer := multipart.NewWriter(&body)
+
+	// the preferred version comes last
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return errors.Wrap(err, err.Error())
+		}
+
+		_, err = partWriter.Write([]byte(part.content))
+		if err != nil {
+			return errors.Wrap(err, err.Error())
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return sendMail(config, to, subject, "multipart/alternative; boundary="+writer.Boundary(), body.Bytes())
+}
+
+// sendMail sends the email over SMTP. The server is only authenticated with when a username
+// is configured.
+func sendMail(config SMTPConfig, to, subject, contentType string, body []byte) error {
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	header := strings.Join([]string{
+		"From: " + config.From,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: " + contentType,
+	}, "\r\n")
+
+	message := append([]byte(header+"\r\n\r\n"), body...)
+
+	err := smtp.SendMail(net.JoinHostPort(config.Host, config.Port), auth, config.From, []string{to}, message)
+
+	// 5xx replies, such as for an unknown mailbox, are permanent failures in SMTP
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+		return fmt.Errorf("%w: error sending email: %v", ErrPermanentDeliveryFailure, err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"math"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrInvalidBoundingBox = errors.New("invalid bounding box, expected min_lng,min_lat,max_lng,max_lat")
+	ErrInvalidZoom        = errors.New("invalid zoom level")
+)
+
+// mapClusterCellsPerTile is how many grid cells a 256px map tile is split into along each
+// axis when clustering, i.e. clusters are roughly 64px apart on screen.
+const mapClusterCellsPerTile = 4
+
+type BoundingBox struct {
+	MinLongitude float64
+	MinLatitude  float64
+	MaxLongitude float64
+	MaxLatitude  float64
+}
+
+func (b BoundingBox) Valid() bool {
+	return b.MinLongitude >= -180 && b.MaxLongitude <= 180 &&
+		b.MinLatitude >= -90 && b.MaxLatitude <= 90 &&
+		b.MinLongitude < b.MaxLongitude && b.MinLatitude < b.MaxLatitude
+}
+
+// FetchTaskMap returns the tasks matching filter inside the viewport. Up to
+// MapClusterMaxZoom tasks are grouped into clusters by snapping them to a grid that gets
+// finer as the map is zoomed in; past it the tasks are returned individually.
+func FetchTaskMap(ctx context.Context, db bun.IDB, bbox BoundingBox, zoom int, filter Filter) (*models.TaskMap, error) {
+	if !bbox.Valid() {
+		return nil, ErrInvalidBoundingBox
+	}
+
+	if zoom < 0 || zoom > 22 {
+		return nil, ErrInvalidZoom
+	}
+
+	taskMap := &models.TaskMap{
+		Points:   []models.TaskMapPoint{},
+		Clusters: []models.TaskMapCluster{},
+	}
+
+	if zoom > constants.MapClusterMaxZoom {
+		err := viewportQuery(db, bbox, filter).
+			ColumnExpr("task.id, task.title, task.status, task.category_id").
+			ColumnExpr("ST_Y(task.location) AS latitude, ST_X(task.location) AS longitude").
+			OrderExpr("task.created_at DESC").
+			Limit(constants.MapMaxPoints).
+			Scan(ctx, &taskMap.Points)
+		if err != nil {
+			return nil, errors.Wrap(err, err.Error())
+		}
+
+		return taskMap, nil
+	}
+
+	cellSize := 360 / (math.Pow(2, float64(zoom)) * mapClusterCellsPerTile)
+
+	err := viewportQuery(db, bbox, filter).
+		ColumnExpr("COUNT(*) AS count").
+		ColumnExpr("ST_Y(ST_Centroid(ST_Collect(task.location))) AS latitude").
+		ColumnExpr("ST_X(ST_Centroid(ST_Collect(task.location))) AS longitude").
+		ColumnExpr("MIN(ST_Y(task.location)) AS min_latitude, MIN(ST_X(task.location)) AS min_longitude").
+		ColumnExpr("MAX(ST_Y(task.location)) AS max_latitude, MAX(ST_X(task.location)) AS max_longitude").
+		GroupExpr("ST_SnapToGrid(task.location, ?)", cellSize).
+		Scan(ctx, &taskMap.Clusters)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	taskMap.Clustered = true
+
+	return taskMap, nil
+}
+
+func viewportQuery(db bun.IDB, bbox BoundingBox, filter Filter) *bun.SelectQuery {
+	query := db.NewSelect().
+		TableExpr("tasks AS task").
+		Where(
+			"task.location && ST_MakeEnvelope(?, ?, ?, ?, 4326)",
+			bbox.MinLongitude, bbox.MinLatitude, bbox.MaxLongitude, bbox.MaxLatitude,
+		)
+
+	return applyFilter(query, filter)
+}
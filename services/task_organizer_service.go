@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"rashikzaman/api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrNotTaskOrganizer      = errors.New("user is not an organizer of this task")
+	ErrAlreadyTaskOrganizer  = errors.New("user is already an organizer of this task")
+	ErrTaskOwnerAsOrganizer  = errors.New("the task's owners already organize it")
+	ErrInvalidTaskPermission = errors.New("invalid task permission")
+)
+
+func IsValidTaskPermission(permission string) bool {
+	switch permission {
+	case models.TaskPermissionEditDetails, models.TaskPermissionManageVolunteers, models.TaskPermissionMessageVolunteers:
+		return true
+	}
+
+	return false
+}
+
+// IsTaskOwner reports whether the user owns the task: the user who posted it or, for a
+// task posted as an organization, its current owners and managers, so that staff who leave
+// the organization no longer manage the tasks they posted for it. Owners hold every
+// permission on the task and are the only ones who can delete it or pick its co-organizers.
+func IsTaskOwner(ctx context.Context, db bun.IDB, task models.Task, userID uuid.UUID) (bool, error) {
+	if task.OrganizationID == nil {
+		return task.UserID == userID, nil
+	}
+
+	member, err := FetchOrganizationMember(ctx, db, *task.OrganizationID, userID)
+	if errors.Is(err, ErrNotOrganizationMember) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return member.CanManageTasks(), nil
+}
+
+// HasTaskPermission reports whether the user may act on the task with the permission,
+// either as one of its owners or as a co-organizer who was given it.
+func HasTaskPermission(ctx context.Context, db bun.IDB, task models.Task, userID uuid.UUID, permission string) (bool, error) {
+	if !IsValidTaskPermission(permission) {
+		return false, ErrInvalidTaskPermission
+	}
+
+	owner, err := IsTaskOwner(ctx, db, task, userID)
+	if err != nil || owner {
+		return owner, err
+	}
+
+	organizer, err := FetchTaskOrganizer(ctx, db, task.ID, userID)
+	if errors.Is(err, ErrNotTaskOrganizer) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return organizer.Can(permission), nil
+}
+
+// FetchUsersWithTaskPermission lists the users who may act on the task with the permission,
+// see HasTaskPermission.
+func FetchUsersWithTaskPermission(
+	ctx context.Context, db bun.IDB, task models.Task, permission string,
+) ([]*models.User, error) {
+	if !IsValidTaskPermission(permission) {
+		return nil, ErrInvalidTaskPermission
+	}
+
+	users := []*models.User{}
+
+	query := db.NewSelect().Model(&users)
+	if task.OrganizationID == nil {
+		query.Where("id = ?", task.UserID)
+	} else {
+		query.Where(
+			"id IN (SELECT user_id FROM organization_members WHERE organization_id = ? AND role IN (?))",
+			*task.OrganizationID, bun.In([]string{models.OrganizationRoleOwner, models.OrganizationRoleManager}),
+		)
+	}
+
+	err := query.Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	organizers, err := FetchTaskOrganizers(ctx, db, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, user := range users {
+		seen[user.ID] = true
+	}
+
+	for _, organizer := range organizers {
+		if organizer.Can(permission) && organizer.User != nil && !seen[organizer.UserID] {
+			seen[organizer.UserID] = true
+			users = append(users, organizer.User)
+		}
+	}
+
+	return users, nil
+}
+
+// IsTaskOrganizer reports whether the user owns the task or has accepted to co-organize it,
+// whatever their permissions.
+func IsTaskOrganizer(ctx context.Context, db bun.IDB, task models.Task, userID uuid.UUID) (bool, error) {
+	owner, err := IsTaskOwner(ctx, db, task, userID)
+	if err != nil || owner {
+		return owner, err
+	}
+
+	organizer, err := FetchTaskOrganizer(ctx, db, task.ID, userID)
+	if errors.Is(err, ErrNotTaskOrganizer) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return organizer.AcceptedAt != nil, nil
+}
+
+// FetchTaskOrganizer returns the co-organizer invitation of the user on the task, accepted
+// or not, or ErrNotTaskOrganizer.
+func FetchTaskOrganizer(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID) (*models.TaskOrganizer, error) {
+	organizer := &models.TaskOrganizer{}
+
+	err := db.NewSelect().
+		Model(organizer).
+		Where("task_id = ?", taskID).
+		Where("user_id = ?", userID).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotTaskOrganizer
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return organizer, nil
+}
+
+// FetchTaskOrganizers lists the co-organizers of the task, including pending invitations.
+func FetchTaskOrganizers(ctx context.Context, db bun.IDB, taskID uuid.UUID) ([]models.TaskOrganizer, error) {
+	organizers := []models.TaskOrganizer{}
+
+	err := db.NewSelect().
+		Model(&organizers).
+		Where("task_organizer.task_id = ?", taskID).
+		Relation("User").
+		Order("task_organizer.created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return organizers, errors.Wrap(err, err.Error())
+	}
+
+	return organizers, nil
+}
+
+// FetchTaskOrganizerInvitations lists the invitations to co-organize a task the user has
+// not answered yet, with their tasks.
+func FetchTaskOrganizerInvitations(ctx context.Context, db bun.IDB, userID uuid.UUID) ([]models.TaskOrganizer, error) {
+	invitations := []models.TaskOrganizer{}
+
+	err := db.NewSelect().
+		Model(&invitations).
+		Where("task_organizer.user_id = ?", userID).
+		Where("task_organizer.accepted_at IS NULL").
+		Relation("Task").
+		Order("task_organizer.created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return invitations, errors.Wrap(err, err.Error())
+	}
+
+	return invitations, nil
+}
+
+// InviteTaskOrganizer invites the user with the given email to co-organize the task with
+// the permissions set on organizer.
+func InviteTaskOrganizer(
+	ctx context.Context, db bun.IDB, task models.Task, email string, organizer *models.TaskOrganizer, invitedByUserID uuid.UUID,
+) error {
+	user, err := fetchUserByEmail(ctx, db, email)
+	if err != nil {
+		return err
+	}
+
+	owner, err := IsTaskOwner(ctx, db, task, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if owner {
+		return ErrTaskOwnerAsOrganizer
+	}
+
+	organizer.TaskID = task.ID
+	organizer.UserID = user.ID
+	organizer.InvitedByUserID = &invitedByUserID
+	organizer.AcceptedAt = nil
+
+	result, err := db.NewInsert().
+		Model(organizer).
+		On("CONFLICT (task_id, user_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	if affected == 0 {
+		return ErrAlreadyTaskOrganizer
+	}
+
+	organizer.User = user
+
+	return nil
+}
+
+// AcceptTaskOrganizerInvitation makes the user a co-organizer of the task they were
+// invited to.
+func AcceptTaskOrganizerInvitation(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID) (*models.TaskOrganizer, error) {
+	organizer, err := FetchTaskOrganizer(ctx, db, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if organizer.AcceptedAt != nil {
+		return organizer, nil
+	}
+
+	now := time.Now()
+	organizer.AcceptedAt = &now
+
+	_, err = db.NewUpdate().
+		Model(organizer).
+		Column("accepted_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return organizer, nil
+}
+
+// UpdateTaskOrganizerPermissions replaces the permissions of the co-organizer with the ones
+// set on permissions.
+func UpdateTaskOrganizerPermissions(
+	ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, permissions models.TaskOrganizer,
+) (*models.TaskOrganizer, error) {
+	organizer, err := FetchTaskOrganizer(ctx, db, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizer.CanEditDetails = permissions.CanEditDetails
+	organizer.CanManageVolunteers = permissions.CanManageVolunteers
+	organizer.CanMessageVolunteers = permissions.CanMessageVolunteers
+
+	_, err = db.NewUpdate().
+		Model(organizer).
+		Column("can_edit_details", "can_manage_volunteers", "can_message_volunteers").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return organizer, nil
+}
+
+// RemoveTaskOrganizer takes the user off the task's co-organizers, or withdraws their
+// pending invitation.
+func RemoveTaskOrganizer(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID) error {
+	organizer, err := FetchTaskOrganizer(ctx, db, taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewDelete().
+		Model(organizer).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
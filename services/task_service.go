@@ -1,11 +1,13 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"rashikzaman/api/constants"
 	"rashikzaman/api/models"
 	"rashikzaman/api/utils"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/paulmach/orb"
@@ -13,18 +15,87 @@ import (
 
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// TaskListSpec is what task listings can be sorted and filtered by on top of Filter.
+// Sorting by distance needs a location to measure from.
+var TaskListSpec = utils.ListSpec{
+	SortColumns: map[string]string{
+		"created_at":                "task.created_at",
+		"starts_at":                 "task.starts_at",
+		"ends_at":                   "task.ends_at",
+		"title":                     "task.title",
+		"required_volunteers_count": "task.required_volunteers_count",
+		"distance":                  "distance_km",
+	},
+	DefaultSort:       "created_at",
+	CursorSortColumns: []string{"created_at", "distance"},
+	FilterColumns: map[string]string{
+		"title":             "task.title",
+		"description":       "task.description",
+		"formatted_address": "task.formatted_address",
+	},
+	DateColumns: map[string]string{
+		"created_at": "task.created_at",
+		"updated_at": "task.updated_at",
+		"starts_at":  "task.starts_at",
+		"ends_at":    "task.ends_at",
+	},
+}
+
 const (
-	S3BucketName = "act-local"
-	S3Region     = "ap-southeast-1"
-	S3FolderPath = "uploads/images"
+	// taskDistanceExpr is the distance in kilometers of a task from the longitude and latitude
+	// it is given.
+	taskDistanceExpr = "ST_Distance(ST_MakePoint(?, ?)::geography, task.location::geography) / 1000"
+	// taskSearchRankExpr ranks a task against the search term it is given, counting the
+	// category's name as well as the task's own text. The rank is a real, cast to double
+	// precision so that the value handed back in cursors compares equal to it.
+	taskSearchRankExpr = `(ts_rank_cd(task.search_vector || COALESCE((
+		SELECT setweight(to_tsvector('english', name), 'C') FROM categories WHERE id = task.category_id
+	), ''), websearch_to_tsquery('english', ?)))::float8`
+	// taskMatchScoreExpr rates from 0 to 1 how well a task suits the user it is given, see
+	// taskMatchScoreArgs: the share of the task's required skills the user declared, weighed
+	// against how close the task is to the user's location. Tasks requiring no skills and
+	// users without a location score nothing on that part.
+	taskMatchScoreExpr = `? * COALESCE((
+		SELECT COUNT(*) FROM user_skills us JOIN skills s ON s.id = us.skill_id
+		WHERE us.user_id = ? AND s.name = ANY(task.required_skills)
+	)::float / NULLIF(cardinality(task.required_skills), 0), 0) + (1 - ?) * COALESCE((
+		SELECT ? / (? + ST_Distance(ul.location::geography, task.location::geography) / 1000)
+		FROM user_locations ul WHERE ul.user_id = ? LIMIT 1
+	), 0)`
+)
+
+func taskMatchScoreArgs(userID uuid.UUID) []interface{} {
+	return []interface{}{
+		constants.MatchSkillWeight, userID, constants.MatchSkillWeight,
+		constants.MatchDistanceHalfScoreKm, constants.MatchDistanceHalfScoreKm, userID,
+	}
+}
+
+// searchHighlightOptions marks the matched words in highlighted search snippets.
+const searchHighlightOptions = "StartSel=<mark>, StopSel=</mark>"
+
+var (
+	ErrInvalidTaskStatus           = errors.New("invalid task status")
+	ErrInvalidTaskStatusTransition = errors.New("invalid task status transition")
+	ErrTaskNotEditable             = errors.New("task can no longer be edited")
+	ErrTaskStatusNotEditable       = errors.New("the status of a task is changed through its status endpoint")
+	ErrTaskNotOpen                 = errors.New("task is not open for applications")
+	ErrInvalidSchedule             = errors.New("task must start before it ends")
+	ErrInvalidTimeZone             = errors.New("invalid time zone")
 )
 
+// taskStatusTransitions lists, for every status, the statuses a task may move to next.
+var taskStatusTransitions = map[string][]string{
+	models.TaskStatusDraft:      {models.TaskStatusOpen, models.TaskStatusCancelled},
+	models.TaskStatusOpen:       {models.TaskStatusFilled, models.TaskStatusInProgress, models.TaskStatusCancelled},
+	models.TaskStatusFilled:     {models.TaskStatusOpen, models.TaskStatusInProgress, models.TaskStatusCancelled},
+	models.TaskStatusInProgress: {models.TaskStatusCompleted, models.TaskStatusCancelled},
+	models.TaskStatusCompleted:  {},
+	models.TaskStatusCancelled:  {},
+}
+
 type Filter struct {
 	CategoryIDs        []string
 	Skills             []string
@@ -34,8 +105,42 @@ type Filter struct {
 	SearchTerm         string
 	CreatedByUserID    uuid.UUID
 	SubscribedByUserID uuid.UUID
-	ApplyBlockFilter   bool
-	Distance           int
+	OrganizationID     uuid.UUID
+	// VerifiedOnly keeps only tasks posted for organizations vetted by the admins
+	VerifiedOnly     bool
+	ApplyBlockFilter bool
+	Distance         int
+	Statuses         []string
+	From             *time.Time
+	To               *time.Time
+	HidePast         bool
+	// HideRecurringTemplates leaves out recurring task templates so only their occurrences
+	// are listed
+	HideRecurringTemplates bool
+	// SortByRelevance orders search results by their rank, it has no effect without a
+	// SearchTerm
+	SortByRelevance bool
+	// MatchUserID ranks tasks by how well they suit the user's skills and location, best
+	// match first
+	MatchUserID uuid.UUID
+	// AvailableForUserID keeps only the tasks the user is free for, see availableForTaskExpr
+	AvailableForUserID uuid.UUID
+}
+
+func IsValidTaskStatus(status string) bool {
+	_, ok := taskStatusTransitions[status]
+
+	return ok
+}
+
+func CanTransitionTaskStatus(from, to string) bool {
+	for _, status := range taskStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
 }
 
 func CreateTask(
@@ -44,67 +149,82 @@ func CreateTask(
 ) error {
 	taskBody.Location = models.PostgisGeometry{Geometry: orb.Point{taskBody.Longitude, taskBody.Latitude}, SRID: 4326}
 	taskBody.UserID = userID
+	// only GenerateOccurrences ties tasks to a series
+	taskBody.ParentTaskID = nil
+	taskBody.RecurrenceGeneratedUntil = nil
+
+	// a new task is either published right away or kept as a draft
+	if taskBody.Status == "" {
+		taskBody.Status = models.TaskStatusOpen
+	}
+
+	if taskBody.Status != models.TaskStatusOpen && taskBody.Status != models.TaskStatusDraft {
+		return ErrInvalidTaskStatus
+	}
 
-	_, err := db.NewInsert().Model(taskBody).Exec(ctx)
+	err := validateSchedule(taskBody)
 	if err != nil {
-		return errors.Wrap(err, err.Error())
+		return err
 	}
 
-	cfg := aws.Config{
-		Region:      "ap-southeast-1",
-		Credentials: credentials.NewStaticCredentialsProvider(awsAccessKey, awsSecretAccessKey, ""),
+	if taskBody.CheckInRadiusMeters < 0 {
+		return ErrInvalidCheckInArea
 	}
 
-	if awsAccessKey != "" && awsSecretAccessKey != "" {
-		for _, media := range taskBody.Media {
-			decoded, err := utils.DecodeBase64Image(media.Base64)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				return err
-			}
+	if taskBody.MinReliabilityScore < 0 || taskBody.MinReliabilityScore > 100 {
+		return ErrInvalidMinReliability
+	}
 
-			mimetype, err := utils.GetMimeTypeFromBase64(media.Base64)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				return err
-			}
+	taskBody.RequiredSkills, err = ResolveSkillNames(ctx, db, taskBody.RequiredSkills)
+	if err != nil {
+		return err
+	}
 
-			fileExt := ""
-			switch mimetype {
-			case "image/jpeg":
-				fileExt = ".jpg"
-			case "image/png":
-				fileExt = ".png"
-			case "image/gif":
-				fileExt = ".gif"
-			default:
-				fileExt = ".bin"
-			}
+	// posting as an organization is up to its owners and managers
+	if taskBody.OrganizationID != nil {
+		err = validateTaskOrganization(ctx, db, *taskBody.OrganizationID, userID)
+		if err != nil {
+			return err
+		}
+	}
 
-			filename := fmt.Sprintf("%s/%s%s", S3FolderPath, uuid.New().String(), fileExt)
+	for _, shift := range taskBody.Shifts {
+		err = validateShift(shift)
+		if err != nil {
+			return err
+		}
+	}
 
-			client := s3.NewFromConfig(cfg)
+	if taskBody.RecurrenceRule != "" {
+		_, err = ParseRecurrenceRule(taskBody)
+		if err != nil {
+			return err
+		}
+	}
 
-			uploadParams := &s3.PutObjectInput{
-				Bucket:        aws.String(S3BucketName),
-				Key:           aws.String(filename),
-				Body:          bytes.NewReader(decoded),
-				ContentType:   aws.String(mimetype),
-				ContentLength: aws.Int64(int64(len(decoded))),
-			}
+	_, err = db.NewInsert().Model(taskBody).Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
 
-			// Upload to S3
-			_, err = client.PutObject(ctx, uploadParams)
+	for _, shift := range taskBody.Shifts {
+		shift.TaskID = taskBody.ID
+
+		err = models.Create(ctx, db, shift)
+		if err != nil {
+			return err
+		}
+	}
+
+	if awsAccessKey != "" && awsSecretAccessKey != "" {
+		for _, media := range taskBody.Media {
+			link, mimetype, err := uploadMedia(ctx, awsAccessKey, awsSecretAccessKey, S3FolderPath, media.Base64)
 			if err != nil {
-				fmt.Printf("Error uploading to S3: %v\n", err)
-				return errors.Wrap(err, "failed to upload file to S3")
+				return err
 			}
 
-			// Generate the S3 URL for the uploaded file
-			s3URL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", S3BucketName, S3Region, filename)
-
 			newMedia := &models.TaskMedia{}
-			newMedia.Link = s3URL
+			newMedia.Link = link
 			newMedia.MimeType = mimetype
 			newMedia.TaskID = taskBody.ID
 
@@ -113,7 +233,14 @@ func CreateTask(
 			if err != nil {
 				return errors.Wrap(err, err.Error())
 			}
+		}
+	}
 
+	// occurrences copy the template's shifts and media, so they are generated last
+	if taskBody.RecurrenceRule != "" {
+		_, err = GenerateOccurrences(ctx, db, taskBody, time.Now().AddDate(0, 0, constants.RecurrenceWindowDays))
+		if err != nil {
+			return err
 		}
 	}
 
@@ -127,75 +254,281 @@ func FetchTasks(
 	tasks := []models.Task{}
 
 	query := db.NewSelect().
-		Model(&tasks)
+		Model(&tasks).
+		Column("task.*").
+		ColumnExpr(
+			`CASE WHEN task.required_volunteers_count > 0 THEN GREATEST(task.required_volunteers_count - (
+			SELECT COUNT(*) FROM user_tasks ut
+			WHERE ut.task_id = task.id AND ut.shift_id IS NULL AND ut.status = 'accepted' AND ut.waitlisted = FALSE
+		), 0) END AS remaining_spots`,
+		).
+		ColumnExpr(
+			`(
+			SELECT COUNT(*) FROM user_tasks ut
+			WHERE ut.task_id = task.id AND ut.status = 'accepted' AND ut.waitlisted = TRUE
+		) AS waitlist_count`,
+		).
+		ColumnExpr(
+			`EXISTS (
+			SELECT 1 FROM organizations o WHERE o.id = task.organization_id AND o.verified_at IS NOT NULL
+		) AS verified_organizer`,
+		)
+
+	applyFilter(query, filter)
+	queryParam.Pagination.ApplyFilters(query, TaskListSpec)
+
+	// how far each task is from the location searched around
+	if filter.Latitude != 0 && filter.Longitude != 0 {
+		query.ColumnExpr(taskDistanceExpr+" AS distance_km", filter.Longitude, filter.Latitude)
+	}
+
+	//check if the current user is subscribed to this Task
+	if filter.SubscribeUserID != uuid.Nil {
+		query.ColumnExpr(
+			`EXISTS (
+			SELECT 1 FROM user_tasks ut
+			WHERE ut.task_id = task.id AND ut.user_id = ? AND ut.status IN ('pending', 'accepted')
+		) AS is_subscribed`, filter.SubscribeUserID,
+		)
+	}
+
+	if filter.SearchTerm != "" {
+		query.ColumnExpr(taskSearchRankExpr+" AS search_rank", filter.SearchTerm)
+		query.ColumnExpr(
+			"ts_headline('english', task.title, websearch_to_tsquery('english', ?), ?) AS title_highlight",
+			filter.SearchTerm, searchHighlightOptions+", HighlightAll=true",
+		)
+		query.ColumnExpr(
+			"ts_headline('english', task.description, websearch_to_tsquery('english', ?), ?) AS description_highlight",
+			filter.SearchTerm, searchHighlightOptions+", MaxFragments=2, MaxWords=20, MinWords=8",
+		)
+	}
+
+	if filter.MatchUserID != uuid.Nil {
+		query.ColumnExpr(taskMatchScoreExpr+" AS match_score", taskMatchScoreArgs(filter.MatchUserID)...)
+	}
+
+	if queryParam.Pagination.UseCursor {
+		count, err := queryParam.Pagination.BuildKeysetPaginationQuery(ctx, query, taskKeyset(queryParam.Pagination, filter))
+		if err != nil {
+			return tasks, 0, errors.Wrap(err, err.Error())
+		}
 
+		return tasks, count, scanTasks(ctx, query, queryParam)
+	}
+
+	count, err := queryParam.Pagination.BuildPaginationQuery(ctx, query)
+	if err != nil {
+		return tasks, 0, errors.Wrap(err, err.Error())
+	}
+
+	if filter.SearchTerm != "" && filter.SortByRelevance {
+		query.OrderExpr("search_rank DESC")
+	}
+
+	if filter.MatchUserID != uuid.Nil {
+		query.OrderExpr("match_score DESC")
+	}
+
+	// nearest first unless asked otherwise, distance is only known around a location
+	switch {
+	case queryParam.Pagination.SortColumn != "distance":
+		queryParam.Pagination.ApplySorting(query, TaskListSpec)
+	case filter.Latitude != 0 && filter.Longitude != 0:
+		direction := "ASC"
+		if strings.EqualFold(queryParam.Pagination.SortDirection, "desc") {
+			direction = "DESC"
+		}
+
+		query.OrderExpr("distance_km " + direction)
+	}
+
+	return tasks, count, scanTasks(ctx, query, queryParam)
+}
+
+func scanTasks(ctx context.Context, query *bun.SelectQuery, queryParam models.QueryParam) error {
+	if len(queryParam.Relations) != 0 {
+		for _, relation := range queryParam.Relations {
+			query.Relation(relation)
+		}
+	}
+
+	err := query.Scan(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// ValidateTaskListQuery rejects the sorting and filtering TaskListSpec does not allow, and
+// sorting by distance without a location to measure it from.
+func ValidateTaskListQuery(pagination utils.PaginationConfig, filter Filter) error {
+	err := pagination.Validate(TaskListSpec)
+	if err != nil {
+		return err
+	}
+
+	if pagination.SortColumn == "distance" && (filter.Latitude == 0 || filter.Longitude == 0) {
+		return fmt.Errorf("%w: sorting by distance needs a latitude and longitude", utils.ErrInvalidListQuery)
+	}
+
+	return nil
+}
+
+// taskKeyset returns the columns tasks are ordered by in cursor mode: by relevance when
+// searching with sort=relevance, by match score in matching mode, by distance when sorting
+// by it around a location and by creation time otherwise, with the id breaking ties.
+func taskKeyset(pagination utils.PaginationConfig, filter Filter) []utils.KeysetColumn {
+	descending := !strings.EqualFold(pagination.SortDirection, "asc")
+
+	switch {
+	case filter.SearchTerm != "" && filter.SortByRelevance:
+		return []utils.KeysetColumn{
+			{Name: "search_rank", Expr: taskSearchRankExpr, Args: []interface{}{filter.SearchTerm}, Descending: true},
+			{Name: "created_at", Expr: "task.created_at", Descending: true},
+			{Name: "id", Expr: "task.id", Descending: true},
+		}
+	case filter.MatchUserID != uuid.Nil:
+		return []utils.KeysetColumn{
+			{Name: "match_score", Expr: taskMatchScoreExpr, Args: taskMatchScoreArgs(filter.MatchUserID), Descending: true},
+			{Name: "created_at", Expr: "task.created_at", Descending: true},
+			{Name: "id", Expr: "task.id", Descending: true},
+		}
+	case pagination.SortColumn == "distance" && filter.Latitude != 0 && filter.Longitude != 0:
+		// nearest first unless asked otherwise
+		descending = strings.EqualFold(pagination.SortDirection, "desc")
+
+		return []utils.KeysetColumn{
+			{
+				Name: "distance", Expr: taskDistanceExpr,
+				Args: []interface{}{filter.Longitude, filter.Latitude}, Descending: descending,
+			},
+			{Name: "id", Expr: "task.id", Descending: descending},
+		}
+	default:
+		return []utils.KeysetColumn{
+			{Name: "created_at", Expr: "task.created_at", Descending: descending},
+			{Name: "id", Expr: "task.id", Descending: descending},
+		}
+	}
+}
+
+// NextTasksCursor returns the cursor of the page following tasks, fetched with the same
+// pagination and filter, or an empty string when tasks is the last page.
+func NextTasksCursor(pagination utils.PaginationConfig, filter Filter, tasks []models.Task) (string, error) {
+	if len(tasks) == 0 {
+		return "", nil
+	}
+
+	keyset := taskKeyset(pagination, filter)
+	last := tasks[len(tasks)-1]
+	values := make([]interface{}, 0, len(keyset))
+
+	for _, column := range keyset {
+		switch column.Name {
+		case "search_rank":
+			values = append(values, last.SearchRank)
+		case "match_score":
+			values = append(values, last.MatchScore)
+		case "distance":
+			values = append(values, last.DistanceKm)
+		case "created_at":
+			values = append(values, last.CreatedAt)
+		case "id":
+			values = append(values, last.ID)
+		}
+	}
+
+	return pagination.NextCursor(len(tasks), keyset, values...)
+}
+
+// applyFilter adds the conditions of filter to a query over tasks aliased as "task", so
+// that every listing of tasks (pages, map, exports) narrows them down the same way.
+func applyFilter(query *bun.SelectQuery, filter Filter) *bun.SelectQuery {
 	query.WhereGroup("AND", func(sq *bun.SelectQuery) *bun.SelectQuery {
 		if len(filter.CategoryIDs) > 0 {
-			sq.Where("category_id IN (?)", bun.In(filter.CategoryIDs))
+			sq.Where("task.category_id IN (?)", bun.In(filter.CategoryIDs))
 		}
 
 		if len(filter.Skills) > 0 {
-			sq.WhereOr("required_skills && ?", pgdialect.Array(filter.Skills))
+			sq.WhereOr("task.required_skills && ?", pgdialect.Array(filter.Skills))
 		}
 
 		return sq
 	})
 
-	// find posts within specified meter's radius
+	// find posts within specified kilometer's radius
 	if filter.Latitude != 0 && filter.Longitude != 0 {
+		distance := filter.Distance
+		if distance <= 0 {
+			distance = constants.DefaultSearchRadiusKm
+		}
+
 		query.Where(
-			"ST_DWithin(ST_MakePoint(?, ?)::geography, location::geography, ?)",
-			filter.Longitude, filter.Latitude, filter.Distance*1000,
+			"ST_DWithin(ST_MakePoint(?, ?)::geography, task.location::geography, ?)",
+			filter.Longitude, filter.Latitude, distance*1000,
 		)
 	}
 
-	//check if the current user is subscribed to this Task
-	if filter.SubscribeUserID != uuid.Nil {
-		query.Column("task.*")
-		query.ColumnExpr(
-			`EXISTS (
-			SELECT 1 FROM user_tasks ut
-			WHERE ut.task_id = task.id AND ut.user_id = ?
-		) AS is_subscribed`, filter.SubscribeUserID,
-		)
+	if filter.CreatedByUserID != uuid.Nil {
+		query.Where("task.user_id = ?", filter.CreatedByUserID)
 	}
 
-	if filter.CreatedByUserID != uuid.Nil {
-		query.Where("user_id = ?", filter.CreatedByUserID)
+	if filter.OrganizationID != uuid.Nil {
+		query.Where("task.organization_id = ?", filter.OrganizationID)
+	}
+
+	if filter.VerifiedOnly {
+		query.Where("task.organization_id IN (SELECT id FROM organizations WHERE verified_at IS NOT NULL)")
 	}
 
 	if filter.SubscribedByUserID != uuid.Nil {
-		fmt.Println(filter.SubscribedByUserID)
 		query.Join("INNER JOIN user_tasks ON user_tasks.task_id = task.id").
-			Where("user_tasks.user_id = ?", filter.SubscribedByUserID)
+			Where("user_tasks.user_id = ?", filter.SubscribedByUserID).
+			Where("user_tasks.status IN (?)", bun.In(ActiveApplicationStatuses))
 	}
 
 	if filter.ApplyBlockFilter {
 		query.Where("task.blocked = FALSE")
 	}
 
-	//case-insensitive search
-	if filter.SearchTerm != "" {
-		query.Where("title ILIKE ?", "%"+filter.SearchTerm+"%")
+	if len(filter.Statuses) > 0 {
+		query.Where("task.status IN (?)", bun.In(filter.Statuses))
 	}
 
-	count, err := queryParam.Pagination.BuildPaginationQuery(ctx, query)
-	if err != nil {
-		return tasks, 0, errors.Wrap(err, err.Error())
+	// tasks overlapping the requested period, unscheduled tasks have no place in it
+	if filter.From != nil {
+		query.Where("COALESCE(task.ends_at, task.starts_at) >= ?", *filter.From)
 	}
 
-	if len(queryParam.Relations) != 0 {
-		for _, relation := range queryParam.Relations {
-			query.Relation(relation)
-		}
+	if filter.To != nil {
+		query.Where("task.starts_at < ?", *filter.To)
 	}
 
-	err = query.Scan(ctx)
-	if err != nil {
-		return tasks, 0, errors.Wrap(err, err.Error())
+	if filter.HidePast {
+		query.Where("task.ends_at IS NULL OR task.ends_at > NOW()")
+	}
+
+	if filter.HideRecurringTemplates {
+		query.Where("task.recurrence_rule = ''")
 	}
 
-	return tasks, count, nil
+	if filter.AvailableForUserID != uuid.Nil {
+		query.Where(availableForTaskExpr, filter.AvailableForUserID)
+	}
+
+	// full-text search over the task's own fields (see tasks.search_vector) and its category
+	if filter.SearchTerm != "" {
+		query.Where(
+			`task.search_vector @@ websearch_to_tsquery('english', ?) OR task.category_id IN (
+				SELECT id FROM categories WHERE to_tsvector('english', name) @@ websearch_to_tsquery('english', ?)
+			)`,
+			filter.SearchTerm, filter.SearchTerm,
+		)
+	}
+
+	return query
 }
 
 func FetchTaskByID(ctx context.Context, db bun.IDB, id uuid.UUID, queryParam models.QueryParam) (models.Task, error) {
@@ -209,9 +542,39 @@ func FetchTaskByID(ctx context.Context, db bun.IDB, id uuid.UUID, queryParam mod
 	return task, nil
 }
 
+// taskEditableColumns are the columns UpdateTask writes. The status and everything
+// applications keep up to date are left to their own queries.
+var taskEditableColumns = []string{
+	"title", "description", "latitude", "longitude", "location", "formatted_address", "category_id",
+	"required_skills", "required_volunteers_count", "auto_accept", "starts_at", "ends_at", "time_zone",
+	"check_in_radius_meters", "qr_check_in_required", "min_reliability_score",
+}
+
+// UpdateTask applies taskBody to the task. It locks the task row like applications do, and
+// when the number of volunteers needed changes, promotes waitlisted volunteers into new
+// spots and fills or reopens the task to match. The promoted applications are returned.
 func UpdateTask(
 	ctx context.Context, db bun.IDB, existingTask models.Task, taskBody models.Task,
-) (models.Task, error) {
+) (models.Task, []*models.UserTask, error) {
+	task, err := lockTask(ctx, db, existingTask.ID)
+	if err != nil {
+		return existingTask, nil, err
+	}
+
+	existingTask = *task
+
+	if existingTask.Status == models.TaskStatusCompleted || existingTask.Status == models.TaskStatusCancelled {
+		return existingTask, nil, ErrTaskNotEditable
+	}
+
+	// status changes go through ChangeTaskStatus, which tells the volunteers and settles
+	// their attendance
+	if taskBody.Status != "" && taskBody.Status != existingTask.Status {
+		return existingTask, nil, ErrTaskStatusNotEditable
+	}
+
+	capacityChanged := taskBody.RequiredVolunteersCount != existingTask.RequiredVolunteersCount
+
 	existingTask.Title = taskBody.Title
 	existingTask.Description = taskBody.Description
 	existingTask.Latitude = taskBody.Latitude
@@ -223,13 +586,58 @@ func UpdateTask(
 	existingTask.CategoryID = taskBody.CategoryID
 	existingTask.RequiredSkills = taskBody.RequiredSkills
 	existingTask.RequiredVolunteersCount = taskBody.RequiredVolunteersCount
+	existingTask.AutoAccept = taskBody.AutoAccept
+	existingTask.StartsAt = taskBody.StartsAt
+	existingTask.EndsAt = taskBody.EndsAt
+	existingTask.TimeZone = taskBody.TimeZone
+	existingTask.CheckInRadiusMeters = taskBody.CheckInRadiusMeters
+	existingTask.QRCheckInRequired = taskBody.QRCheckInRequired
+	existingTask.MinReliabilityScore = taskBody.MinReliabilityScore
+
+	err = validateSchedule(&existingTask)
+	if err != nil {
+		return existingTask, nil, err
+	}
+
+	// the times of a series' template and occurrences are what its later occurrences are
+	// generated and shifted from
+	isSeries := existingTask.RecurrenceRule != "" || existingTask.ParentTaskID != nil
+	if isSeries && (existingTask.StartsAt == nil || existingTask.EndsAt == nil) {
+		return existingTask, nil, ErrRecurrenceNeedsSchedule
+	}
+
+	if existingTask.CheckInRadiusMeters < 0 {
+		return existingTask, nil, ErrInvalidCheckInArea
+	}
+
+	if existingTask.MinReliabilityScore < 0 || existingTask.MinReliabilityScore > 100 {
+		return existingTask, nil, ErrInvalidMinReliability
+	}
 
-	err := models.Update(ctx, db, &existingTask)
+	existingTask.RequiredSkills, err = ResolveSkillNames(ctx, db, existingTask.RequiredSkills)
 	if err != nil {
-		return existingTask, errors.Wrap(err, err.Error())
+		return existingTask, nil, err
 	}
 
-	return existingTask, nil
+	_, err = db.NewUpdate().
+		Model(&existingTask).
+		Column(taskEditableColumns...).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return existingTask, nil, errors.Wrap(err, err.Error())
+	}
+
+	if !capacityChanged {
+		return existingTask, nil, nil
+	}
+
+	promoted, err := rebalanceSpots(ctx, db, &existingTask)
+	if err != nil {
+		return existingTask, nil, err
+	}
+
+	return existingTask, promoted, nil
 }
 
 func DeleteTask(ctx context.Context, db bun.IDB, taskID uuid.UUID) error {
@@ -244,33 +652,93 @@ func DeleteTask(ctx context.Context, db bun.IDB, taskID uuid.UUID) error {
 	return nil
 }
 
-func ApplyToTask(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID) error {
-	userTask := &models.UserTask{UserID: userID, TaskID: taskID}
+// ChangeTaskStatus moves a task to the given status if the transition is allowed.
+func ChangeTaskStatus(ctx context.Context, db bun.IDB, taskID uuid.UUID, status string) (*models.Task, error) {
+	if !IsValidTaskStatus(status) {
+		return nil, ErrInvalidTaskStatus
+	}
+
+	// locked so that the transition is checked against the status applications may be
+	// changing at the same time between open and filled
+	task, err := lockTask(ctx, db, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !CanTransitionTaskStatus(task.Status, status) {
+		return nil, ErrInvalidTaskStatusTransition
+	}
+
+	err = updateTaskStatus(ctx, db, task, status)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == models.TaskStatusCompleted {
+		_, err = RecordTaskOutcomes(ctx, db, task.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return task, nil
+}
+
+func CompleteTask(ctx context.Context, db bun.IDB, taskID uuid.UUID) (*models.Task, error) {
+	return ChangeTaskStatus(ctx, db, taskID, models.TaskStatusCompleted)
+}
 
-	return models.Create(ctx, db, userTask)
+func CancelTask(ctx context.Context, db bun.IDB, taskID uuid.UUID) (*models.Task, error) {
+	return ChangeTaskStatus(ctx, db, taskID, models.TaskStatusCancelled)
 }
 
-func WithdrawFromTask(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID) error {
-	userTask := &models.UserTask{}
+// LoadTimeZone loads a time zone given by its IANA name, or fails with ErrInvalidTimeZone.
+// Go takes "Local" to be the server's time zone, but Postgres does not know it and the zones
+// of tasks and users end up in its queries, so it is refused.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
 
-	_, err := db.NewDelete().Model(userTask).Where("user_id = ?", userID).Where("task_id = ?", taskID).Exec(ctx)
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
 
-	return err
+	return location, nil
 }
 
-func FetchSubscribersForTask(ctx context.Context, db bun.IDB, taskID uuid.UUID) ([]models.UserTask, error) {
-	var userTasks []models.UserTask
+// validateSchedule checks the task's start/end times and time zone, defaulting the time
+// zone to UTC.
+func validateSchedule(task *models.Task) error {
+	if task.TimeZone == "" {
+		task.TimeZone = "UTC"
+	}
 
-	query := db.NewSelect().
-		Model(&userTasks).
-		Join("JOIN tasks ON tasks.id = user_task.task_id").
-		Where("user_task.task_id = ?", taskID).
-		Relation("User").
-		Relation("Task")
+	if _, err := LoadTimeZone(task.TimeZone); err != nil {
+		return err
+	}
 
-	err := query.Scan(ctx)
+	if task.EndsAt != nil && (task.StartsAt == nil || !task.EndsAt.After(*task.StartsAt)) {
+		return ErrInvalidSchedule
+	}
 
-	return userTasks, err
+	return nil
+}
+
+func updateTaskStatus(ctx context.Context, db bun.IDB, task *models.Task, status string) error {
+	task.Status = status
+
+	_, err := db.NewUpdate().
+		Model(task).
+		Column("status").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
 }
 
 func ApplyActionToTask(ctx context.Context, db bun.IDB, taskID uuid.UUID, action string) (*models.Task, error) {
@@ -290,12 +758,38 @@ func ApplyActionToTask(ctx context.Context, db bun.IDB, taskID uuid.UUID, action
 	return &task, err
 }
 
+// FetchNearbyUsersOfTask lists the users to alert of a new task: those whose alert radius,
+// distance by default, reaches it, who are free at its time, follow its category and one of
+// its skills when they narrowed their alerts to some, and have not had their daily cap of
+// alerts yet. Users in digest mode get the task in their next digest instead.
 func FetchNearbyUsersOfTask(ctx context.Context, db bun.IDB, taskID uuid.UUID, latitude, longitude float32, distance int) ([]models.UserLocation, error) {
 	userLocations := []models.UserLocation{}
 	query := db.NewSelect().Model(&userLocations)
+	query.Join("LEFT JOIN notification_preferences AS np ON np.user_id = user_location.user_id")
+	query.Where(
+		"ST_DWithin(ST_MakePoint(?, ?)::geography, user_location.location::geography, COALESCE(np.alert_radius_km, ?) * 1000)",
+		longitude, latitude, distance,
+	)
+	query.Where("np.digest_frequency IS NULL OR np.digest_frequency = ?", models.DigestFrequencyOff)
+
+	// only people free at the time of the task, and interested in it, are told about it
+	query.Where(
+		`EXISTS (
+			SELECT 1 FROM tasks AS task WHERE task.id = ? AND `+availableForTaskExpr+`
+			AND (np.category_ids IS NULL OR np.category_ids = '{}' OR task.category_id = ANY(np.category_ids))
+			AND (np.skills IS NULL OR np.skills = '{}' OR task.required_skills && np.skills)
+		)`,
+		taskID, bun.Ident("user_location.user_id"),
+	)
+
+	// the daily cap counts the alerts since midnight in the user's time zone
 	query.Where(
-		"ST_DWithin(ST_MakePoint(?, ?)::geography, location::geography, ?)",
-		longitude, latitude, distance*1000,
+		`np.daily_cap IS NULL OR (
+			SELECT COUNT(*) FROM notifications AS n
+			WHERE n.user_id = user_location.user_id AND n.event_type = ?
+			AND n.created_at >= date_trunc('day', NOW() AT TIME ZONE "user".time_zone) AT TIME ZONE "user".time_zone
+		) < np.daily_cap`,
+		NotificationEventTaskCreatedNearby,
 	)
 
 	query.Relation("User")
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"rashikzaman/api/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var ErrInvalidShiftCapacity = errors.New("shift capacity cannot be negative")
+
+func CreateTaskShift(ctx context.Context, db bun.IDB, taskID uuid.UUID, shift *models.TaskShift) error {
+	err := validateShift(shift)
+	if err != nil {
+		return err
+	}
+
+	shift.TaskID = taskID
+
+	return models.Create(ctx, db, shift)
+}
+
+func FetchTaskShifts(ctx context.Context, db bun.IDB, taskID uuid.UUID) ([]models.TaskShift, error) {
+	shifts := []models.TaskShift{}
+
+	err := db.NewSelect().
+		Model(&shifts).
+		Where("task_id = ?", taskID).
+		Order("starts_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return shifts, errors.Wrap(err, err.Error())
+	}
+
+	return shifts, nil
+}
+
+// DeleteTaskShift removes the shift along with the applications made to it.
+func DeleteTaskShift(ctx context.Context, db bun.IDB, taskID, shiftID uuid.UUID) error {
+	result, err := db.NewDelete().
+		Model((*models.TaskShift)(nil)).
+		Where("id = ?", shiftID).
+		Where("task_id = ?", taskID).
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrShiftNotFound
+	}
+
+	return nil
+}
+
+func validateShift(shift *models.TaskShift) error {
+	if !shift.EndsAt.After(shift.StartsAt) {
+		return ErrInvalidSchedule
+	}
+
+	if shift.Capacity < 0 {
+		return ErrInvalidShiftCapacity
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"rashikzaman/api/models"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var ErrInvalidTile = errors.New("invalid tile coordinates")
+
+const (
+	// TaskTileLayer is the name of the layer tasks are put in within a vector tile.
+	TaskTileLayer = "tasks"
+
+	tileExtent = 4096
+	tileBuffer = 64
+)
+
+// FetchTaskTile renders the tasks matching filter inside the z/x/y web mercator tile as a
+// Mapbox Vector Tile.
+func FetchTaskTile(ctx context.Context, db bun.IDB, z, x, y int, filter Filter) ([]byte, error) {
+	if z < 0 || z > 22 || x < 0 || y < 0 || x >= 1<<z || y >= 1<<z {
+		return nil, ErrInvalidTile
+	}
+
+	features := db.NewSelect().
+		TableExpr("tasks AS task").
+		ColumnExpr(
+			"ST_AsMVTGeom(ST_Transform(task.location, 3857), ST_TileEnvelope(?, ?, ?), ?, ?, true) AS geom",
+			z, x, y, tileExtent, tileBuffer,
+		).
+		ColumnExpr("task.id::text AS id, task.title, task.status, task.category_id::text AS category_id").
+		ColumnExpr("task.required_volunteers_count").
+		ColumnExpr("EXTRACT(EPOCH FROM task.starts_at)::bigint AS starts_at").
+		ColumnExpr("EXTRACT(EPOCH FROM task.ends_at)::bigint AS ends_at").
+		Where("task.location && ST_Transform(ST_TileEnvelope(?, ?, ?, margin => ?), 4326)", z, x, y, float64(tileBuffer)/tileExtent)
+
+	applyFilter(features, filter)
+
+	tile := []byte{}
+
+	err := db.NewSelect().
+		With("features", features).
+		TableExpr("features").
+		ColumnExpr("COALESCE(ST_AsMVT(features.*, ?, ?, 'geom'), '')", TaskTileLayer, tileExtent).
+		Scan(ctx, &tile)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return tile, nil
+}
+
+// FetchTasksFeatureCollection returns a page of tasks as a GeoJSON FeatureCollection, with the
+// total number of matching tasks in its "count" member and, in cursor mode, the cursor of
+// the next page in its "nextCursor" member.
+func FetchTasksFeatureCollection(
+	ctx context.Context, db bun.IDB, queryParam models.QueryParam, filter Filter,
+) (*geojson.FeatureCollection, error) {
+	tasks, count, err := FetchTasks(ctx, db, queryParam, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	nextCursor, err := NextTasksCursor(queryParam.Pagination, filter, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := models.TasksFeatureCollection(tasks)
+	collection.ExtraMembers = map[string]interface{}{}
+
+	if !queryParam.Pagination.SkipCount {
+		collection.ExtraMembers["count"] = count
+	}
+
+	if nextCursor != "" {
+		collection.ExtraMembers["nextCursor"] = nextCursor
+	}
+
+	return collection, nil
+}
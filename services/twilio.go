@@ -35,10 +35,21 @@ func SendSMS(to, message, twilioAccountSid, twilioPhoneNumber, twilioAuthToken s
 	}
 	defer resp.Body.Close()
 
-	// Check response status
+	// Check response status, Twilio rejecting the message, say for an invalid number, will
+	// reject it again
+	if isPermanentHTTPFailure(resp.StatusCode) {
+		return fmt.Errorf("%w: unexpected status code: %d", ErrPermanentDeliveryFailure, resp.StatusCode)
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	return nil
 }
+
+// isPermanentHTTPFailure reports whether a provider refused a request for good, as opposed
+// to being rate limited or failing on its end.
+func isPermanentHTTPFailure(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"rashikzaman/api/models"
+	"rashikzaman/api/utils"
 	"strconv"
 	"time"
 
@@ -45,7 +46,20 @@ func FetchUsersForAdmin(ctx context.Context, db bun.IDB, queryParam models.Query
 	query := db.NewSelect().
 		Model(&users)
 
-	count, err := queryParam.Pagination.BuildPaginationQuery(ctx, query)
+	queryParam.Pagination.ApplyFilters(query, UserListSpec)
+
+	var (
+		count int
+		err   error
+	)
+
+	if queryParam.Pagination.UseCursor {
+		count, err = queryParam.Pagination.BuildKeysetPaginationQuery(ctx, query, userKeyset)
+	} else {
+		count, err = queryParam.Pagination.BuildPaginationQuery(ctx, query)
+		queryParam.Pagination.ApplySorting(query, UserListSpec)
+	}
+
 	if err != nil {
 		return users, 0, errors.Wrap(err, err.Error())
 	}
@@ -64,12 +78,57 @@ func FetchUsersForAdmin(ctx context.Context, db bun.IDB, queryParam models.Query
 	return users, count, nil
 }
 
+// UserListSpec is what the admin list of users can be sorted and filtered by.
+var UserListSpec = utils.ListSpec{
+	SortColumns: map[string]string{
+		"created_at": "\"user\".created_at",
+		"first_name": "\"user\".first_name",
+		"last_name":  "\"user\".last_name",
+		"email":      "\"user\".email",
+		"role":       "\"user\".role",
+	},
+	DefaultSort:       "created_at",
+	CursorSortColumns: []string{"created_at"},
+	FilterColumns: map[string]string{
+		"first_name":   "\"user\".first_name",
+		"last_name":    "\"user\".last_name",
+		"email":        "\"user\".email",
+		"phone_number": "\"user\".phone_number",
+		"role":         "\"user\".role",
+	},
+	SearchColumns: []string{
+		"\"user\".first_name", "\"user\".last_name", "\"user\".email", "\"user\".phone_number",
+	},
+	DateColumns: map[string]string{
+		"created_at":    "\"user\".created_at",
+		"date_of_birth": "\"user\".date_of_birth",
+	},
+}
+
+// userKeyset orders users newest first in cursor mode.
+var userKeyset = []utils.KeysetColumn{
+	{Name: "created_at", Expr: "\"user\".created_at", Descending: true},
+	{Name: "id", Expr: "\"user\".id", Descending: true},
+}
+
+// NextUsersCursor returns the cursor of the page following users, or an empty string when
+// users is the last page.
+func NextUsersCursor(pagination utils.PaginationConfig, users []models.User) (string, error) {
+	if len(users) == 0 {
+		return "", nil
+	}
+
+	last := users[len(users)-1]
+
+	return pagination.NextCursor(len(users), userKeyset, last.CreatedAt, last.ID)
+}
+
 func GetUserByID(ctx context.Context, db bun.IDB, userID uuid.UUID) (*models.User, error) {
 	user := &models.User{}
 	user.ID = userID
 
 	err := models.SelectByID(ctx, db, userID, user, models.QueryParam{
-		Relations: []string{"UserLocations"},
+		Relations: []string{"UserLocations", "AvailabilityWindows", "AvailabilityExceptions", "NotificationPreferences"},
 		Alias:     "user"})
 
 	return user, err
@@ -87,9 +146,16 @@ func ApplyActionToUser(ctx context.Context, db bun.IDB, userID uuid.UUID, action
 		user.Blocked = false
 	}
 
-	err = models.Update(ctx, db, user)
+	_, err = db.NewUpdate().
+		Model(user).
+		Column("blocked").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return user, errors.Wrap(err, err.Error())
+	}
 
-	return user, err
+	return user, nil
 }
 
 func UpdateMe(ctx context.Context, db bun.IDB, existingUser *models.User, userBody models.User) (*models.User, error) {
@@ -98,9 +164,52 @@ func UpdateMe(ctx context.Context, db bun.IDB, existingUser *models.User, userBo
 	existingUser.PhoneNumber = userBody.PhoneNumber
 	existingUser.ReceiveSMSNotification = userBody.ReceiveSMSNotification
 
-	err := models.Update(ctx, db, existingUser)
+	// only what the user edits is written, the reliability counters are kept up to date
+	// by their own queries
+	columns := []string{"first_name", "last_name", "phone_number", "receive_sms_notification"}
+
+	if userBody.TimeZone != "" {
+		_, err := LoadTimeZone(userBody.TimeZone)
+		if err != nil {
+			return existingUser, err
+		}
+
+		existingUser.TimeZone = userBody.TimeZone
+		columns = append(columns, "time_zone")
+	}
+
+	_, err := db.NewUpdate().
+		Model(existingUser).
+		Column(columns...).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return existingUser, errors.Wrap(err, err.Error())
+	}
+
+	// availability and notification preferences are only replaced when they are sent
+	if userBody.AvailabilityWindows != nil {
+		err = SetAvailabilityWindows(ctx, db, existingUser.ID, userBody.AvailabilityWindows)
+		if err != nil {
+			return existingUser, err
+		}
+	}
+
+	if userBody.AvailabilityExceptions != nil {
+		err = SetAvailabilityExceptions(ctx, db, existingUser.ID, userBody.AvailabilityExceptions)
+		if err != nil {
+			return existingUser, err
+		}
+	}
+
+	if userBody.NotificationPreferences != nil {
+		err = SetNotificationPreferences(ctx, db, existingUser.ID, userBody.NotificationPreferences)
+		if err != nil {
+			return existingUser, err
+		}
+	}
 
-	return existingUser, err
+	return existingUser, nil
 }
 
 func CreateOrUpdateUserLocation(
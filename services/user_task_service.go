@@ -0,0 +1,560 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"rashikzaman/api/models"
+	"rashikzaman/api/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	ErrAlreadyApplied               = errors.New("user has already applied to this task")
+	ErrApplicationRejected          = errors.New("application to this task was rejected")
+	ErrApplicationNotFound          = errors.New("application not found")
+	ErrInvalidApplicationTransition = errors.New("invalid application status transition")
+	ErrShiftNotFound                = errors.New("shift not found for this task")
+	ErrShiftRequired                = errors.New("task has shifts, pick one to apply to")
+	ErrWithdrawalClosed             = errors.New("the task is over, it can no longer be withdrawn from")
+)
+
+// ActiveApplicationStatuses are the statuses of applications that still count towards a task.
+var ActiveApplicationStatuses = []string{models.ApplicationStatusPending, models.ApplicationStatusAccepted}
+
+// ApplyToTask records the user's application to the task, or to one of its shifts when
+// shiftID is set. On auto-accept tasks the user is accepted right away, or put on the
+// waitlist once RequiredVolunteersCount (or the shift capacity) is reached; otherwise the
+// application stays pending until the organizer reviews it. It locks the task row, so it
+// must run inside a transaction for concurrent applications to be counted correctly.
+func ApplyToTask(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, error) {
+	task, err := lockTask(ctx, db, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Status != models.TaskStatusOpen && task.Status != models.TaskStatusFilled {
+		return nil, ErrTaskNotOpen
+	}
+
+	if task.RecurrenceRule != "" {
+		return nil, ErrRecurringTaskTemplate
+	}
+
+	err = validateReliability(ctx, db, task, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateShiftChoice(ctx, db, taskID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	userTask, err := fetchApplication(ctx, db, taskID, userID, shiftID)
+
+	switch {
+	case errors.Is(err, ErrApplicationNotFound):
+		userTask = &models.UserTask{UserID: userID, TaskID: taskID, ShiftID: shiftID}
+	case err != nil:
+		return nil, err
+	case userTask.Status == models.ApplicationStatusRejected:
+		return nil, ErrApplicationRejected
+	case userTask.Status != models.ApplicationStatusWithdrawn:
+		return nil, ErrAlreadyApplied
+	}
+
+	userTask.Status = models.ApplicationStatusPending
+	userTask.Waitlisted = false
+
+	if task.AutoAccept {
+		err = admit(ctx, db, task, userTask)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if userTask.ID == uuid.Nil {
+		err = models.Create(ctx, db, userTask)
+		if models.IsUniqueViolation(err) {
+			return nil, ErrAlreadyApplied
+		}
+
+		return userTask, err
+	}
+
+	// re-applying puts the user at the back of the waitlist
+	userTask.CreatedAt = time.Now()
+
+	return userTask, saveApplication(ctx, db, userTask, "created_at")
+}
+
+// WithdrawFromTask marks the user's application as withdrawn and, if they held a spot,
+// promotes the earliest waitlisted user into it. The promoted application is returned, or
+// nil if nobody was waiting. Once the task (or shift) has ended, or the volunteer's
+// attendance was settled, it fails with ErrWithdrawalClosed. Like ApplyToTask it must run
+// inside a transaction.
+func WithdrawFromTask(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, error) {
+	task, err := lockTask(ctx, db, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Status == models.TaskStatusCompleted || task.Status == models.TaskStatusCancelled {
+		return nil, ErrWithdrawalClosed
+	}
+
+	_, endsAt, err := attendanceWindow(ctx, db, task, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	if endsAt != nil && endsAt.Before(time.Now()) {
+		return nil, ErrWithdrawalClosed
+	}
+
+	userTask, err := fetchApplication(ctx, db, taskID, userID, shiftID)
+	if errors.Is(err, ErrApplicationNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.Contains(ActiveApplicationStatuses, userTask.Status) {
+		return nil, nil
+	}
+
+	// the attendance and hours recorded for the volunteer stand
+	if userTask.AttendanceOutcome != "" {
+		return nil, ErrWithdrawalClosed
+	}
+
+	heldSpot := holdsSpot(userTask)
+
+	userTask.Status = models.ApplicationStatusWithdrawn
+	userTask.Waitlisted = false
+
+	err = saveApplication(ctx, db, userTask)
+	if err != nil {
+		return nil, err
+	}
+
+	if !heldSpot {
+		return nil, nil
+	}
+
+	err = recordLateWithdrawal(ctx, db, task, userTask, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return releaseSpot(ctx, db, task, shiftID)
+}
+
+// AcceptApplication accepts a pending (or previously rejected) application, putting the
+// user on the waitlist if the task is already full.
+func AcceptApplication(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, error) {
+	task, err := lockTask(ctx, db, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Status != models.TaskStatusOpen && task.Status != models.TaskStatusFilled {
+		return nil, ErrTaskNotOpen
+	}
+
+	userTask, err := fetchApplication(ctx, db, taskID, userID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userTask.Status != models.ApplicationStatusPending && userTask.Status != models.ApplicationStatusRejected {
+		return nil, ErrInvalidApplicationTransition
+	}
+
+	err = admit(ctx, db, task, userTask)
+	if err != nil {
+		return nil, err
+	}
+
+	return userTask, saveApplication(ctx, db, userTask)
+}
+
+// RejectApplication rejects a pending or accepted application. When the rejected user held
+// a spot, the earliest waitlisted user is promoted and returned as the second value.
+func RejectApplication(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, *models.UserTask, error) {
+	task, err := lockTask(ctx, db, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userTask, err := fetchApplication(ctx, db, taskID, userID, shiftID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !utils.Contains(ActiveApplicationStatuses, userTask.Status) {
+		return nil, nil, ErrInvalidApplicationTransition
+	}
+
+	heldSpot := holdsSpot(userTask)
+
+	userTask.Status = models.ApplicationStatusRejected
+	userTask.Waitlisted = false
+
+	err = saveApplication(ctx, db, userTask)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !heldSpot {
+		return userTask, nil, nil
+	}
+
+	promoted, err := releaseSpot(ctx, db, task, shiftID)
+
+	return userTask, promoted, err
+}
+
+// FetchSubscribersForTask lists the applications to a task, optionally narrowed to the
+// given statuses. Users holding a spot come first, followed by the waitlist in order.
+func FetchSubscribersForTask(ctx context.Context, db bun.IDB, taskID uuid.UUID, statuses ...string) ([]models.UserTask, error) {
+	var userTasks []models.UserTask
+
+	err := subscribersQuery(db, &userTasks, taskID, statuses).
+		Order("user_task.waitlisted ASC", "user_task.created_at ASC").
+		Scan(ctx)
+
+	return userTasks, err
+}
+
+// FetchSubscribersPage is FetchSubscribersForTask one page at a time, following the cursor
+// in queryParam.
+func FetchSubscribersPage(
+	ctx context.Context, db bun.IDB, taskID uuid.UUID, queryParam models.QueryParam, statuses ...string,
+) ([]models.UserTask, int, error) {
+	userTasks := []models.UserTask{}
+
+	query := subscribersQuery(db, &userTasks, taskID, statuses)
+
+	count, err := queryParam.Pagination.BuildKeysetPaginationQuery(ctx, query, subscriberKeyset)
+	if err != nil {
+		return userTasks, 0, errors.Wrap(err, err.Error())
+	}
+
+	err = query.Scan(ctx)
+	if err != nil {
+		return userTasks, 0, errors.Wrap(err, err.Error())
+	}
+
+	return userTasks, count, nil
+}
+
+// NextSubscribersCursor returns the cursor of the page following userTasks, or an empty
+// string when userTasks is the last page.
+func NextSubscribersCursor(pagination utils.PaginationConfig, userTasks []models.UserTask) (string, error) {
+	if len(userTasks) == 0 {
+		return "", nil
+	}
+
+	last := userTasks[len(userTasks)-1]
+
+	return pagination.NextCursor(len(userTasks), subscriberKeyset, last.Waitlisted, last.CreatedAt, last.ID)
+}
+
+// subscriberKeyset keeps the order of FetchSubscribersForTask in cursor mode.
+var subscriberKeyset = []utils.KeysetColumn{
+	{Name: "waitlisted", Expr: "user_task.waitlisted"},
+	{Name: "created_at", Expr: "user_task.created_at"},
+	{Name: "id", Expr: "user_task.id"},
+}
+
+func subscribersQuery(db bun.IDB, userTasks *[]models.UserTask, taskID uuid.UUID, statuses []string) *bun.SelectQuery {
+	query := db.NewSelect().
+		Model(userTasks).
+		Join("JOIN tasks ON tasks.id = user_task.task_id").
+		Where("user_task.task_id = ?", taskID).
+		Relation("User").
+		Relation("Task").
+		Relation("Shift")
+
+	if len(statuses) > 0 {
+		query.Where("user_task.status IN (?)", bun.In(statuses))
+	}
+
+	return query
+}
+
+func IsValidApplicationStatus(status string) bool {
+	switch status {
+	case models.ApplicationStatusPending, models.ApplicationStatusAccepted,
+		models.ApplicationStatusRejected, models.ApplicationStatusWithdrawn:
+		return true
+	default:
+		return false
+	}
+}
+
+// admit accepts the application, on the waitlist if the task or shift has no spot left,
+// and marks the task filled when it takes the last task-level spot. The caller is
+// responsible for saving userTask.
+func admit(ctx context.Context, db bun.IDB, task *models.Task, userTask *models.UserTask) error {
+	capacity, err := capacityOf(ctx, db, task, userTask.ShiftID)
+	if err != nil {
+		return err
+	}
+
+	subscribedCount, err := countSubscribers(ctx, db, task.ID, userTask.ShiftID)
+	if err != nil {
+		return err
+	}
+
+	isFull := capacity > 0 && subscribedCount >= capacity
+
+	userTask.Status = models.ApplicationStatusAccepted
+	userTask.Waitlisted = isFull
+
+	// shifts fill up independently, only task-level applications decide if the task is full
+	if userTask.ShiftID == nil && !isFull && capacity > 0 && subscribedCount+1 >= capacity &&
+		task.Status == models.TaskStatusOpen {
+		return updateTaskStatus(ctx, db, task, models.TaskStatusFilled)
+	}
+
+	return nil
+}
+
+// releaseSpot hands a freed spot on the task or shift to the earliest waitlisted user, or
+// reopens a filled task when nobody is waiting.
+func releaseSpot(ctx context.Context, db bun.IDB, task *models.Task, shiftID *uuid.UUID) (*models.UserTask, error) {
+	promoted, err := nextWaitlisted(ctx, db, task.ID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	if promoted == nil {
+		if shiftID == nil && task.Status == models.TaskStatusFilled {
+			return nil, updateTaskStatus(ctx, db, task, models.TaskStatusOpen)
+		}
+
+		return nil, nil
+	}
+
+	promoted.Waitlisted = false
+
+	return promoted, saveApplication(ctx, db, promoted)
+}
+
+// rebalanceSpots fits the task-level applications to a changed RequiredVolunteersCount:
+// waitlisted users are promoted into the new spots, earliest first, and the task is filled
+// or reopened to match. Volunteers holding a spot keep it when the capacity shrinks. The
+// promoted applications are returned. Tasks split into shifts fill up by shift and are left
+// alone.
+func rebalanceSpots(ctx context.Context, db bun.IDB, task *models.Task) ([]*models.UserTask, error) {
+	promoted := []*models.UserTask{}
+
+	hasShifts, err := db.NewSelect().
+		Model((*models.TaskShift)(nil)).
+		Where("task_id = ?", task.ID).
+		Exists(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	if hasShifts {
+		return promoted, nil
+	}
+
+	subscribedCount, err := countSubscribers(ctx, db, task.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := task.RequiredVolunteersCount
+
+	for capacity == 0 || subscribedCount < capacity {
+		userTask, err := nextWaitlisted(ctx, db, task.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if userTask == nil {
+			break
+		}
+
+		userTask.Waitlisted = false
+
+		err = saveApplication(ctx, db, userTask)
+		if err != nil {
+			return nil, err
+		}
+
+		promoted = append(promoted, userTask)
+		subscribedCount++
+	}
+
+	if task.Status != models.TaskStatusOpen && task.Status != models.TaskStatusFilled {
+		return promoted, nil
+	}
+
+	status := models.TaskStatusOpen
+	if capacity > 0 && subscribedCount >= capacity {
+		status = models.TaskStatusFilled
+	}
+
+	if status != task.Status {
+		err = updateTaskStatus(ctx, db, task, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return promoted, nil
+}
+
+// nextWaitlisted returns the earliest waitlisted application on the task or shift, or nil
+// when nobody is waiting.
+func nextWaitlisted(ctx context.Context, db bun.IDB, taskID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, error) {
+	userTask := &models.UserTask{}
+
+	err := whereShift(db.NewSelect().
+		Model(userTask).
+		Where("task_id = ?", taskID).
+		Where("status = ?", models.ApplicationStatusAccepted).
+		Where("waitlisted = TRUE"), shiftID).
+		Order("created_at ASC", "id ASC").
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return userTask, nil
+}
+
+func holdsSpot(userTask *models.UserTask) bool {
+	return userTask.Status == models.ApplicationStatusAccepted && !userTask.Waitlisted
+}
+
+func fetchApplication(ctx context.Context, db bun.IDB, taskID, userID uuid.UUID, shiftID *uuid.UUID) (*models.UserTask, error) {
+	userTask := &models.UserTask{}
+
+	err := whereShift(db.NewSelect().
+		Model(userTask).
+		Where("task_id = ?", taskID).
+		Where("user_id = ?", userID), shiftID).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrApplicationNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return userTask, nil
+}
+
+func saveApplication(ctx context.Context, db bun.IDB, userTask *models.UserTask, extraColumns ...string) error {
+	_, err := db.NewUpdate().
+		Model(userTask).
+		Column(append([]string{"status", "waitlisted"}, extraColumns...)...).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	return nil
+}
+
+// lockTask loads the task with a row lock so that concurrent capacity checks serialize.
+func lockTask(ctx context.Context, db bun.IDB, taskID uuid.UUID) (*models.Task, error) {
+	task := &models.Task{}
+
+	err := db.NewSelect().
+		Model(task).
+		Where("id = ?", taskID).
+		For("UPDATE").
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, err.Error())
+	}
+
+	return task, nil
+}
+
+// countSubscribers returns the number of users holding a spot on the task or shift,
+// ignoring the waitlist.
+func countSubscribers(ctx context.Context, db bun.IDB, taskID uuid.UUID, shiftID *uuid.UUID) (int, error) {
+	count, err := whereShift(db.NewSelect().
+		Model((*models.UserTask)(nil)).
+		Where("task_id = ?", taskID).
+		Where("status = ?", models.ApplicationStatusAccepted).
+		Where("waitlisted = FALSE"), shiftID).
+		Count(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, err.Error())
+	}
+
+	return count, nil
+}
+
+// capacityOf returns how many volunteers the task, or the given shift of it, can take.
+// Zero means there is no limit.
+func capacityOf(ctx context.Context, db bun.IDB, task *models.Task, shiftID *uuid.UUID) (int, error) {
+	if shiftID == nil {
+		return task.RequiredVolunteersCount, nil
+	}
+
+	shift := &models.TaskShift{}
+
+	err := models.SelectByID(ctx, db, *shiftID, shift, models.QueryParam{})
+	if err != nil {
+		return 0, err
+	}
+
+	return shift.Capacity, nil
+}
+
+// validateShiftChoice makes sure shiftID belongs to the task, and that a shift is picked
+// whenever the task is split into shifts.
+func validateShiftChoice(ctx context.Context, db bun.IDB, taskID uuid.UUID, shiftID *uuid.UUID) error {
+	query := db.NewSelect().
+		Model((*models.TaskShift)(nil)).
+		Where("task_id = ?", taskID)
+
+	if shiftID != nil {
+		query.Where("id = ?", *shiftID)
+	}
+
+	exists, err := query.Exists(ctx)
+	if err != nil {
+		return errors.Wrap(err, err.Error())
+	}
+
+	switch {
+	case shiftID != nil && !exists:
+		return ErrShiftNotFound
+	case shiftID == nil && exists:
+		return ErrShiftRequired
+	}
+
+	return nil
+}
+
+func whereShift(query *bun.SelectQuery, shiftID *uuid.UUID) *bun.SelectQuery {
+	if shiftID == nil {
+		return query.Where("shift_id IS NULL")
+	}
+
+	return query.Where("shift_id = ?", *shiftID)
+}
@@ -0,0 +1,205 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestCheckInCheckOutAndHours() {
+	ctx := context.Background()
+
+	startsAt := time.Now().Add(-time.Hour)
+	endsAt := time.Now().Add(time.Hour)
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	outsider := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+	task := &models.Task{
+		Base:                models.Base{ID: uuid.New()},
+		Title:               "Park cleanup",
+		Description:         "Bring gloves",
+		AutoAccept:          true,
+		Location:            models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+		StartsAt:            &startsAt,
+		EndsAt:              &endsAt,
+		CheckInRadiusMeters: 200,
+		UserID:              owner.ID,
+		CategoryID:          category.ID,
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, volunteer, outsider} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(task).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = services.ApplyToTask(ctx, tx, task.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+
+		// only accepted volunteers can check in
+		_, err = services.CheckIn(ctx, tx, task.ID, outsider.ID, nil, nil, "", "")
+		require.ErrorIs(s.T(), err, services.ErrApplicationNotFound)
+
+		// the task is geofenced
+		_, err = services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, nil, "", "")
+		require.ErrorIs(s.T(), err, services.ErrLocationRequired)
+
+		_, err = services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, &services.CheckInLocation{Latitude: 40.7306, Longitude: -73.9352}, "", "")
+		require.ErrorIs(s.T(), err, services.ErrOutsideGeofence)
+
+		_, err = services.CheckOut(ctx, tx, task.ID, volunteer.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrNotCheckedIn)
+
+		userTask, err := services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, &services.CheckInLocation{Latitude: 40.7129, Longitude: -74.0061}, "", "")
+		require.NoError(s.T(), err)
+		require.NotNil(s.T(), userTask.CheckedInAt)
+
+		_, err = services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, &services.CheckInLocation{Latitude: 40.7129, Longitude: -74.0061}, "", "")
+		require.ErrorIs(s.T(), err, services.ErrAlreadyCheckedIn)
+
+		userTask, err = services.CheckOut(ctx, tx, task.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+		require.NotNil(s.T(), userTask.RecordedMinutes)
+
+		// the organizer corrects the recorded time
+		_, err = services.CorrectHours(ctx, tx, task.ID, volunteer.ID, nil, -5, "", owner.ID)
+		require.ErrorIs(s.T(), err, services.ErrInvalidHours)
+
+		userTask, err = services.CorrectHours(ctx, tx, task.ID, volunteer.ID, nil, 120, "Forgot to check in", owner.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 120, userTask.CreditedMinutes())
+
+		summary, err := services.FetchHoursSummary(ctx, tx, volunteer.ID, time.UTC)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 120, summary.TotalMinutes)
+		require.Len(s.T(), summary.Categories, 1)
+		require.Equal(s.T(), category.ID, summary.Categories[0].CategoryID)
+		require.Equal(s.T(), "Test Category", summary.Categories[0].CategoryName)
+		require.Len(s.T(), summary.Months, 1)
+		require.Equal(s.T(), userTask.CheckedInAt.UTC().Format("2006-01"), summary.Months[0].Month)
+
+		// checking out days late credits no more than the task lasted, plus the grace period
+		ended := time.Now().Add(-48 * time.Hour)
+		checkedIn := ended.Add(-time.Hour)
+
+		_, err = tx.NewUpdate().
+			Model(task).
+			Set("ends_at = ?", ended).
+			WherePK().
+			Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewUpdate().
+			Model(userTask).
+			Set("checked_in_at = ?", checkedIn).
+			Set("checked_out_at = NULL").
+			WherePK().
+			Exec(ctx)
+		require.NoError(s.T(), err)
+
+		userTask, err = services.CheckOut(ctx, tx, task.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 60+constants.CheckOutGraceMinutes, *userTask.RecordedMinutes)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestQRCodeCheckIn() {
+	ctx := context.Background()
+	secret := "attendance-secret"
+
+	startsAt := time.Now().Add(-time.Hour)
+	endsAt := time.Now().Add(time.Hour)
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+	task := &models.Task{
+		Base:              models.Base{ID: uuid.New()},
+		Title:             "Food bank sorting",
+		Description:       "Scan the code at the door",
+		AutoAccept:        true,
+		Location:          models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+		StartsAt:          &startsAt,
+		EndsAt:            &endsAt,
+		QRCheckInRequired: true,
+		UserID:            owner.ID,
+		CategoryID:        category.ID,
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, volunteer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(task).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = services.ApplyToTask(ctx, tx, task.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+
+		_, err = services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, nil, "", secret)
+		require.ErrorIs(s.T(), err, services.ErrAttendanceTokenRequired)
+
+		// yesterday's code has expired
+		oldToken, _, err := services.IssueAttendanceToken(secret, task.ID, time.Now().Add(-24*time.Hour))
+		require.NoError(s.T(), err)
+
+		_, err = services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, nil, oldToken, secret)
+		require.ErrorIs(s.T(), err, services.ErrAttendanceTokenExpired)
+
+		// codes are bound to their task and secret
+		otherToken, _, err := services.IssueAttendanceToken(secret, uuid.New(), time.Now())
+		require.NoError(s.T(), err)
+
+		_, err = services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, nil, otherToken, secret)
+		require.ErrorIs(s.T(), err, services.ErrInvalidAttendanceToken)
+
+		token, expiresAt, err := services.IssueAttendanceToken(secret, task.ID, time.Now())
+		require.NoError(s.T(), err)
+		require.True(s.T(), expiresAt.After(time.Now()))
+
+		_, err = services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, nil, token, "another-secret")
+		require.ErrorIs(s.T(), err, services.ErrInvalidAttendanceToken)
+
+		userTask, err := services.CheckIn(ctx, tx, task.ID, volunteer.ID, nil, nil, token, secret)
+		require.NoError(s.T(), err)
+		require.NotNil(s.T(), userTask.CheckedInAt)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,152 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestAvailability() {
+	ctx := context.Background()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(s.T(), err)
+
+	// next Saturday in New York
+	saturday := time.Now().In(newYork).AddDate(0, 0, 1)
+	for saturday.Weekday() != time.Saturday {
+		saturday = saturday.AddDate(0, 0, 1)
+	}
+
+	at := func(hour int) *time.Time {
+		t := time.Date(saturday.Year(), saturday.Month(), saturday.Day(), hour, 0, 0, 0, newYork)
+		return &t
+	}
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err = models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = services.CreateOrUpdateUserLocation(ctx, tx, &models.UserLocation{Latitude: 40.7128, Longitude: -74.0060}, volunteer.ID)
+		require.NoError(s.T(), err)
+
+		tasks := map[string]*models.Task{
+			"morning":     {StartsAt: at(10), EndsAt: at(12)},
+			"afternoon":   {StartsAt: at(14), EndsAt: at(15)},
+			"unscheduled": {},
+		}
+
+		for title, task := range tasks {
+			task.Title = title
+			task.Description = title
+			task.CategoryID = category.ID
+			task.Latitude = 40.7130
+			task.Longitude = -74.0060
+
+			err = services.CreateTask(ctx, tx, task, organizer.ID, "", "")
+			require.NoError(s.T(), err)
+		}
+
+		availableTitles := func() []string {
+			fetched, _, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{
+				CreatedByUserID:    organizer.ID,
+				AvailableForUserID: volunteer.ID,
+			})
+			require.NoError(s.T(), err)
+
+			titles := []string{}
+			for _, task := range fetched {
+				titles = append(titles, task.Title)
+			}
+
+			return titles
+		}
+
+		notified := func(task *models.Task) bool {
+			userLocations, err := services.FetchNearbyUsersOfTask(ctx, tx, task.ID, 40.7130, -74.0060, 10)
+			require.NoError(s.T(), err)
+
+			for _, userLocation := range userLocations {
+				if userLocation.UserID == volunteer.ID {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		// volunteers who never said when they are free are always available
+		require.ElementsMatch(s.T(), []string{"morning", "afternoon", "unscheduled"}, availableTitles())
+		require.True(s.T(), notified(tasks["afternoon"]))
+
+		_, err = services.UpdateMe(ctx, tx, volunteer, models.User{
+			TimeZone: "America/New_York",
+			AvailabilityWindows: []*models.AvailabilityWindow{
+				{Weekday: int(time.Saturday), StartTime: "09:00", EndTime: "13:00"},
+			},
+		})
+		require.NoError(s.T(), err)
+
+		require.ElementsMatch(s.T(), []string{"morning", "unscheduled"}, availableTitles())
+		require.True(s.T(), notified(tasks["morning"]))
+		require.False(s.T(), notified(tasks["afternoon"]))
+
+		// an exception replaces the weekly windows on its date
+		_, err = services.UpdateMe(ctx, tx, volunteer, models.User{
+			AvailabilityExceptions: []*models.AvailabilityException{
+				{Date: saturday.Format("2006-01-02"), Available: true, StartTime: "13:30", EndTime: "24:00"},
+			},
+		})
+		require.NoError(s.T(), err)
+
+		require.ElementsMatch(s.T(), []string{"afternoon", "unscheduled"}, availableTitles())
+
+		_, err = services.UpdateMe(ctx, tx, volunteer, models.User{
+			AvailabilityExceptions: []*models.AvailabilityException{{Date: saturday.Format("2006-01-02")}},
+		})
+		require.NoError(s.T(), err)
+
+		require.ElementsMatch(s.T(), []string{"unscheduled"}, availableTitles())
+		require.False(s.T(), notified(tasks["morning"]))
+
+		for _, window := range []*models.AvailabilityWindow{
+			{Weekday: 7, StartTime: "09:00", EndTime: "10:00"},
+			{Weekday: 1, StartTime: "9:00", EndTime: "10:00"},
+			{Weekday: 1, StartTime: "11:00", EndTime: "10:00"},
+			{Weekday: 1, StartTime: "23:00", EndTime: "24:30"},
+		} {
+			err = services.SetAvailabilityWindows(ctx, tx, volunteer.ID, []*models.AvailabilityWindow{window})
+			require.ErrorIs(s.T(), err, services.ErrInvalidAvailability)
+		}
+
+		_, err = services.UpdateMe(ctx, tx, volunteer, models.User{TimeZone: "Mars/Olympus_Mons"})
+		require.ErrorIs(s.T(), err, services.ErrInvalidTimeZone)
+
+		// Postgres has no zone called Local
+		_, err = services.UpdateMe(ctx, tx, volunteer, models.User{TimeZone: "Local"})
+		require.ErrorIs(s.T(), err, services.ErrInvalidTimeZone)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,164 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestSendDueDigests() {
+	ctx := context.Background()
+	sink := newSMTPSink(s.T())
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}, FirstName: "Ada", Email: ptr("ada@example.org")}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	cooking := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Cooking"}
+	gardening := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Gardening"}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		for _, category := range []*models.Category{cooking, gardening} {
+			_, err := tx.NewInsert().Model(category).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := services.CreateOrUpdateUserLocation(ctx, tx, &models.UserLocation{Latitude: 40.7128, Longitude: -74.0060}, volunteer.ID)
+		require.NoError(s.T(), err)
+
+		err = services.SetNotificationPreferences(ctx, tx, volunteer.ID, &models.NotificationPreferences{
+			AlertRadiusKm:   2,
+			CategoryIDs:     []uuid.UUID{cooking.ID},
+			DigestFrequency: models.DigestFrequencyDaily,
+		})
+		require.NoError(s.T(), err)
+
+		newTask := func(title string, category *models.Category, latitude float64) *models.Task {
+			task := &models.Task{
+				Title:       title,
+				Description: title,
+				CategoryID:  category.ID,
+				Latitude:    latitude,
+				Longitude:   -74.0060,
+			}
+
+			err := services.CreateTask(ctx, tx, task, organizer.ID, "", "")
+			require.NoError(s.T(), err)
+
+			return task
+		}
+
+		bakeSale := newTask("Bake sale", cooking, 40.7130)
+		newTask("Soup kitchen", cooking, 40.7140)
+		newTask("Prune the roses", gardening, 40.7130)
+		newTask("Bake far away", cooking, 40.7580)
+
+		// users in digest mode are not alerted of each task
+		userLocations, err := services.FetchNearbyUsersOfTask(ctx, tx, bakeSale.ID, float32(bakeSale.Latitude), float32(bakeSale.Longitude), 10)
+		require.NoError(s.T(), err)
+
+		for _, userLocation := range userLocations {
+			require.NotEqual(s.T(), volunteer.ID, userLocation.UserID)
+		}
+
+		now := time.Now()
+
+		sent, failed, err := services.SendDueDigests(ctx, tx, sink.Config(), now)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, sent)
+		require.Zero(s.T(), failed)
+
+		messages := sink.Messages()
+		require.Len(s.T(), messages, 1)
+		require.Contains(s.T(), messages[0], "To: ada@example.org")
+		require.Contains(s.T(), messages[0], "multipart/alternative")
+		require.Contains(s.T(), messages[0], "<h3>Bake sale</h3>")
+		require.Contains(s.T(), messages[0], "Soup kitchen")
+		require.NotContains(s.T(), messages[0], "Prune the roses")
+		require.NotContains(s.T(), messages[0], "Bake far away")
+
+		// the next digest is not due until tomorrow
+		sent, _, err = services.SendDueDigests(ctx, tx, sink.Config(), now.Add(time.Minute))
+		require.NoError(s.T(), err)
+		require.Zero(s.T(), sent)
+
+		_, err = tx.NewUpdate().
+			Model((*models.NotificationPreferences)(nil)).
+			Set("last_digest_at = ?", now.AddDate(0, 0, -2)).
+			Where("user_id = ?", volunteer.ID).
+			Exec(ctx)
+		require.NoError(s.T(), err)
+
+		newTask("Pie contest", cooking, 40.7135)
+
+		// tasks are never sent twice
+		sent, _, err = services.SendDueDigests(ctx, tx, sink.Config(), now)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, sent)
+
+		messages = sink.Messages()
+		require.Len(s.T(), messages, 2)
+		require.Contains(s.T(), messages[1], "Pie contest")
+		require.NotContains(s.T(), messages[1], "Bake sale")
+
+		// a digest that cannot be emailed is not recorded, it goes out on the next run
+		_, err = tx.NewUpdate().
+			Model((*models.NotificationPreferences)(nil)).
+			Set("last_digest_at = ?", now.AddDate(0, 0, -2)).
+			Where("user_id = ?", volunteer.ID).
+			Exec(ctx)
+		require.NoError(s.T(), err)
+
+		newTask("Jam tasting", cooking, 40.7135)
+
+		unreachable := services.SMTPConfig{Host: "127.0.0.1", Port: "1", From: "noreply@example.com"}
+
+		sent, failed, err = services.SendDueDigests(ctx, tx, unreachable, now)
+		require.NoError(s.T(), err)
+		require.Zero(s.T(), sent)
+		require.Equal(s.T(), 1, failed)
+
+		preferences, err := services.FetchNotificationPreferences(ctx, tx, []uuid.UUID{volunteer.ID})
+		require.NoError(s.T(), err)
+		require.True(s.T(), services.DigestDue(
+			models.DigestFrequencyDaily, preferences[volunteer.ID].LastDigestAt, volunteer.TimeZone, now,
+		))
+
+		digestTasks, err := tx.NewSelect().
+			Model((*models.DigestTask)(nil)).
+			Where("user_id = ?", volunteer.ID).
+			Count(ctx)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 3, digestTasks)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestDigestDue() {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(s.T(), err)
+
+	// Monday, March 2 2026
+	sentAt := time.Date(2026, 3, 2, 8, 0, 0, 0, newYork)
+
+	require.True(s.T(), services.DigestDue(models.DigestFrequencyDaily, nil, "America/New_York", sentAt))
+	require.False(s.T(), services.DigestDue(models.DigestFrequencyDaily, &sentAt, "America/New_York", time.Date(2026, 3, 3, 7, 0, 0, 0, newYork)))
+	require.True(s.T(), services.DigestDue(models.DigestFrequencyDaily, &sentAt, "America/New_York", time.Date(2026, 3, 3, 8, 0, 0, 0, newYork)))
+
+	// weekly digests go out on Mondays
+	require.False(s.T(), services.DigestDue(models.DigestFrequencyWeekly, &sentAt, "America/New_York", time.Date(2026, 3, 8, 23, 0, 0, 0, newYork)))
+	require.True(s.T(), services.DigestDue(models.DigestFrequencyWeekly, &sentAt, "America/New_York", time.Date(2026, 3, 9, 8, 0, 0, 0, newYork)))
+
+	require.False(s.T(), services.DigestDue(models.DigestFrequencyOff, nil, "UTC", sentAt))
+}
@@ -0,0 +1,185 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"rashikzaman/api/utils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestNotificationInbox() {
+	ctx := context.Background()
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Test Category"}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		task := &models.Task{
+			Title:       "Clean the beach",
+			Description: "Bring gloves",
+			CategoryID:  category.ID,
+			Latitude:    40.7130,
+			Longitude:   -74.0060,
+		}
+		err = services.CreateTask(ctx, tx, task, organizer.ID, "", "")
+		require.NoError(s.T(), err)
+
+		inbox := services.NewNotificationService(services.InAppNotifier{}, services.SMSNotifier{})
+		smsOnly := services.NewNotificationService(services.SMSNotifier{})
+
+		for _, message := range []string{"First", "Second", "Third"} {
+			_, err = inbox.Notify(ctx, tx, services.NotificationEventTaskMessage, services.NotificationData{
+				Task:    task,
+				Message: message,
+			}, []*models.User{volunteer})
+			require.NoError(s.T(), err)
+		}
+
+		// notifications that never went to the app are not in the inbox
+		_, err = smsOnly.Notify(ctx, tx, services.NotificationEventTaskMessage, services.NotificationData{
+			Task:    task,
+			Message: "By text only",
+		}, []*models.User{volunteer})
+		require.NoError(s.T(), err)
+
+		pagination := utils.PaginationConfig{Limit: 2, UseCursor: true, CursorSecret: "secret"}
+
+		notifications, count, err := services.FetchInbox(ctx, tx, volunteer.ID, models.QueryParam{Pagination: pagination}, false)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 3, count)
+		require.Len(s.T(), notifications, 2)
+
+		cursor, err := services.NextInboxCursor(pagination, notifications)
+		require.NoError(s.T(), err)
+		require.NotEmpty(s.T(), cursor)
+
+		pagination.Cursor = cursor
+
+		rest, _, err := services.FetchInbox(ctx, tx, volunteer.ID, models.QueryParam{Pagination: pagination}, false)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), rest, 1)
+
+		unread, err := services.CountUnreadNotifications(ctx, tx, volunteer.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 3, unread)
+
+		notification, err := services.MarkNotificationRead(ctx, tx, volunteer.ID, rest[0].ID, true)
+		require.NoError(s.T(), err)
+		require.NotNil(s.T(), notification.ReadAt)
+
+		unread, err = services.CountUnreadNotifications(ctx, tx, volunteer.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 2, unread)
+
+		pagination = utils.PaginationConfig{Limit: 10, UseCursor: true, CursorSecret: "secret"}
+
+		notifications, _, err = services.FetchInbox(ctx, tx, volunteer.ID, models.QueryParam{Pagination: pagination}, true)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), notifications, 2)
+
+		notification, err = services.MarkNotificationRead(ctx, tx, volunteer.ID, rest[0].ID, false)
+		require.NoError(s.T(), err)
+		require.Nil(s.T(), notification.ReadAt)
+
+		// only the notification's recipient can mark it
+		_, err = services.MarkNotificationRead(ctx, tx, uuid.New(), rest[0].ID, true)
+		require.ErrorIs(s.T(), err, services.ErrNotificationNotFound)
+
+		marked, err := services.MarkAllNotificationsRead(ctx, tx, volunteer.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 3, marked)
+
+		unread, err = services.CountUnreadNotifications(ctx, tx, volunteer.ID)
+		require.NoError(s.T(), err)
+		require.Zero(s.T(), unread)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestNotifyApplication() {
+	ctx := context.Background()
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}, FirstName: "Ada", LastName: "Lovelace"}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Test Category"}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		inbox := services.NewNotificationService(services.InAppNotifier{})
+
+		inboxEvents := func(user *models.User) []string {
+			notifications, _, err := services.FetchInbox(ctx, tx, user.ID, models.QueryParam{}, false)
+			require.NoError(s.T(), err)
+
+			events := []string{}
+			for _, notification := range notifications {
+				events = append(events, notification.EventType)
+			}
+
+			return events
+		}
+
+		// applications waiting for review are the organizers' to hear about
+		screened := &models.Task{Title: "Sort donations", Description: "Sort the donated food", CategoryID: category.ID}
+		err = services.CreateTask(ctx, tx, screened, organizer.ID, "", "")
+		require.NoError(s.T(), err)
+
+		userTask, err := services.ApplyToTask(ctx, tx, screened.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.ApplicationStatusPending, userTask.Status)
+
+		err = inbox.NotifyApplication(ctx, tx, userTask)
+		require.NoError(s.T(), err)
+		require.Empty(s.T(), inboxEvents(volunteer))
+		require.Equal(s.T(), []string{services.NotificationEventApplicationReceived}, inboxEvents(organizer))
+
+		notifications, _, err := services.FetchInbox(ctx, tx, organizer.ID, models.QueryParam{}, false)
+		require.NoError(s.T(), err)
+		require.Contains(s.T(), notifications[0].Body, "Ada Lovelace applied to Sort donations")
+
+		// auto-accepted applicants get their confirmation
+		open := &models.Task{Title: "Clean the beach", Description: "Bring gloves", CategoryID: category.ID, AutoAccept: true}
+		err = services.CreateTask(ctx, tx, open, organizer.ID, "", "")
+		require.NoError(s.T(), err)
+
+		userTask, err = services.ApplyToTask(ctx, tx, open.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+
+		err = inbox.NotifyApplication(ctx, tx, userTask)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), []string{services.NotificationEventApplicationAccepted}, inboxEvents(volunteer))
+		require.Len(s.T(), inboxEvents(organizer), 1)
+
+		require.Equal(s.T(), services.NotificationEventTaskStarted, services.TaskStatusEvent(models.TaskStatusInProgress))
+		require.Equal(s.T(), services.NotificationEventTaskCompleted, services.TaskStatusEvent(models.TaskStatusCompleted))
+		require.Equal(s.T(), services.NotificationEventTaskCancelled, services.TaskStatusEvent(models.TaskStatusCancelled))
+		require.Empty(s.T(), services.TaskStatusEvent(models.TaskStatusFilled))
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,158 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestNotificationPreferences() {
+	ctx := context.Background()
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	cooking := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Cooking"}
+	gardening := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Gardening"}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		for _, category := range []*models.Category{cooking, gardening} {
+			_, err := tx.NewInsert().Model(category).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		for _, name := range []string{"Baking", "Pruning"} {
+			err := services.CreateSkill(ctx, tx, &models.Skill{Name: name})
+			require.NoError(s.T(), err)
+		}
+
+		_, err := services.CreateOrUpdateUserLocation(ctx, tx, &models.UserLocation{Latitude: 40.7128, Longitude: -74.0060}, volunteer.ID)
+		require.NoError(s.T(), err)
+
+		dailyCap := 1
+
+		_, err = services.UpdateMe(ctx, tx, volunteer, models.User{
+			TimeZone: "America/New_York",
+			NotificationPreferences: &models.NotificationPreferences{
+				AlertRadiusKm: 2,
+				CategoryIDs:   []uuid.UUID{cooking.ID},
+				Skills:        []string{"baking"},
+				EventChannels: map[string][]string{
+					services.NotificationEventTaskCreatedNearby: {models.NotificationChannelInApp},
+				},
+				DailyCap: &dailyCap,
+			},
+		})
+		require.NoError(s.T(), err)
+
+		newTask := func(title string, category *models.Category, skills []string, latitude float64) *models.Task {
+			task := &models.Task{
+				Title:          title,
+				Description:    title,
+				CategoryID:     category.ID,
+				RequiredSkills: skills,
+				Latitude:       latitude,
+				Longitude:      -74.0060,
+			}
+
+			err := services.CreateTask(ctx, tx, task, organizer.ID, "", "")
+			require.NoError(s.T(), err)
+
+			return task
+		}
+
+		alerted := func(task *models.Task) bool {
+			userLocations, err := services.FetchNearbyUsersOfTask(ctx, tx, task.ID, float32(task.Latitude), float32(task.Longitude), 10)
+			require.NoError(s.T(), err)
+
+			for _, userLocation := range userLocations {
+				if userLocation.UserID == volunteer.ID {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		bakeSale := newTask("Bake sale", cooking, []string{"Baking"}, 40.7130)
+
+		require.True(s.T(), alerted(bakeSale))
+		// outside the categories, skills or radius the volunteer chose
+		require.False(s.T(), alerted(newTask("Prune the roses", gardening, []string{"Pruning"}, 40.7130)))
+		require.False(s.T(), alerted(newTask("Bake with pruners", cooking, []string{"Pruning"}, 40.7130)))
+		require.False(s.T(), alerted(newTask("Bake far away", cooking, []string{"Baking"}, 40.7580)))
+
+		notificationService := services.NewNotificationService(services.InAppNotifier{}, services.SMSNotifier{})
+
+		err = notificationService.NotifyNearbyUsers(ctx, tx, bakeSale)
+		require.NoError(s.T(), err)
+
+		notification := &models.Notification{}
+		err = tx.NewSelect().
+			Model(notification).
+			Relation("Deliveries").
+			Where("user_id = ?", volunteer.ID).
+			Scan(ctx)
+		require.NoError(s.T(), err)
+
+		// the volunteer only wants alerts of new tasks in the app
+		require.Len(s.T(), notification.Deliveries, 1)
+		require.Equal(s.T(), models.NotificationChannelInApp, notification.Deliveries[0].Channel)
+
+		// one alert a day
+		require.False(s.T(), alerted(newTask("Bake again", cooking, []string{"Baking"}, 40.7130)))
+
+		for _, preferences := range []*models.NotificationPreferences{
+			{AlertRadiusKm: 500},
+			{CategoryIDs: []uuid.UUID{uuid.New()}},
+			{EventChannels: map[string][]string{"unknown_event": {models.NotificationChannelSMS}}},
+			{EventChannels: map[string][]string{services.NotificationEventTaskCancelled: {"pigeon"}}},
+			{QuietHoursStart: "22:00"},
+		} {
+			err = services.SetNotificationPreferences(ctx, tx, volunteer.ID, preferences)
+			require.ErrorIs(s.T(), err, services.ErrInvalidNotificationPreferences)
+		}
+
+		err = services.SetNotificationPreferences(ctx, tx, volunteer.ID, &models.NotificationPreferences{Skills: []string{"juggling"}})
+		require.ErrorIs(s.T(), err, services.ErrUnknownSkill)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestQuietUntil() {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(s.T(), err)
+
+	preferences := &models.NotificationPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}
+
+	// quiet hours run over midnight
+	until, quiet := services.QuietUntil(preferences, "America/New_York", time.Date(2026, 3, 2, 23, 30, 0, 0, newYork))
+	require.True(s.T(), quiet)
+	require.Equal(s.T(), time.Date(2026, 3, 3, 7, 0, 0, 0, newYork), until)
+
+	until, quiet = services.QuietUntil(preferences, "America/New_York", time.Date(2026, 3, 3, 6, 0, 0, 0, newYork))
+	require.True(s.T(), quiet)
+	require.Equal(s.T(), time.Date(2026, 3, 3, 7, 0, 0, 0, newYork), until)
+
+	_, quiet = services.QuietUntil(preferences, "America/New_York", time.Date(2026, 3, 3, 12, 0, 0, 0, newYork))
+	require.False(s.T(), quiet)
+
+	// in the user's time zone, not the server's
+	_, quiet = services.QuietUntil(preferences, "America/New_York", time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC))
+	require.True(s.T(), quiet)
+
+	_, quiet = services.QuietUntil(nil, "UTC", time.Now())
+	require.False(s.T(), quiet)
+}
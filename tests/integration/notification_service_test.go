@@ -0,0 +1,196 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"rashikzaman/api/constants"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+// flakyNotifier fails the first failures sends on its channel, like a provider that is
+// down for a while, and fails for good when permanent is set.
+type flakyNotifier struct {
+	channel   string
+	failures  int
+	permanent bool
+	sent      int
+}
+
+func (n *flakyNotifier) Channel() string {
+	return n.channel
+}
+
+func (n *flakyNotifier) Send(context.Context, bun.IDB, *models.User, *models.Notification) error {
+	if n.permanent {
+		return fmt.Errorf("%w: mailbox does not exist", services.ErrPermanentDeliveryFailure)
+	}
+
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("provider unavailable")
+	}
+
+	n.sent++
+
+	return nil
+}
+
+func (s *TestSuite) TestNotify() {
+	ctx := context.Background()
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	neighbour := &models.User{Base: models.Base{ID: uuid.New()}}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, neighbour, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		for _, user := range []*models.User{volunteer, neighbour} {
+			_, err = services.CreateOrUpdateUserLocation(ctx, tx, &models.UserLocation{Latitude: 40.7128, Longitude: -74.0060}, user.ID)
+			require.NoError(s.T(), err)
+		}
+
+		// alerts of new tasks are only emailed and pushed to users who ask for them
+		err = services.SetNotificationPreferences(ctx, tx, volunteer.ID, &models.NotificationPreferences{
+			EventChannels: map[string][]string{
+				services.NotificationEventTaskCreatedNearby: {
+					models.NotificationChannelInApp, models.NotificationChannelSMS,
+					models.NotificationChannelPush, models.NotificationChannelEmail,
+				},
+			},
+		})
+		require.NoError(s.T(), err)
+
+		task := &models.Task{
+			Title:       "Clean the beach",
+			Description: "Bring gloves",
+			CategoryID:  category.ID,
+			Latitude:    40.7130,
+			Longitude:   -74.0060,
+		}
+		err = services.CreateTask(ctx, tx, task, organizer.ID, "", "")
+		require.NoError(s.T(), err)
+
+		push := &flakyNotifier{channel: models.NotificationChannelPush, failures: 1}
+		email := &flakyNotifier{channel: models.NotificationChannelEmail, permanent: true}
+
+		notificationService := services.NewNotificationService(
+			services.InAppNotifier{}, services.SMSNotifier{}, push, email,
+		)
+
+		err = notificationService.NotifyNearbyUsers(ctx, tx, task)
+		require.NoError(s.T(), err)
+
+		neighbourDeliveries := []models.NotificationDelivery{}
+		err = tx.NewSelect().
+			Model(&neighbourDeliveries).
+			Where("notification_id IN (SELECT id FROM notifications WHERE user_id = ?)", neighbour.ID).
+			Order("channel ASC").
+			Scan(ctx)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), neighbourDeliveries, 2)
+		require.Equal(s.T(), models.NotificationChannelInApp, neighbourDeliveries[0].Channel)
+		require.Equal(s.T(), models.NotificationChannelSMS, neighbourDeliveries[1].Channel)
+
+		deliveries := func() map[string]models.NotificationDelivery {
+			notifications := []models.Notification{}
+			err := tx.NewSelect().
+				Model(&notifications).
+				Relation("Deliveries").
+				Where("user_id = ?", volunteer.ID).
+				Scan(ctx)
+			require.NoError(s.T(), err)
+			require.Len(s.T(), notifications, 1)
+			require.Equal(s.T(), services.NotificationEventTaskCreatedNearby, notifications[0].EventType)
+			require.Equal(s.T(), "New task near you: Clean the beach", notifications[0].Subject)
+			require.Equal(s.T(), task.ID, *notifications[0].TaskID)
+
+			byChannel := map[string]models.NotificationDelivery{}
+			for _, delivery := range notifications[0].Deliveries {
+				byChannel[delivery.Channel] = *delivery
+			}
+
+			return byChannel
+		}
+
+		// nothing is sent until the worker runs
+		for _, delivery := range deliveries() {
+			require.Equal(s.T(), models.NotificationDeliveryStatusPending, delivery.Status)
+		}
+
+		_, err = notificationService.DeliverPending(ctx, tx, constants.NotificationWorkerBatchSize)
+		require.NoError(s.T(), err)
+
+		byChannel := deliveries()
+		require.Equal(s.T(), models.NotificationDeliveryStatusSent, byChannel[models.NotificationChannelInApp].Status)
+		// the volunteer has no phone number
+		require.Equal(s.T(), models.NotificationDeliveryStatusSkipped, byChannel[models.NotificationChannelSMS].Status)
+		require.Equal(s.T(), models.NotificationDeliveryStatusPending, byChannel[models.NotificationChannelPush].Status)
+		require.Equal(s.T(), models.NotificationDeliveryStatusFailed, byChannel[models.NotificationChannelEmail].Status)
+
+		jobs := []models.NotificationOutboxJob{}
+		err = tx.NewSelect().
+			Model(&jobs).
+			Relation("Delivery").
+			Where("delivery.notification_id IN (SELECT id FROM notifications WHERE user_id = ?)", volunteer.ID).
+			Order("notification_outbox_job.status ASC").
+			Scan(ctx)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), jobs, 2)
+
+		// permanent failures are dead-lettered right away, others are retried later
+		require.Equal(s.T(), models.NotificationOutboxJobStatusDead, jobs[0].Status)
+		require.Equal(s.T(), models.NotificationChannelEmail, jobs[0].Delivery.Channel)
+		require.Equal(s.T(), models.NotificationOutboxJobStatusPending, jobs[1].Status)
+		require.Equal(s.T(), 1, jobs[1].Attempts)
+		require.True(s.T(), jobs[1].RunAt.After(time.Now()))
+
+		// the retry is not due yet
+		claimed, err := notificationService.DeliverPending(ctx, tx, constants.NotificationWorkerBatchSize)
+		require.NoError(s.T(), err)
+		require.Zero(s.T(), claimed)
+
+		_, err = tx.NewUpdate().
+			Model(&jobs[1]).
+			Set("run_at = NOW() - INTERVAL '1 second'").
+			WherePK().
+			Exec(ctx)
+		require.NoError(s.T(), err)
+
+		claimed, err = notificationService.DeliverPending(ctx, tx, constants.NotificationWorkerBatchSize)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, claimed)
+		require.Equal(s.T(), 1, push.sent)
+		require.Equal(s.T(), models.NotificationDeliveryStatusSent, deliveries()[models.NotificationChannelPush].Status)
+
+		require.Equal(s.T(), 30*time.Second, services.NotificationRetryDelay(1))
+		require.Equal(s.T(), 2*time.Minute, services.NotificationRetryDelay(3))
+		require.Equal(s.T(), time.Hour, services.NotificationRetryDelay(20))
+
+		_, err = notificationService.Notify(ctx, tx, "unknown_event", services.NotificationData{}, []*models.User{volunteer})
+		require.ErrorIs(s.T(), err, services.ErrUnknownNotificationEvent)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,138 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestOrganizations() {
+	ctx := context.Background()
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}, Email: ptr("owner@example.org")}
+	manager := &models.User{Base: models.Base{ID: uuid.New()}, Email: ptr("manager@example.org")}
+	member := &models.User{Base: models.Base{ID: uuid.New()}, Email: ptr("member@example.org")}
+	outsider := &models.User{Base: models.Base{ID: uuid.New()}, Email: ptr("outsider@example.org")}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, manager, member, outsider} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		organization := &models.Organization{Name: "  Food Bank  "}
+		err = services.CreateOrganization(ctx, tx, organization, owner.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), "Food Bank", organization.Name)
+
+		_, err = services.AddOrganizationMember(ctx, tx, organization.ID, "MANAGER@example.org", models.OrganizationRoleManager)
+		require.NoError(s.T(), err)
+
+		_, err = services.AddOrganizationMember(ctx, tx, organization.ID, "member@example.org", models.OrganizationRoleMember)
+		require.NoError(s.T(), err)
+
+		_, err = services.AddOrganizationMember(ctx, tx, organization.ID, "member@example.org", models.OrganizationRoleManager)
+		require.ErrorIs(s.T(), err, services.ErrAlreadyOrganizationMember)
+
+		_, err = services.AddOrganizationMember(ctx, tx, organization.ID, "nobody@example.org", models.OrganizationRoleMember)
+		require.ErrorIs(s.T(), err, services.ErrUserNotFound)
+
+		// plain members cannot post as the organization
+		task := &models.Task{
+			Title:          "Sort donations",
+			Description:    "Sort the donated food",
+			CategoryID:     category.ID,
+			OrganizationID: &organization.ID,
+		}
+		err = services.CreateTask(ctx, tx, task, member.ID, "", "")
+		require.ErrorIs(s.T(), err, services.ErrNotOrganizationManager)
+
+		err = services.CreateTask(ctx, tx, task, manager.ID, "", "")
+		require.NoError(s.T(), err)
+
+		// any owner or manager of the organization manages its tasks
+		for _, tt := range []struct {
+			user    *models.User
+			allowed bool
+		}{
+			{owner, true},
+			{manager, true},
+			{member, false},
+			{outsider, false},
+		} {
+			allowed, err := services.IsTaskOwner(ctx, tx, *task, tt.user.ID)
+			require.NoError(s.T(), err)
+			require.Equal(s.T(), tt.allowed, allowed)
+		}
+
+		// staff no longer manage the tasks they posted once they stop being managers
+		_, err = services.ChangeOrganizationMemberRole(ctx, tx, organization.ID, member.ID, models.OrganizationRoleManager)
+		require.NoError(s.T(), err)
+
+		postedByMember := &models.Task{
+			Title:          "Pack boxes",
+			Description:    "Pack the food boxes",
+			CategoryID:     category.ID,
+			OrganizationID: &organization.ID,
+		}
+		err = services.CreateTask(ctx, tx, postedByMember, member.ID, "", "")
+		require.NoError(s.T(), err)
+
+		_, err = services.ChangeOrganizationMemberRole(ctx, tx, organization.ID, member.ID, models.OrganizationRoleMember)
+		require.NoError(s.T(), err)
+
+		allowed, err := services.IsTaskOwner(ctx, tx, *postedByMember, member.ID)
+		require.NoError(s.T(), err)
+		require.False(s.T(), allowed)
+
+		err = services.DeleteTask(ctx, tx, postedByMember.ID)
+		require.NoError(s.T(), err)
+
+		tasks, count, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{OrganizationID: organization.ID})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, count)
+		require.Equal(s.T(), task.ID, tasks[0].ID)
+
+		// the organization always keeps an owner
+		_, err = services.ChangeOrganizationMemberRole(ctx, tx, organization.ID, owner.ID, models.OrganizationRoleMember)
+		require.ErrorIs(s.T(), err, services.ErrLastOrganizationOwner)
+
+		err = services.RemoveOrganizationMember(ctx, tx, organization.ID, owner.ID)
+		require.ErrorIs(s.T(), err, services.ErrLastOrganizationOwner)
+
+		_, err = services.ChangeOrganizationMemberRole(ctx, tx, organization.ID, manager.ID, models.OrganizationRoleOwner)
+		require.NoError(s.T(), err)
+
+		err = services.RemoveOrganizationMember(ctx, tx, organization.ID, owner.ID)
+		require.NoError(s.T(), err)
+
+		allowed, err = services.IsTaskOwner(ctx, tx, *task, manager.ID)
+		require.NoError(s.T(), err)
+		require.True(s.T(), allowed)
+
+		memberships, err := services.FetchOrganizationsOfUser(ctx, tx, owner.ID)
+		require.NoError(s.T(), err)
+		require.Empty(s.T(), memberships)
+
+		fetched, err := services.FetchOrganizationByID(ctx, tx, organization.ID)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), fetched.Members, 2)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
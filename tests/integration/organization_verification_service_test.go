@@ -0,0 +1,130 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestOrganizationVerification() {
+	ctx := context.Background()
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	admin := &models.User{Base: models.Base{ID: uuid.New()}, Role: "admin"}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, admin} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		organization := &models.Organization{Name: "Shelter"}
+		err = services.CreateOrganization(ctx, tx, organization, owner.ID)
+		require.NoError(s.T(), err)
+
+		task := &models.Task{Title: "Walk dogs", Description: "Walk the dogs", CategoryID: category.ID, OrganizationID: &organization.ID}
+		err = services.CreateTask(ctx, tx, task, owner.ID, "", "")
+		require.NoError(s.T(), err)
+
+		filter := services.Filter{OrganizationID: organization.ID}
+
+		_, err = services.ReviewOrganizationVerification(ctx, tx, organization.ID, admin.ID, "approve", "")
+		require.ErrorIs(s.T(), err, services.ErrNoPendingVerification)
+
+		err = services.RequestOrganizationVerification(
+			ctx, tx, organization.ID, owner.ID, &models.OrganizationVerificationRequest{}, "", "",
+		)
+		require.ErrorIs(s.T(), err, services.ErrVerificationDocumentsRequired)
+
+		request := &models.OrganizationVerificationRequest{
+			Note:      "Registered charity",
+			Documents: []*models.OrganizationVerificationDocument{{Base64: "data:application/pdf;base64,JVBERi0="}},
+		}
+
+		// documents are never dropped for want of somewhere to keep them
+		err = services.RequestOrganizationVerification(ctx, tx, organization.ID, owner.ID, request, "", "")
+		require.ErrorIs(s.T(), err, services.ErrMediaStorageUnavailable)
+
+		// stands in for a request whose documents were uploaded
+		request.OrganizationID = organization.ID
+		request.RequestedByUserID = &owner.ID
+		request.Documents = nil
+		err = models.Create(ctx, tx, request)
+		require.NoError(s.T(), err)
+
+		err = models.Create(ctx, tx, &models.OrganizationVerificationDocument{
+			RequestID: request.ID,
+			MimeType:  "application/pdf",
+			ObjectKey: "uploads/documents/registration.pdf",
+		})
+		require.NoError(s.T(), err)
+
+		err = services.RequestOrganizationVerification(ctx, tx, organization.ID, owner.ID, &models.OrganizationVerificationRequest{
+			Documents: []*models.OrganizationVerificationDocument{{Base64: "data:application/pdf;base64,JVBERi0="}},
+		}, "key", "secret")
+		require.ErrorIs(s.T(), err, services.ErrVerificationPending)
+
+		queue, count, err := services.FetchVerificationRequests(ctx, tx, models.QueryParam{}, "key", "secret")
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, count)
+		require.Equal(s.T(), organization.ID, queue[0].OrganizationID)
+		require.Equal(s.T(), "Shelter", queue[0].Organization.Name)
+
+		// admins reach the documents through short-lived links to the private bucket
+		require.Len(s.T(), queue[0].Documents, 1)
+		require.Contains(s.T(), queue[0].Documents[0].URL, services.S3PrivateBucketName)
+		require.Contains(s.T(), queue[0].Documents[0].URL, "X-Amz-Signature")
+
+		// not verified yet, so left out of verified only listings
+		tasks, _, err := services.FetchTasks(ctx, tx, models.QueryParam{}, filter)
+		require.NoError(s.T(), err)
+		require.False(s.T(), tasks[0].VerifiedOrganizer)
+
+		filter.VerifiedOnly = true
+
+		_, count, err = services.FetchTasks(ctx, tx, models.QueryParam{}, filter)
+		require.NoError(s.T(), err)
+		require.Zero(s.T(), count)
+
+		verified, err := services.ReviewOrganizationVerification(ctx, tx, organization.ID, admin.ID, "approve", "Looks good")
+		require.NoError(s.T(), err)
+		require.NotNil(s.T(), verified.VerifiedAt)
+
+		tasks, count, err = services.FetchTasks(ctx, tx, models.QueryParam{}, filter)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, count)
+		require.True(s.T(), tasks[0].VerifiedOrganizer)
+
+		latest, err := services.FetchLatestVerificationRequest(ctx, tx, organization.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.VerificationStatusApproved, latest.Status)
+		require.Equal(s.T(), admin.ID, *latest.ReviewedByUserID)
+
+		err = services.RequestOrganizationVerification(ctx, tx, organization.ID, owner.ID, request, "key", "secret")
+		require.ErrorIs(s.T(), err, services.ErrOrganizationAlreadyVerified)
+
+		revoked, err := services.ReviewOrganizationVerification(ctx, tx, organization.ID, admin.ID, "revoke", "")
+		require.NoError(s.T(), err)
+		require.Nil(s.T(), revoked.VerifiedAt)
+
+		_, err = services.ReviewOrganizationVerification(ctx, tx, organization.ID, admin.ID, "bless", "")
+		require.ErrorIs(s.T(), err, services.ErrInvalidVerificationAction)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
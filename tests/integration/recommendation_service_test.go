@@ -0,0 +1,104 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestFetchRecommendedTasks() {
+	ctx := context.Background()
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	gardening := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Gardening"}
+	cooking := &models.Category{Base: models.Base{ID: uuid.New()}, Name: "Cooking"}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		for _, category := range []*models.Category{gardening, cooking} {
+			_, err := tx.NewInsert().Model(category).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		err := services.CreateSkill(ctx, tx, &models.Skill{Name: "First Aid"})
+		require.NoError(s.T(), err)
+
+		_, err = services.SetUserSkills(ctx, tx, volunteer.ID, []services.UserSkillInput{{Name: "first aid"}})
+		require.NoError(s.T(), err)
+
+		_, err = services.CreateOrUpdateUserLocation(ctx, tx, &models.UserLocation{Latitude: 40.7128, Longitude: -74.0060}, volunteer.ID)
+		require.NoError(s.T(), err)
+
+		startsAt := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+		endsAt := startsAt.Add(2 * time.Hour)
+		clashStartsAt := startsAt.Add(time.Hour)
+
+		newTask := func(title string, category *models.Category, skills []string, startsAt *time.Time) *models.Task {
+			task := &models.Task{
+				Title:          title,
+				Description:    title,
+				CategoryID:     category.ID,
+				RequiredSkills: skills,
+				Latitude:       40.7130,
+				Longitude:      -74.0060,
+				StartsAt:       startsAt,
+			}
+
+			err := services.CreateTask(ctx, tx, task, organizer.ID, "", "")
+			require.NoError(s.T(), err)
+
+			return task
+		}
+
+		// the volunteer already joined a gardening task
+		joined := newTask("Weed the park", gardening, nil, &startsAt)
+		joined.EndsAt = &endsAt
+		_, err = tx.NewUpdate().Model(joined).Column("ends_at").WherePK().Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(&models.UserTask{
+			UserID: volunteer.ID,
+			TaskID: joined.ID,
+			Status: models.ApplicationStatusAccepted,
+		}).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		newTask("Plant trees", gardening, []string{"First Aid"}, nil)
+		newTask("Water the garden", gardening, []string{"First Aid"}, nil)
+		newTask("Cook for the shelter", cooking, []string{"First Aid"}, nil)
+		clash := newTask("Bake at the same time", cooking, []string{"First Aid"}, &clashStartsAt)
+
+		tasks, err := services.FetchRecommendedTasks(ctx, tx, volunteer.ID, 3)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), tasks, 3)
+
+		// a second gardening task would score higher but the feed mixes categories
+		require.Equal(s.T(), gardening.ID, tasks[0].CategoryID)
+		require.Equal(s.T(), "Cook for the shelter", tasks[1].Title)
+		require.Equal(s.T(), gardening.ID, tasks[2].CategoryID)
+
+		// tasks already joined, or clashing with them, are left out
+		for _, task := range tasks {
+			require.NotEqual(s.T(), joined.ID, task.ID)
+			require.NotEqual(s.T(), clash.ID, task.ID)
+		}
+
+		require.Equal(s.T(), []string{
+			"matches your First Aid skill", "near you", "like the Gardening tasks you joined before",
+		}, tasks[0].Explanation)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,156 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestReliabilityTracking() {
+	ctx := context.Background()
+
+	now := time.Now()
+	pastStart, pastEnd := now.Add(-3*time.Hour), now.Add(-time.Hour)
+	soonStart, soonEnd := now.Add(2*time.Hour), now.Add(4*time.Hour)
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	reliable := &models.User{Base: models.Base{ID: uuid.New()}}
+	flaky := &models.User{Base: models.Base{ID: uuid.New()}}
+	newcomer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	newTask := func(title string, startsAt, endsAt *time.Time, minReliability int) *models.Task {
+		return &models.Task{
+			Base:                models.Base{ID: uuid.New()},
+			Title:               title,
+			Description:         title,
+			AutoAccept:          true,
+			StartsAt:            startsAt,
+			EndsAt:              endsAt,
+			MinReliabilityScore: minReliability,
+			UserID:              owner.ID,
+			CategoryID:          category.ID,
+		}
+	}
+
+	completedTask := newTask("Completed task", &pastStart, &pastEnd, 0)
+	endedTask := newTask("Ended task", &pastStart, &pastEnd, 0)
+	upcomingTask := newTask("Upcoming task", &soonStart, &soonEnd, 0)
+	selectiveTask := newTask("Selective task", &soonStart, &soonEnd, 80)
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, reliable, flaky, newcomer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		for _, task := range []*models.Task{completedTask, endedTask, upcomingTask, selectiveTask} {
+			_, err = tx.NewInsert().Model(task).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		// completing a task settles who showed up
+		for _, user := range []*models.User{reliable, flaky} {
+			_, err = services.ApplyToTask(ctx, tx, completedTask.ID, user.ID, nil)
+			require.NoError(s.T(), err)
+		}
+
+		_, err = services.CheckInOnBehalf(ctx, tx, completedTask.ID, reliable.ID, nil)
+		require.NoError(s.T(), err)
+
+		_, err = services.ChangeTaskStatus(ctx, tx, completedTask.ID, models.TaskStatusInProgress)
+		require.NoError(s.T(), err)
+
+		_, err = services.CompleteTask(ctx, tx, completedTask.ID)
+		require.NoError(s.T(), err)
+
+		user, err := services.GetUserByID(ctx, tx, reliable.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, user.CompletedTasksCount)
+		require.Equal(s.T(), 100.0, *user.ReliabilityScore())
+
+		user, err = services.GetUserByID(ctx, tx, flaky.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, user.NoShowCount)
+
+		// withdrawing shortly before the start counts against the volunteer
+		_, err = services.ApplyToTask(ctx, tx, upcomingTask.ID, flaky.ID, nil)
+		require.NoError(s.T(), err)
+
+		_, err = services.WithdrawFromTask(ctx, tx, upcomingTask.ID, flaky.ID, nil)
+		require.NoError(s.T(), err)
+
+		user, err = services.GetUserByID(ctx, tx, flaky.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, user.LateWithdrawalCount)
+		require.Equal(s.T(), 25.0, *user.ReliabilityScore())
+
+		// organizers can require a minimum score, newcomers get the benefit of the doubt
+		_, err = services.ApplyToTask(ctx, tx, selectiveTask.ID, flaky.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrReliabilityTooLow)
+
+		_, err = services.ApplyToTask(ctx, tx, selectiveTask.ID, newcomer.ID, nil)
+		require.NoError(s.T(), err)
+
+		// the sweep settles tasks that ended without being completed
+		_, err = services.ApplyToTask(ctx, tx, endedTask.ID, newcomer.ID, nil)
+		require.NoError(s.T(), err)
+
+		_, err = services.RecordEndedTaskOutcomes(ctx, tx, now)
+		require.NoError(s.T(), err)
+
+		user, err = services.GetUserByID(ctx, tx, newcomer.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, user.NoShowCount)
+
+		// settling again does not count twice, and a late check-in by the organizer
+		// turns the no-show into a completion
+		_, err = services.RecordEndedTaskOutcomes(ctx, tx, now)
+		require.NoError(s.T(), err)
+
+		_, err = services.CheckInOnBehalf(ctx, tx, endedTask.ID, newcomer.ID, nil)
+		require.NoError(s.T(), err)
+
+		user, err = services.GetUserByID(ctx, tx, newcomer.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 0, user.NoShowCount)
+		require.Equal(s.T(), 1, user.CompletedTasksCount)
+
+		// an attendance that was settled cannot be withdrawn from
+		_, err = services.WithdrawFromTask(ctx, tx, endedTask.ID, newcomer.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrWithdrawalClosed)
+
+		_, err = services.WithdrawFromTask(ctx, tx, completedTask.ID, reliable.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrWithdrawalClosed)
+
+		// editing the profile, even from a stale copy of it, keeps the counters
+		_, err = services.UpdateMe(ctx, tx, newcomer, models.User{FirstName: "New"})
+		require.NoError(s.T(), err)
+
+		_, err = services.ApplyActionToUser(ctx, tx, newcomer.ID, "block")
+		require.NoError(s.T(), err)
+
+		user, err = services.GetUserByID(ctx, tx, newcomer.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), "New", user.FirstName)
+		require.True(s.T(), user.Blocked)
+		require.Equal(s.T(), 1, user.CompletedTasksCount)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
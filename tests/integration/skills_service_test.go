@@ -0,0 +1,173 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestSkills() {
+	ctx := context.Background()
+
+	// Create test data
+	user := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(user).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		firstAid := &models.Skill{Name: " First aid ", Synonyms: []*models.SkillSynonym{{Name: "CPR"}}}
+		err = services.CreateSkill(ctx, tx, firstAid)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), "First aid", firstAid.Name)
+
+		err = services.CreateSkill(ctx, tx, &models.Skill{Name: "cpr"})
+		require.ErrorIs(s.T(), err, services.ErrSkillExists)
+
+		driving := &models.Skill{Name: "Driving"}
+		err = services.CreateSkill(ctx, tx, driving)
+		require.NoError(s.T(), err)
+
+		// synonyms and any case resolve to the skill's name
+		task := &models.Task{
+			Title:          "Run the first aid tent",
+			Description:    "Look after the runners",
+			CategoryID:     category.ID,
+			RequiredSkills: []string{"cpr", "FIRST AID", "driving"},
+		}
+		err = services.CreateTask(ctx, tx, task, user.ID, "", "")
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), []string{"First aid", "Driving"}, task.RequiredSkills)
+
+		err = services.CreateTask(ctx, tx, &models.Task{
+			Title:          "Juggle",
+			CategoryID:     category.ID,
+			RequiredSkills: []string{"juggling"},
+		}, user.ID, "", "")
+		require.ErrorIs(s.T(), err, services.ErrUnknownSkill)
+
+		// renaming a skill renames it on the tasks requiring it
+		_, err = services.RenameSkill(ctx, tx, driving.ID, "Driving licence")
+		require.NoError(s.T(), err)
+
+		fetched, err := services.FetchTaskByID(ctx, tx, task.ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), []string{"First aid", "Driving licence"}, fetched.RequiredSkills)
+
+		// task listings filtered by a synonym find the tasks requiring the skill
+		filterSkills, err := services.ResolveSkillNames(ctx, tx, []string{"cpr"})
+		require.NoError(s.T(), err)
+
+		tasks, count, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{Skills: filterSkills})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, count)
+		require.Equal(s.T(), task.ID, tasks[0].ID)
+
+		_, err = services.ResolveSkillNames(ctx, tx, []string{"juggling"})
+		require.ErrorIs(s.T(), err, services.ErrUnknownSkill)
+
+		skills, err := services.FetchSkills(ctx, tx, "cpr")
+		require.NoError(s.T(), err)
+		require.Len(s.T(), skills, 1)
+		require.Equal(s.T(), firstAid.ID, skills[0].ID)
+
+		_, err = services.SetUserSkills(ctx, tx, user.ID, []services.UserSkillInput{{Name: "CPR", Proficiency: "wizard"}})
+		require.ErrorIs(s.T(), err, services.ErrInvalidSkillProficiency)
+
+		userSkills, err := services.SetUserSkills(ctx, tx, user.ID, []services.UserSkillInput{
+			{Name: "CPR", Proficiency: models.SkillProficiencyBeginner},
+			{Name: "first aid", Proficiency: models.SkillProficiencyExpert},
+		})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), userSkills, 1)
+		require.Equal(s.T(), models.SkillProficiencyExpert, userSkills[0].Proficiency)
+
+		err = services.DeleteSkill(ctx, tx, driving.ID)
+		require.NoError(s.T(), err)
+
+		fetched, err = services.FetchTaskByID(ctx, tx, task.ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), []string{"First aid"}, fetched.RequiredSkills)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestFetchTasksMatching() {
+	ctx := context.Background()
+
+	// Create test data
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	organizer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{volunteer, organizer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		for _, name := range []string{"Cooking", "Carpentry"} {
+			err = services.CreateSkill(ctx, tx, &models.Skill{Name: name})
+			require.NoError(s.T(), err)
+		}
+
+		_, err = services.SetUserSkills(ctx, tx, volunteer.ID, []services.UserSkillInput{{Name: "Cooking"}})
+		require.NoError(s.T(), err)
+
+		_, err = services.CreateOrUpdateUserLocation(ctx, tx, &models.UserLocation{Latitude: 40.7128, Longitude: -74.0060}, volunteer.ID)
+		require.NoError(s.T(), err)
+
+		// the same skills nearby beat the same skills far away, which beat other skills nearby
+		tasks := []*models.Task{
+			{Title: "Cook far away", RequiredSkills: []string{"Cooking"}, Latitude: 41.7128, Longitude: -74.0060},
+			{Title: "Build nearby", RequiredSkills: []string{"Carpentry"}, Latitude: 40.7128, Longitude: -74.0060},
+			{Title: "Cook nearby", RequiredSkills: []string{"Cooking"}, Latitude: 40.7130, Longitude: -74.0060},
+		}
+
+		for _, task := range tasks {
+			task.Description = task.Title
+			task.CategoryID = category.ID
+
+			err = services.CreateTask(ctx, tx, task, organizer.ID, "", "")
+			require.NoError(s.T(), err)
+		}
+
+		fetched, _, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{
+			CreatedByUserID: organizer.ID,
+			MatchUserID:     volunteer.ID,
+		})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), fetched, 3)
+		require.Equal(s.T(), "Cook nearby", fetched[0].Title)
+		require.Equal(s.T(), "Cook far away", fetched[1].Title)
+		require.Equal(s.T(), "Build nearby", fetched[2].Title)
+		require.NotNil(s.T(), fetched[0].MatchScore)
+		require.Greater(s.T(), *fetched[0].MatchScore, *fetched[1].MatchScore)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,104 @@
+package integration_test
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"rashikzaman/api/services"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSink is a local SMTP server keeping the emails sent to it, for tests of what the API
+// emails.
+type smtpSink struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sink := &smtpSink{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go sink.serve(conn)
+		}
+	}()
+
+	return sink
+}
+
+// Config returns the settings to send emails to the sink with.
+func (s *smtpSink) Config() services.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+
+	return services.SMTPConfig{Host: host, Port: port, From: "noreply@example.com"}
+}
+
+// Messages returns the emails received so far, headers and body.
+func (s *smtpSink) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.messages...)
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	writer := textproto.NewWriter(bufio.NewWriter(conn))
+
+	reply := func(line string) bool {
+		return writer.PrintfLine("%s", line) == nil
+	}
+
+	if !reply("220 localhost") {
+		return
+	}
+
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+
+			data, err := reader.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestFetchTaskMap() {
+	ctx := context.Background()
+
+	// Create test data
+	user := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	// two tasks a few hundred meters apart in Manhattan and one in Brooklyn
+	tasks := []models.Task{
+		{
+			Title:       "Task 1",
+			Description: "Description 1",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+		{
+			Title:       "Task 2",
+			Description: "Description 2",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0030, 40.7150}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+		{
+			Title:       "Task 3",
+			Description: "Description 3",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-73.9442, 40.6782}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+			Blocked:     true,
+		},
+	}
+
+	bbox := services.BoundingBox{MinLongitude: -74.1, MinLatitude: 40.6, MaxLongitude: -73.9, MaxLatitude: 40.8}
+	filter := services.Filter{CreatedByUserID: user.ID}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(user).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(&tasks).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		// zoomed out, the nearby tasks end up in one cluster
+		taskMap, err := services.FetchTaskMap(ctx, tx, bbox, 10, filter)
+		require.NoError(s.T(), err)
+		require.True(s.T(), taskMap.Clustered)
+		require.Len(s.T(), taskMap.Clusters, 2)
+
+		total := 0
+		for _, cluster := range taskMap.Clusters {
+			total += cluster.Count
+		}
+		require.Equal(s.T(), 3, total)
+
+		// zoomed in, tasks are returned individually and the usual filters apply
+		filter.ApplyBlockFilter = true
+
+		taskMap, err = services.FetchTaskMap(ctx, tx, bbox, 15, filter)
+		require.NoError(s.T(), err)
+		require.False(s.T(), taskMap.Clustered)
+		require.Len(s.T(), taskMap.Points, 2)
+		require.InDelta(s.T(), 40.7, taskMap.Points[0].Latitude, 0.1)
+
+		// a viewport elsewhere is empty
+		taskMap, err = services.FetchTaskMap(ctx, tx, services.BoundingBox{MinLongitude: 0, MinLatitude: 0, MaxLongitude: 1, MaxLatitude: 1}, 15, filter)
+		require.NoError(s.T(), err)
+		require.Empty(s.T(), taskMap.Points)
+
+		_, err = services.FetchTaskMap(ctx, tx, services.BoundingBox{MinLongitude: 1, MaxLongitude: 0}, 15, filter)
+		require.ErrorIs(s.T(), err, services.ErrInvalidBoundingBox)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,132 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestTaskOrganizers() {
+	ctx := context.Background()
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}, Email: ptr("owner@example.org")}
+	helper := &models.User{Base: models.Base{ID: uuid.New()}, Email: ptr("helper@example.org")}
+	outsider := &models.User{Base: models.Base{ID: uuid.New()}, Email: ptr("outsider@example.org")}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, helper, outsider} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		task := &models.Task{
+			Title:       "Beach cleanup",
+			Description: "Pick up litter on the beach",
+			CategoryID:  category.ID,
+		}
+		err = services.CreateTask(ctx, tx, task, owner.ID, "", "")
+		require.NoError(s.T(), err)
+
+		err = services.InviteTaskOrganizer(ctx, tx, *task, "OWNER@example.org", &models.TaskOrganizer{}, owner.ID)
+		require.ErrorIs(s.T(), err, services.ErrTaskOwnerAsOrganizer)
+
+		err = services.InviteTaskOrganizer(ctx, tx, *task, "nobody@example.org", &models.TaskOrganizer{}, owner.ID)
+		require.ErrorIs(s.T(), err, services.ErrUserNotFound)
+
+		err = services.InviteTaskOrganizer(ctx, tx, *task, "helper@example.org", &models.TaskOrganizer{
+			CanManageVolunteers: true,
+		}, owner.ID)
+		require.NoError(s.T(), err)
+
+		err = services.InviteTaskOrganizer(ctx, tx, *task, "helper@example.org", &models.TaskOrganizer{}, owner.ID)
+		require.ErrorIs(s.T(), err, services.ErrAlreadyTaskOrganizer)
+
+		// the invitation grants nothing until it is accepted
+		organizer, err := services.IsTaskOrganizer(ctx, tx, *task, helper.ID)
+		require.NoError(s.T(), err)
+		require.False(s.T(), organizer)
+
+		allowed, err := services.HasTaskPermission(ctx, tx, *task, helper.ID, models.TaskPermissionManageVolunteers)
+		require.NoError(s.T(), err)
+		require.False(s.T(), allowed)
+
+		invitations, err := services.FetchTaskOrganizerInvitations(ctx, tx, helper.ID)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), invitations, 1)
+		require.Equal(s.T(), task.ID, invitations[0].TaskID)
+
+		_, err = services.AcceptTaskOrganizerInvitation(ctx, tx, task.ID, helper.ID)
+		require.NoError(s.T(), err)
+
+		// co-organizers only get the permissions they were given, owners get them all
+		for _, tt := range []struct {
+			user       *models.User
+			permission string
+			allowed    bool
+		}{
+			{owner, models.TaskPermissionEditDetails, true},
+			{owner, models.TaskPermissionMessageVolunteers, true},
+			{helper, models.TaskPermissionManageVolunteers, true},
+			{helper, models.TaskPermissionEditDetails, false},
+			{helper, models.TaskPermissionMessageVolunteers, false},
+			{outsider, models.TaskPermissionManageVolunteers, false},
+		} {
+			allowed, err := services.HasTaskPermission(ctx, tx, *task, tt.user.ID, tt.permission)
+			require.NoError(s.T(), err)
+			require.Equal(s.T(), tt.allowed, allowed)
+		}
+
+		organizer, err = services.IsTaskOrganizer(ctx, tx, *task, helper.ID)
+		require.NoError(s.T(), err)
+		require.True(s.T(), organizer)
+
+		owns, err := services.IsTaskOwner(ctx, tx, *task, helper.ID)
+		require.NoError(s.T(), err)
+		require.False(s.T(), owns)
+
+		_, err = services.UpdateTaskOrganizerPermissions(ctx, tx, task.ID, helper.ID, models.TaskOrganizer{
+			CanEditDetails: true,
+		})
+		require.NoError(s.T(), err)
+
+		allowed, err = services.HasTaskPermission(ctx, tx, *task, helper.ID, models.TaskPermissionEditDetails)
+		require.NoError(s.T(), err)
+		require.True(s.T(), allowed)
+
+		allowed, err = services.HasTaskPermission(ctx, tx, *task, helper.ID, models.TaskPermissionManageVolunteers)
+		require.NoError(s.T(), err)
+		require.False(s.T(), allowed)
+
+		organizers, err := services.FetchTaskOrganizers(ctx, tx, task.ID)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), organizers, 1)
+		require.Equal(s.T(), helper.ID, organizers[0].User.ID)
+
+		err = services.RemoveTaskOrganizer(ctx, tx, task.ID, helper.ID)
+		require.NoError(s.T(), err)
+
+		organizer, err = services.IsTaskOrganizer(ctx, tx, *task, helper.ID)
+		require.NoError(s.T(), err)
+		require.False(s.T(), organizer)
+
+		err = services.RemoveTaskOrganizer(ctx, tx, task.ID, helper.ID)
+		require.ErrorIs(s.T(), err, services.ErrNotTaskOrganizer)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"rashikzaman/api/models"
 	"rashikzaman/api/services"
+	"rashikzaman/api/utils"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/paulmach/orb"
@@ -33,6 +36,12 @@ func (s *TestSuite) TestCreateTask() {
 		_, err = tx.NewInsert().Model(category).Exec(ctx)
 		require.NoError(s.T(), err)
 
+		// tasks can only require skills of the taxonomy
+		for _, name := range []string{"skill1", "skill2"} {
+			err = services.CreateSkill(ctx, tx, &models.Skill{Name: name})
+			require.NoError(s.T(), err)
+		}
+
 		// Test data
 		taskBody := &models.Task{
 			Title:                   "Test Task",
@@ -246,7 +255,7 @@ func (s *TestSuite) TestUpdateTask() {
 		}
 
 		// Test function
-		result, err := services.UpdateTask(ctx, tx, *originalTask, updatedTask)
+		result, _, err := services.UpdateTask(ctx, tx, *originalTask, updatedTask)
 		require.NoError(s.T(), err)
 		require.Equal(s.T(), "Updated Title", result.Title)
 
@@ -256,6 +265,11 @@ func (s *TestSuite) TestUpdateTask() {
 		require.NoError(s.T(), err)
 		require.Equal(s.T(), "Updated Title", dbTask.Title)
 
+		// the status is changed through ChangeTaskStatus only
+		updatedTask.Status = models.TaskStatusCancelled
+		_, _, err = services.UpdateTask(ctx, tx, result, updatedTask)
+		require.ErrorIs(s.T(), err, services.ErrTaskStatusNotEditable)
+
 		return nil
 	})
 }
@@ -334,7 +348,7 @@ func (s *TestSuite) TestApplyAndWithdrawFromTask() {
 		require.NoError(s.T(), err)
 
 		// Test ApplyToTask
-		err = services.ApplyToTask(ctx, tx, task.ID, user.ID)
+		_, err = services.ApplyToTask(ctx, tx, task.ID, user.ID, nil)
 		require.NoError(s.T(), err)
 
 		// Verify application
@@ -346,16 +360,16 @@ func (s *TestSuite) TestApplyAndWithdrawFromTask() {
 		require.NoError(s.T(), err)
 
 		// Test WithdrawFromTask
-		err = services.WithdrawFromTask(ctx, tx, task.ID, user.ID)
+		_, err = services.WithdrawFromTask(ctx, tx, task.ID, user.ID, nil)
 		require.NoError(s.T(), err)
 
 		// Verify withdrawal
-		count, err := tx.NewSelect().Model((*models.UserTask)(nil)).
+		err = tx.NewSelect().Model(&userTask).
 			Where("user_id = ?", user.ID).
 			Where("task_id = ?", task.ID).
-			Count(ctx)
+			Scan(ctx)
 		require.NoError(s.T(), err)
-		require.Equal(s.T(), 0, count)
+		require.Equal(s.T(), models.ApplicationStatusWithdrawn, userTask.Status)
 
 		return nil
 	})
@@ -475,3 +489,782 @@ func (s *TestSuite) TestApplyActionToTask() {
 		return nil
 	})
 }
+
+func (s *TestSuite) TestChangeTaskStatus() {
+	ctx := context.Background()
+
+	// Create test data
+	user := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+	task := &models.Task{
+		Base:        models.Base{ID: uuid.New()},
+		Title:       "Task with a lifecycle",
+		Description: "Will be started and completed",
+		Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+		UserID:      user.ID,
+		CategoryID:  category.ID,
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(user).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(task).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		// new tasks default to open
+		fetchedTask, err := services.FetchTaskByID(ctx, tx, task.ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.TaskStatusOpen, fetchedTask.Status)
+
+		// open -> completed skips in_progress
+		_, err = services.CompleteTask(ctx, tx, task.ID)
+		require.ErrorIs(s.T(), err, services.ErrInvalidTaskStatusTransition)
+
+		updatedTask, err := services.ChangeTaskStatus(ctx, tx, task.ID, models.TaskStatusInProgress)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.TaskStatusInProgress, updatedTask.Status)
+
+		updatedTask, err = services.CompleteTask(ctx, tx, task.ID)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.TaskStatusCompleted, updatedTask.Status)
+
+		// completed is terminal
+		_, err = services.CancelTask(ctx, tx, task.ID)
+		require.ErrorIs(s.T(), err, services.ErrInvalidTaskStatusTransition)
+
+		_, _, err = services.UpdateTask(ctx, tx, *updatedTask, models.Task{Title: "Too late"})
+		require.ErrorIs(s.T(), err, services.ErrTaskNotEditable)
+
+		_, err = services.ChangeTaskStatus(ctx, tx, task.ID, "unknown")
+		require.ErrorIs(s.T(), err, services.ErrInvalidTaskStatus)
+
+		// status filter
+		tasks, count, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{
+			CreatedByUserID: user.ID,
+			Statuses:        []string{models.TaskStatusOpen},
+		})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 0, count)
+		require.Len(s.T(), tasks, 0)
+
+		tasks, count, err = services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{
+			CreatedByUserID: user.ID,
+			Statuses:        []string{models.TaskStatusCompleted},
+		})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, count)
+		require.Len(s.T(), tasks, 1)
+
+		return nil
+	})
+
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestApplyToTaskWaitlist() {
+	ctx := context.Background()
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer1 := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer2 := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+	task := &models.Task{
+		Base:                    models.Base{ID: uuid.New()},
+		Title:                   "Task with one spot",
+		Description:             "Only one volunteer needed",
+		RequiredVolunteersCount: 1,
+		AutoAccept:              true,
+		Location:                models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+		UserID:                  owner.ID,
+		CategoryID:              category.ID,
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, volunteer1, volunteer2} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(task).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		// first volunteer takes the only spot and fills the task
+		userTask, err := services.ApplyToTask(ctx, tx, task.ID, volunteer1.ID, nil)
+		require.NoError(s.T(), err)
+		require.False(s.T(), userTask.Waitlisted)
+
+		_, err = services.ApplyToTask(ctx, tx, task.ID, volunteer1.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrAlreadyApplied)
+
+		fetchedTask, err := services.FetchTaskByID(ctx, tx, task.ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.TaskStatusFilled, fetchedTask.Status)
+
+		// second volunteer lands on the waitlist
+		userTask, err = services.ApplyToTask(ctx, tx, task.ID, volunteer2.ID, nil)
+		require.NoError(s.T(), err)
+		require.True(s.T(), userTask.Waitlisted)
+
+		tasks, _, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{CreatedByUserID: owner.ID})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), tasks, 1)
+		require.NotNil(s.T(), tasks[0].RemainingSpots)
+		require.Equal(s.T(), 0, *tasks[0].RemainingSpots)
+		require.Equal(s.T(), 1, tasks[0].WaitlistCount)
+
+		// withdrawing the first volunteer promotes the second
+		promoted, err := services.WithdrawFromTask(ctx, tx, task.ID, volunteer1.ID, nil)
+		require.NoError(s.T(), err)
+		require.NotNil(s.T(), promoted)
+		require.Equal(s.T(), volunteer2.ID, promoted.UserID)
+		require.False(s.T(), promoted.Waitlisted)
+
+		// withdrawing the last volunteer reopens the task
+		promoted, err = services.WithdrawFromTask(ctx, tx, task.ID, volunteer2.ID, nil)
+		require.NoError(s.T(), err)
+		require.Nil(s.T(), promoted)
+
+		fetchedTask, err = services.FetchTaskByID(ctx, tx, task.ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.TaskStatusOpen, fetchedTask.Status)
+
+		return nil
+	})
+
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestUpdateTaskCapacity() {
+	ctx := context.Background()
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer1 := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer2 := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+	task := &models.Task{
+		Base:                    models.Base{ID: uuid.New()},
+		Title:                   "Task with one spot",
+		Description:             "Only one volunteer needed",
+		RequiredVolunteersCount: 1,
+		AutoAccept:              true,
+		Location:                models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+		UserID:                  owner.ID,
+		CategoryID:              category.ID,
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, volunteer1, volunteer2} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(task).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		for _, volunteer := range []*models.User{volunteer1, volunteer2} {
+			_, err = services.ApplyToTask(ctx, tx, task.ID, volunteer.ID, nil)
+			require.NoError(s.T(), err)
+		}
+
+		body := *task
+		body.Status = ""
+		body.RequiredVolunteersCount = 2
+
+		// a second spot goes to the waitlisted volunteer and the task stays filled
+		updated, promoted, err := services.UpdateTask(ctx, tx, *task, body)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), promoted, 1)
+		require.Equal(s.T(), volunteer2.ID, promoted[0].UserID)
+		require.Equal(s.T(), models.TaskStatusFilled, updated.Status)
+
+		body.RequiredVolunteersCount = 3
+
+		updated, promoted, err = services.UpdateTask(ctx, tx, updated, body)
+		require.NoError(s.T(), err)
+		require.Empty(s.T(), promoted)
+		require.Equal(s.T(), models.TaskStatusOpen, updated.Status)
+
+		fetchedTask, err := services.FetchTaskByID(ctx, tx, task.ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.TaskStatusOpen, fetchedTask.Status)
+		require.Equal(s.T(), 3, fetchedTask.RequiredVolunteersCount)
+
+		return nil
+	})
+
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestReviewApplication() {
+	ctx := context.Background()
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+	task := &models.Task{
+		Base:        models.Base{ID: uuid.New()},
+		Title:       "Task with screening",
+		Description: "Organizer reviews every volunteer",
+		AutoAccept:  false,
+		Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+		UserID:      owner.ID,
+		CategoryID:  category.ID,
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, volunteer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(task).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		userTask, err := services.ApplyToTask(ctx, tx, task.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.ApplicationStatusPending, userTask.Status)
+
+		pending, err := services.FetchSubscribersForTask(ctx, tx, task.ID, models.ApplicationStatusPending)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), pending, 1)
+
+		userTask, err = services.AcceptApplication(ctx, tx, task.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), models.ApplicationStatusAccepted, userTask.Status)
+
+		// accepting twice is not a valid transition
+		_, err = services.AcceptApplication(ctx, tx, task.ID, volunteer.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrInvalidApplicationTransition)
+
+		userTask, promoted, err := services.RejectApplication(ctx, tx, task.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+		require.Nil(s.T(), promoted)
+		require.Equal(s.T(), models.ApplicationStatusRejected, userTask.Status)
+
+		// rejected applicants cannot simply apply again
+		_, err = services.ApplyToTask(ctx, tx, task.ID, volunteer.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrApplicationRejected)
+
+		_, err = services.AcceptApplication(ctx, tx, task.ID, uuid.New(), nil)
+		require.ErrorIs(s.T(), err, services.ErrApplicationNotFound)
+
+		// applications on a task that is no longer open cannot be accepted
+		_, err = tx.NewUpdate().Model(task).Set("status = ?", models.TaskStatusCancelled).WherePK().Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = services.AcceptApplication(ctx, tx, task.ID, volunteer.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrTaskNotOpen)
+
+		return nil
+	})
+
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestTaskScheduleAndShifts() {
+	ctx := context.Background()
+
+	now := time.Now()
+	tomorrow := now.Add(24 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer1 := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer2 := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, volunteer1, volunteer2} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		upcomingEnd := tomorrow.Add(4 * time.Hour)
+		upcoming := &models.Task{
+			Title:       "Upcoming event",
+			Description: "Happens tomorrow",
+			AutoAccept:  true,
+			StartsAt:    &tomorrow,
+			EndsAt:      &upcomingEnd,
+			TimeZone:    "Asia/Dhaka",
+			CategoryID:  category.ID,
+			Shifts: []*models.TaskShift{
+				{StartsAt: tomorrow, EndsAt: tomorrow.Add(2 * time.Hour), Capacity: 1},
+			},
+		}
+		err = services.CreateTask(ctx, tx, upcoming, owner.ID, "", "")
+		require.NoError(s.T(), err)
+
+		pastEnd := yesterday.Add(time.Hour)
+		past := &models.Task{
+			Title:       "Past event",
+			Description: "Already happened",
+			StartsAt:    &yesterday,
+			EndsAt:      &pastEnd,
+			CategoryID:  category.ID,
+		}
+		err = services.CreateTask(ctx, tx, past, owner.ID, "", "")
+		require.NoError(s.T(), err)
+
+		// a task cannot end before it starts
+		invalid := &models.Task{Title: "Invalid", Description: "Invalid", StartsAt: &tomorrow, EndsAt: &yesterday, CategoryID: category.ID}
+		err = services.CreateTask(ctx, tx, invalid, owner.ID, "", "")
+		require.ErrorIs(s.T(), err, services.ErrInvalidSchedule)
+
+		tasks, _, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{CreatedByUserID: owner.ID, HidePast: true})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), tasks, 1)
+		require.Equal(s.T(), upcoming.ID, tasks[0].ID)
+
+		from := now
+		to := now.Add(48 * time.Hour)
+		tasks, _, err = services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{CreatedByUserID: owner.ID, From: &from, To: &to})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), tasks, 1)
+
+		// tasks split into shifts need a shift to apply to
+		_, err = services.ApplyToTask(ctx, tx, upcoming.ID, volunteer1.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrShiftRequired)
+
+		shiftID := upcoming.Shifts[0].ID
+
+		userTask, err := services.ApplyToTask(ctx, tx, upcoming.ID, volunteer1.ID, &shiftID)
+		require.NoError(s.T(), err)
+		require.False(s.T(), userTask.Waitlisted)
+
+		// the shift has a single spot
+		userTask, err = services.ApplyToTask(ctx, tx, upcoming.ID, volunteer2.ID, &shiftID)
+		require.NoError(s.T(), err)
+		require.True(s.T(), userTask.Waitlisted)
+
+		return nil
+	})
+
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestRecurringTasks() {
+	ctx := context.Background()
+
+	startsAt := time.Now().Add(time.Hour).Truncate(time.Minute)
+	endsAt := startsAt.Add(2 * time.Hour)
+
+	// Create test data
+	owner := &models.User{Base: models.Base{ID: uuid.New()}}
+	volunteer := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		for _, user := range []*models.User{owner, volunteer} {
+			_, err := tx.NewInsert().Model(user).Exec(ctx)
+			require.NoError(s.T(), err)
+		}
+
+		_, err := tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		invalid := &models.Task{Title: "Invalid", Description: "Invalid", RecurrenceRule: "FREQ=HOURLY", StartsAt: &startsAt, EndsAt: &endsAt, CategoryID: category.ID}
+		err = services.CreateTask(ctx, tx, invalid, owner.ID, "", "")
+		require.ErrorIs(s.T(), err, services.ErrInvalidRecurrenceRule)
+
+		unscheduled := &models.Task{Title: "Unscheduled", Description: "Unscheduled", RecurrenceRule: "FREQ=WEEKLY", CategoryID: category.ID}
+		err = services.CreateTask(ctx, tx, unscheduled, owner.ID, "", "")
+		require.ErrorIs(s.T(), err, services.ErrRecurrenceNeedsSchedule)
+
+		template := &models.Task{
+			Title:          "Food bank sorting",
+			Description:    "Every week",
+			AutoAccept:     true,
+			RecurrenceRule: "RRULE:FREQ=WEEKLY;COUNT=4",
+			StartsAt:       &startsAt,
+			EndsAt:         &endsAt,
+			CategoryID:     category.ID,
+			Shifts: []*models.TaskShift{
+				{StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour), Capacity: 2},
+			},
+		}
+		err = services.CreateTask(ctx, tx, template, owner.ID, "", "")
+		require.NoError(s.T(), err)
+
+		occurrences, _, err := services.FetchTasks(ctx, tx, models.QueryParam{Relations: []string{"Shifts"}}, services.Filter{CreatedByUserID: owner.ID, HideRecurringTemplates: true})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), occurrences, 4)
+
+		sort.Slice(occurrences, func(i, j int) bool {
+			return occurrences[i].StartsAt.Before(*occurrences[j].StartsAt)
+		})
+
+		for _, occurrence := range occurrences {
+			require.Equal(s.T(), template.ID, *occurrence.ParentTaskID)
+			require.Len(s.T(), occurrence.Shifts, 1)
+			require.Equal(s.T(), occurrence.StartsAt.UTC(), occurrence.Shifts[0].StartsAt.UTC())
+		}
+
+		// generating again does not duplicate occurrences
+		created, err := services.GenerateUpcomingOccurrences(ctx, tx, time.Now().AddDate(0, 0, 30))
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 0, created)
+
+		// volunteers apply to occurrences, not to the template
+		_, err = services.ApplyToTask(ctx, tx, template.ID, volunteer.ID, nil)
+		require.ErrorIs(s.T(), err, services.ErrRecurringTaskTemplate)
+
+		second, err := services.FetchTaskByID(ctx, tx, occurrences[1].ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+
+		_, err = services.ApplyToTask(ctx, tx, second.ID, volunteer.ID, nil)
+		require.NoError(s.T(), err)
+
+		// editing this and all future occurrences leaves earlier ones untouched
+		body := second
+		body.Title = "Food bank sorting (new hall)"
+		newStartsAt := second.StartsAt.Add(time.Hour)
+		newEndsAt := second.EndsAt.Add(time.Hour)
+		body.StartsAt = &newStartsAt
+		body.EndsAt = &newEndsAt
+
+		// only the organizers of the series can edit it
+		_, err = services.UpdateFutureOccurrences(ctx, tx, volunteer.ID, second, body)
+		require.ErrorIs(s.T(), err, services.ErrNotTaskOrganizer)
+
+		_, err = services.UpdateFutureOccurrences(ctx, tx, owner.ID, second, body)
+		require.NoError(s.T(), err)
+
+		first, err := services.FetchTaskByID(ctx, tx, occurrences[0].ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), "Food bank sorting", first.Title)
+
+		last, err := services.FetchTaskByID(ctx, tx, occurrences[3].ID, models.QueryParam{})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), "Food bank sorting (new hall)", last.Title)
+		require.Equal(s.T(), occurrences[3].StartsAt.Add(time.Hour).UTC(), last.StartsAt.UTC())
+
+		// the template itself is not an occurrence
+		_, err = services.UpdateFutureOccurrences(ctx, tx, owner.ID, *template, body)
+		require.ErrorIs(s.T(), err, services.ErrNotAnOccurrence)
+
+		// occurrences cannot lose the times the rest of the series is shifted from
+		cleared := last
+		cleared.EndsAt = nil
+		_, _, err = services.UpdateTask(ctx, tx, last, cleared)
+		require.ErrorIs(s.T(), err, services.ErrRecurrenceNeedsSchedule)
+
+		// nobody can attach their own task to someone else's series
+		forged := &models.Task{
+			Title:        "Forged occurrence",
+			Description:  "Forged occurrence",
+			StartsAt:     &startsAt,
+			EndsAt:       &endsAt,
+			CategoryID:   category.ID,
+			ParentTaskID: &template.ID,
+		}
+		err = services.CreateTask(ctx, tx, forged, volunteer.ID, "", "")
+		require.NoError(s.T(), err)
+		require.Nil(s.T(), forged.ParentTaskID)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestSearchTasks() {
+	ctx := context.Background()
+
+	// Create test data
+	user := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Environment",
+	}
+
+	tasks := []models.Task{
+		{
+			Title:       "Gardening at the community center",
+			Description: "Help us plant vegetables",
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+		{
+			Title:          "Food bank sorting",
+			Description:    "Sort donations, some gardening experience is a plus",
+			RequiredSkills: []string{"lifting"},
+			UserID:         user.ID,
+			CategoryID:     category.ID,
+		},
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(user).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(&tasks).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		// stemming matches "gardeners" to "gardening", and a title match ranks first
+		result, count, err := services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{SearchTerm: "gardeners", SortByRelevance: true})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 2, count)
+		require.Equal(s.T(), tasks[0].ID, result[0].ID)
+		require.Greater(s.T(), *result[0].SearchRank, *result[1].SearchRank)
+		require.Contains(s.T(), result[0].TitleHighlight, "<mark>Gardening</mark>")
+		require.Contains(s.T(), result[1].DescriptionHighlight, "<mark>gardening</mark>")
+
+		// skills and the category name are searched too
+		_, count, err = services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{SearchTerm: "lifting"})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, count)
+
+		_, count, err = services.FetchTasks(ctx, tx, models.QueryParam{}, services.Filter{SearchTerm: "environment", CreatedByUserID: user.ID})
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 2, count)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestFetchTasksByDistance() {
+	ctx := context.Background()
+
+	// Create test data
+	user := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	// roughly 11 km, 1 km and 100 km north of the search location
+	tasks := []models.Task{
+		{
+			Title:       "Farther task",
+			Description: "Farther",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.8128}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+		{
+			Title:       "Nearest task",
+			Description: "Nearest",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7218}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+		{
+			Title:       "Far away task",
+			Description: "Outside the default radius",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 41.6128}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(user).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(&tasks).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		queryParam := models.QueryParam{Pagination: utils.PaginationConfig{SortColumn: "distance"}}
+		filter := services.Filter{Latitude: 40.7128, Longitude: -74.0060, CreatedByUserID: user.ID}
+
+		result, count, err := services.FetchTasks(ctx, tx, queryParam, filter)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 2, count)
+		require.Equal(s.T(), tasks[1].ID, result[0].ID)
+		require.InDelta(s.T(), 1.0, *result[0].DistanceKm, 0.1)
+		require.InDelta(s.T(), 11.1, *result[1].DistanceKm, 0.2)
+
+		queryParam.Pagination.SortDirection = "desc"
+		filter.Distance = 200
+
+		result, count, err = services.FetchTasks(ctx, tx, queryParam, filter)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 3, count)
+		require.Equal(s.T(), tasks[2].ID, result[0].ID)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestFetchTasksWithCursor() {
+	ctx := context.Background()
+
+	// Create test data
+	user := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	// created in one transaction the tasks share their creation time, so the id decides
+	tasks := []models.Task{}
+	for i := 0; i < 5; i++ {
+		tasks = append(tasks, models.Task{
+			Title:       "Task",
+			Description: "Description",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128 + float64(i)/100}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		})
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(user).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(&tasks).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		// the tasks share their text, so when searching by relevance they all tie on rank
+		// and the pages break in the middle of the tie
+		orders := []struct {
+			sortColumn string
+			filter     services.Filter
+		}{
+			{filter: services.Filter{CreatedByUserID: user.ID}},
+			{sortColumn: "distance", filter: services.Filter{CreatedByUserID: user.ID, Latitude: 40.7128, Longitude: -74.0060}},
+			{filter: services.Filter{CreatedByUserID: user.ID, SearchTerm: "task", SortByRelevance: true}},
+		}
+
+		for _, order := range orders {
+			pagination := utils.PaginationConfig{
+				Limit: 2, UseCursor: true, CursorSecret: "secret", SortColumn: order.sortColumn,
+			}
+			filter := order.filter
+			seen := map[uuid.UUID]bool{}
+			pages := 0
+
+			for {
+				result, count, err := services.FetchTasks(ctx, tx, models.QueryParam{Pagination: pagination}, filter)
+				require.NoError(s.T(), err)
+				require.Equal(s.T(), 5, count)
+
+				for _, task := range result {
+					require.False(s.T(), seen[task.ID])
+					seen[task.ID] = true
+				}
+
+				pages++
+
+				pagination.Cursor, err = services.NextTasksCursor(pagination, filter, result)
+				require.NoError(s.T(), err)
+
+				if pagination.Cursor == "" {
+					break
+				}
+			}
+
+			require.Len(s.T(), seen, 5)
+			require.Equal(s.T(), 3, pages)
+		}
+
+		pagination := utils.PaginationConfig{Limit: 2, UseCursor: true, CursorSecret: "secret", SkipCount: true}
+		filter := services.Filter{CreatedByUserID: user.ID}
+
+		result, count, err := services.FetchTasks(ctx, tx, models.QueryParam{Pagination: pagination}, filter)
+		require.NoError(s.T(), err)
+		require.Zero(s.T(), count)
+
+		// a cursor signed with another secret or for another order is rejected
+		pagination.Cursor, err = services.NextTasksCursor(pagination, filter, result)
+		require.NoError(s.T(), err)
+
+		pagination.CursorSecret = "other secret"
+		_, _, err = services.FetchTasks(ctx, tx, models.QueryParam{Pagination: pagination}, filter)
+		require.ErrorIs(s.T(), err, utils.ErrInvalidCursor)
+
+		// without a secret cursors could be forged by anyone
+		pagination.CursorSecret = ""
+		_, _, err = services.FetchTasks(ctx, tx, models.QueryParam{Pagination: pagination}, filter)
+		require.ErrorIs(s.T(), err, utils.ErrCursorsDisabled)
+
+		pagination.CursorSecret = "secret"
+		pagination.SortDirection = "asc"
+		_, _, err = services.FetchTasks(ctx, tx, models.QueryParam{Pagination: pagination}, filter)
+		require.ErrorIs(s.T(), err, utils.ErrInvalidCursor)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *TestSuite) TestValidateTaskListQuery() {
+	// distance is only known around a location
+	err := services.ValidateTaskListQuery(utils.PaginationConfig{SortColumn: "distance"}, services.Filter{})
+	require.ErrorIs(s.T(), err, utils.ErrInvalidListQuery)
+
+	err = services.ValidateTaskListQuery(
+		utils.PaginationConfig{SortColumn: "distance"}, services.Filter{Latitude: 40.7128, Longitude: -74.0060},
+	)
+	require.NoError(s.T(), err)
+
+	err = services.ValidateTaskListQuery(utils.PaginationConfig{SortColumn: "unknown"}, services.Filter{})
+	require.ErrorIs(s.T(), err, utils.ErrInvalidListQuery)
+}
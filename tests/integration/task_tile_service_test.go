@@ -0,0 +1,80 @@
+package integration_test
+
+import (
+	"context"
+	"rashikzaman/api/models"
+	"rashikzaman/api/services"
+
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+)
+
+func (s *TestSuite) TestTaskGeoJSONAndTiles() {
+	ctx := context.Background()
+
+	// Create test data
+	user := &models.User{Base: models.Base{ID: uuid.New()}}
+	category := &models.Category{
+		Base: models.Base{
+			ID: uuid.New(),
+		},
+		Name: "Test Category",
+	}
+
+	tasks := []models.Task{
+		{
+			Title:       "Task 1",
+			Description: "Description 1",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-74.0060, 40.7128}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+		{
+			Title:       "Task 2",
+			Description: "Description 2",
+			Location:    models.PostgisGeometry{Geometry: orb.Point{-73.9442, 40.6782}, SRID: 4326},
+			UserID:      user.ID,
+			CategoryID:  category.ID,
+		},
+	}
+
+	filter := services.Filter{CreatedByUserID: user.ID}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(user).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(category).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		_, err = tx.NewInsert().Model(&tasks).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		collection, err := services.FetchTasksFeatureCollection(ctx, tx, models.QueryParam{}, filter)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), collection.Features, 2)
+		require.Equal(s.T(), 2, collection.ExtraMembers["count"])
+
+		point, ok := collection.Features[0].Geometry.(orb.Point)
+		require.True(s.T(), ok)
+		require.NotZero(s.T(), point.Lon())
+
+		// zoom 10 tile 301/384 covers lower Manhattan and Brooklyn
+		tile, err := services.FetchTaskTile(ctx, tx, 10, 301, 384, filter)
+		require.NoError(s.T(), err)
+		require.NotEmpty(s.T(), tile)
+
+		// a tile on the other side of the world has no features
+		tile, err = services.FetchTaskTile(ctx, tx, 10, 0, 0, filter)
+		require.NoError(s.T(), err)
+		require.Empty(s.T(), tile)
+
+		_, err = services.FetchTaskTile(ctx, tx, 2, 4, 0, filter)
+		require.ErrorIs(s.T(), err, services.ErrInvalidTile)
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
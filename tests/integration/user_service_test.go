@@ -163,6 +163,89 @@ func (s *TestSuite) TestFetchUsersForAdmin() {
 	require.NoError(s.T(), err)
 }
 
+func (s *TestSuite) TestFetchUsersForAdminWithFilters() {
+	ctx := context.Background()
+
+	users := []models.User{
+		{ClerkID: "clerk_1", Email: ptr("ada@example.com"), FirstName: "Ada", LastName: "Lovelace", Role: "user"},
+		{ClerkID: "clerk_2", Email: ptr("alan@example.com"), FirstName: "Alan", LastName: "Turing", Role: "user"},
+		{ClerkID: "clerk_3", Email: ptr("grace_h@example.com"), FirstName: "Grace", LastName: "Hopper", Role: "admin"},
+	}
+
+	err := models.WithRollBackOnlyTransaction(ctx, s.application.DB, func(tx *bun.Tx) error {
+		_, err := tx.NewInsert().Model(&users).Exec(ctx)
+		require.NoError(s.T(), err)
+
+		tests := []struct {
+			name       string
+			pagination utils.PaginationConfig
+			expected   []string
+		}{
+			{
+				name:       "sort by first name",
+				pagination: utils.PaginationConfig{SortColumn: "first_name", SortDirection: "asc"},
+				expected:   []string{"Ada", "Alan", "Grace"},
+			},
+			{
+				name:       "filter by column",
+				pagination: utils.PaginationConfig{ColumnSearch: map[string]string{"role": "admin"}},
+				expected:   []string{"Grace"},
+			},
+			{
+				name:       "search",
+				pagination: utils.PaginationConfig{Search: "TURING"},
+				expected:   []string{"Alan"},
+			},
+			{
+				name:       "wildcards are taken literally",
+				pagination: utils.PaginationConfig{ColumnSearch: map[string]string{"email": "_h@"}},
+				expected:   []string{"Grace"},
+			},
+			{
+				name: "date range",
+				pagination: utils.PaginationConfig{
+					DateField: "created_at", EndDate: time.Now().AddDate(0, 0, -1).Format(time.RFC3339),
+				},
+				expected: []string{},
+			},
+		}
+
+		for _, tt := range tests {
+			s.T().Run(tt.name, func(t *testing.T) {
+				require.NoError(t, tt.pagination.Validate(services.UserListSpec))
+
+				result, count, err := services.FetchUsersForAdmin(ctx, tx, models.QueryParam{Pagination: tt.pagination})
+				require.NoError(t, err)
+				require.Equal(t, len(tt.expected), count)
+
+				names := []string{}
+				for _, user := range result {
+					names = append(names, user.FirstName)
+				}
+
+				require.Equal(t, tt.expected, names)
+			})
+		}
+
+		return nil
+	})
+	require.NoError(s.T(), err)
+
+	// nothing outside of the spec gets near the query
+	invalid := []utils.PaginationConfig{
+		{SortColumn: "created_at; DROP TABLE users"},
+		{SortColumn: "created_at", SortDirection: "sideways"},
+		{ColumnSearch: map[string]string{"clerk_id": "clerk"}},
+		{DateField: "updated_at", StartDate: "2024-01-01"},
+		{DateField: "created_at", StartDate: "yesterday"},
+		{SortColumn: "first_name", UseCursor: true},
+	}
+
+	for _, pagination := range invalid {
+		require.ErrorIs(s.T(), pagination.Validate(services.UserListSpec), utils.ErrInvalidListQuery)
+	}
+}
+
 func (s *TestSuite) TestGetUserByID() {
 	ctx := context.Background()
 
@@ -9,3 +9,12 @@ func DeleteEmptyFromSlice(s []string) []string {
 	}
 	return r
 }
+
+func Contains(s []string, str string) bool {
+	for _, item := range s {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
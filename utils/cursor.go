@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrCursorsDisabled = errors.New("cursors are not configured")
+)
+
+// KeysetColumn is one of the columns a listing is ordered by in cursor mode. Expr is the SQL
+// expression of the column with its placeholders filled by Args.
+type KeysetColumn struct {
+	Name       string
+	Expr       string
+	Args       []interface{}
+	Descending bool
+}
+
+// args returns the arguments of the column's expression followed by value.
+func (column KeysetColumn) args(value interface{}) []interface{} {
+	args := make([]interface{}, 0, len(column.Args)+1)
+
+	return append(append(args, column.Args...), value)
+}
+
+type cursorPayload struct {
+	Keyset string        `json:"k"`
+	Values []interface{} `json:"v"`
+}
+
+// EncodeCursor returns an opaque cursor pointing right after the record with the given
+// values of the keyset columns. The cursor is signed so that clients cannot craft one.
+func EncodeCursor(secret string, keyset []KeysetColumn, values ...interface{}) (string, error) {
+	if secret == "" {
+		return "", ErrCursorsDisabled
+	}
+
+	payload, err := json.Marshal(cursorPayload{Keyset: keysetSignature(keyset), Values: values})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + signCursor(secret, encoded), nil
+}
+
+// DecodeCursor verifies the cursor and returns the values it points after. A cursor issued
+// for a different ordering is rejected, as its values would not line up with the columns.
+func DecodeCursor(secret, cursor string, keyset []KeysetColumn) ([]interface{}, error) {
+	if secret == "" {
+		return nil, ErrCursorsDisabled
+	}
+
+	encoded, signature, found := strings.Cut(cursor, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(signCursor(secret, encoded))) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	decoded := cursorPayload{}
+
+	err = json.Unmarshal(payload, &decoded)
+	if err != nil || decoded.Keyset != keysetSignature(keyset) || len(decoded.Values) != len(keyset) {
+		return nil, ErrInvalidCursor
+	}
+
+	return decoded.Values, nil
+}
+
+func keysetSignature(keyset []KeysetColumn) string {
+	columns := make([]string, 0, len(keyset))
+
+	for _, column := range keyset {
+		if column.Descending {
+			columns = append(columns, column.Name+":desc")
+		} else {
+			columns = append(columns, column.Name+":asc")
+		}
+	}
+
+	return strings.Join(columns, ",")
+}
+
+func signCursor(secret, payload string) string {
+	mac := hmac.New(sha256.New, DeriveKey(secret, "cursor"))
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
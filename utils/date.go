@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// ParseDateRange resolves either a named period ("today", "this_week", "this_weekend") or
+// explicit from/to values into a time range. Values may be RFC 3339 timestamps or plain
+// dates, in which case they are read in loc and a plain "to" date is inclusive. Either end
+// of the range is nil when it was not asked for.
+func ParseDateRange(period, from, to string, loc *time.Location, now time.Time) (*time.Time, *time.Time, error) {
+	if period != "" {
+		return namedDateRange(period, now.In(loc))
+	}
+
+	var start, end *time.Time
+
+	if from != "" {
+		parsed, _, err := parseDateOrTime(from, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		start = &parsed
+	}
+
+	if to != "" {
+		parsed, isDate, err := parseDateOrTime(to, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if isDate {
+			parsed = parsed.AddDate(0, 0, 1)
+		}
+
+		end = &parsed
+	}
+
+	if start != nil && end != nil && !end.After(*start) {
+		return nil, nil, errors.New("invalid date range: to must be after from")
+	}
+
+	return start, end, nil
+}
+
+func namedDateRange(period string, now time.Time) (*time.Time, *time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// days left until next monday, counting today
+	untilMonday := (8 - int(today.Weekday())) % 7
+	if untilMonday == 0 {
+		untilMonday = 7
+	}
+
+	nextMonday := today.AddDate(0, 0, untilMonday)
+
+	var start, end time.Time
+
+	switch period {
+	case "today":
+		start, end = now, today.AddDate(0, 0, 1)
+	case "this_week":
+		start, end = now, nextMonday
+	case "this_weekend":
+		start, end = nextMonday.AddDate(0, 0, -2), nextMonday
+		if now.After(start) {
+			start = now
+		}
+	default:
+		return nil, nil, errors.New("invalid period: " + period)
+	}
+
+	return &start, &end, nil
+}
+
+func parseDateOrTime(value string, loc *time.Location) (time.Time, bool, error) {
+	if parsed, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return parsed, true, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.New("invalid date: " + value)
+	}
+
+	return parsed, false, nil
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// ListSpec declares what clients may sort and filter a listing by. Each map goes from the
+// name used in the query string to the SQL expression it stands for, so nothing from the
+// query string ever ends up in the SQL itself.
+type ListSpec struct {
+	// SortColumns are the columns accepted in sort-column. DefaultSort is used when none is
+	// given.
+	SortColumns map[string]string
+	DefaultSort string
+	// CursorSortColumns are the sort columns that cursor pagination supports, all of
+	// SortColumns when empty.
+	CursorSortColumns []string
+	// FilterColumns are the text columns accepted in filter[column], matched by substring.
+	FilterColumns map[string]string
+	// SearchColumns are the columns the search parameter looks in. Listings without any
+	// do not accept it.
+	SearchColumns []string
+	// DateColumns are the columns accepted in date-field for start-date/end-date ranges.
+	DateColumns map[string]string
+}
+
+// Validate rejects the sorting and filtering of paginationConfig that spec does not allow,
+// so clients hear about a typo instead of silently getting everything.
+func (paginationConfig *PaginationConfig) Validate(spec ListSpec) error {
+	if paginationConfig.SortColumn != "" {
+		if _, ok := spec.SortColumns[paginationConfig.SortColumn]; !ok {
+			return listQueryError("cannot sort by %q, use one of %s", paginationConfig.SortColumn, keys(spec.SortColumns))
+		}
+
+		if paginationConfig.UseCursor && len(spec.CursorSortColumns) > 0 &&
+			!Contains(spec.CursorSortColumns, paginationConfig.SortColumn) {
+			return listQueryError(
+				"cannot sort by %q with cursor pagination, use one of %s",
+				paginationConfig.SortColumn, strings.Join(spec.CursorSortColumns, ", "),
+			)
+		}
+	}
+
+	direction := strings.ToUpper(paginationConfig.SortDirection)
+	if direction != "" && direction != "ASC" && direction != "DESC" {
+		return listQueryError("invalid sort direction %q, use asc or desc", paginationConfig.SortDirection)
+	}
+
+	for column := range paginationConfig.ColumnSearch {
+		if _, ok := spec.FilterColumns[column]; !ok {
+			return listQueryError("cannot filter by %q, use one of %s", column, keys(spec.FilterColumns))
+		}
+	}
+
+	if paginationConfig.Search != "" && len(spec.SearchColumns) == 0 {
+		return listQueryError("search is not supported here")
+	}
+
+	if paginationConfig.StartDate == "" && paginationConfig.EndDate == "" {
+		return nil
+	}
+
+	if _, ok := spec.DateColumns[paginationConfig.DateField]; !ok {
+		return listQueryError("cannot filter dates by %q, use one of %s", paginationConfig.DateField, keys(spec.DateColumns))
+	}
+
+	_, _, err := paginationConfig.dateRange()
+	if err != nil {
+		return listQueryError("%s", err.Error())
+	}
+
+	return nil
+}
+
+// ApplyFilters narrows query down by the column filters, search and date range of
+// paginationConfig. Anything spec does not allow is ignored, see Validate.
+func (paginationConfig *PaginationConfig) ApplyFilters(query *bun.SelectQuery, spec ListSpec) *bun.SelectQuery {
+	for column, value := range paginationConfig.ColumnSearch {
+		if expr, ok := spec.FilterColumns[column]; ok && value != "" {
+			query.Where("? ILIKE ?", bun.Safe(expr), likePattern(value))
+		}
+	}
+
+	if paginationConfig.Search != "" && len(spec.SearchColumns) > 0 {
+		query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			for _, expr := range spec.SearchColumns {
+				q.WhereOr("? ILIKE ?", bun.Safe(expr), likePattern(paginationConfig.Search))
+			}
+
+			return q
+		})
+	}
+
+	if expr, ok := spec.DateColumns[paginationConfig.DateField]; ok {
+		start, end, err := paginationConfig.dateRange()
+		if err == nil && start != nil {
+			query.Where("? >= ?", bun.Safe(expr), *start)
+		}
+
+		if err == nil && end != nil {
+			query.Where("? < ?", bun.Safe(expr), *end)
+		}
+	}
+
+	return query
+}
+
+// ApplySorting orders query by the sort column of paginationConfig, or by the default sort
+// of spec when there is none or spec does not allow it.
+func (paginationConfig *PaginationConfig) ApplySorting(query *bun.SelectQuery, spec ListSpec) *bun.SelectQuery {
+	column := paginationConfig.SortColumn
+	if _, ok := spec.SortColumns[column]; !ok {
+		column = spec.DefaultSort
+	}
+
+	expr, ok := spec.SortColumns[column]
+	if !ok {
+		return query
+	}
+
+	direction := "DESC"
+	if strings.EqualFold(paginationConfig.SortDirection, "asc") {
+		direction = "ASC"
+	}
+
+	return query.OrderExpr("? "+direction, bun.Safe(expr))
+}
+
+func (paginationConfig *PaginationConfig) dateRange() (*time.Time, *time.Time, error) {
+	return ParseDateRange("", paginationConfig.StartDate, paginationConfig.EndDate, time.UTC, time.Now())
+}
+
+func listQueryError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidListQuery}, args...)...)
+}
+
+// likePattern matches value anywhere in a column, taking its wildcards literally.
+func likePattern(value string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+}
+
+func keys(m map[string]string) string {
+	names := make([]string, 0, len(m))
+
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -21,25 +21,30 @@ type PaginationConfig struct {
 	DateField       string
 	StartDate       string
 	EndDate         string
+
+	// UseCursor switches to keyset pagination, see BuildKeysetPaginationQuery. Cursor is
+	// the position returned with the previous page, empty for the first one.
+	UseCursor    bool
+	Cursor       string
+	CursorSecret string
+	// SkipCount leaves out the COUNT(*) of matching records, which clients scrolling through
+	// a listing rarely need.
+	SkipCount bool
 }
 
+// BuildPaginationQueryWithSorting filters query by the column filters, search and date range
+// spec allows, then pages and orders it.
 func (paginationConfig *PaginationConfig) BuildPaginationQueryWithSorting(
-	ctx context.Context, query *bun.SelectQuery, distinctCountColumn ...string,
+	ctx context.Context, query *bun.SelectQuery, spec ListSpec,
 ) (int, error) {
-	count, err := paginationConfig.BuildPaginationQuery(ctx, query, distinctCountColumn...)
+	paginationConfig.ApplyFilters(query, spec)
+
+	count, err := paginationConfig.BuildPaginationQuery(ctx, query)
 	if err != nil {
 		return 0, err
 	}
 
-	if paginationConfig.SortColumn == "" {
-		paginationConfig.SortColumn = "created_at"
-	}
-
-	if paginationConfig.SortDirection == "" {
-		paginationConfig.SortDirection = "DESC"
-	}
-
-	query.Order(paginationConfig.SortColumn + " " + paginationConfig.SortDirection)
+	paginationConfig.ApplySorting(query, spec)
 
 	return count, nil
 }
@@ -52,9 +57,11 @@ func (paginationConfig *PaginationConfig) BuildPaginationQuery(
 		countQuery.ExcludeColumn("*").ColumnExpr(fmt.Sprintf("COUNT(DISTINCT %s)", distinctCountColumn[0]))
 	}
 
-	count, err = query.Count(ctx)
-	if err != nil {
-		return 0, err
+	if !paginationConfig.SkipCount {
+		count, err = query.Count(ctx)
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	if paginationConfig.Page > 0 && paginationConfig.Limit > 0 {
@@ -73,6 +80,74 @@ func (paginationConfig *PaginationConfig) BuildPaginationQuery(
 	return count, nil
 }
 
+// BuildKeysetPaginationQuery pages query by the keyset columns, the last of which must be
+// unique, starting right after the cursor instead of skipping rows with OFFSET. Pages stay
+// fast however deep a client scrolls and records created meanwhile do not shift them.
+func (paginationConfig *PaginationConfig) BuildKeysetPaginationQuery(
+	ctx context.Context, query *bun.SelectQuery, keyset []KeysetColumn,
+) (count int, err error) {
+	if !paginationConfig.SkipCount {
+		count, err = query.Count(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if paginationConfig.Cursor != "" {
+		values, err := DecodeCursor(paginationConfig.CursorSecret, paginationConfig.Cursor, keyset)
+		if err != nil {
+			return 0, err
+		}
+
+		// (a, b) after (x, y) reads as a > x OR (a = x AND b > y), which also works when
+		// the columns are ordered in different directions
+		query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			for i, column := range keyset {
+				q.WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
+					for j := 0; j < i; j++ {
+						q.Where(keyset[j].Expr+" = ?", keyset[j].args(values[j])...)
+					}
+
+					operator := " > ?"
+					if column.Descending {
+						operator = " < ?"
+					}
+
+					return q.Where(column.Expr+operator, column.args(values[i])...)
+				})
+			}
+
+			return q
+		})
+	}
+
+	for _, column := range keyset {
+		if column.Descending {
+			query.OrderExpr(column.Expr+" DESC", column.Args...)
+		} else {
+			query.OrderExpr(column.Expr+" ASC", column.Args...)
+		}
+	}
+
+	if paginationConfig.Limit > 0 {
+		query.Limit(paginationConfig.Limit)
+	}
+
+	return count, nil
+}
+
+// NextCursor returns the cursor of the page following one of the given length whose last
+// record has the given keyset values, or an empty string when that page was the last.
+func (paginationConfig *PaginationConfig) NextCursor(
+	length int, keyset []KeysetColumn, values ...interface{},
+) (string, error) {
+	if !paginationConfig.UseCursor || paginationConfig.Limit <= 0 || length < paginationConfig.Limit {
+		return "", nil
+	}
+
+	return EncodeCursor(paginationConfig.CursorSecret, keyset, values...)
+}
+
 func PaginationConfigFromRequest(c *gin.Context) PaginationConfig {
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if err != nil || page < 1 {
@@ -92,5 +167,13 @@ func PaginationConfigFromRequest(c *gin.Context) PaginationConfig {
 		Offset:        offset,
 		SortColumn:    c.Query("sort-column"),
 		SortDirection: c.Query("sort-direction"),
+		Search:        c.Query("search"),
+		ColumnSearch:  c.QueryMap("filter"),
+		DateField:     c.Query("date-field"),
+		StartDate:     c.Query("start-date"),
+		EndDate:       c.Query("end-date"),
+		UseCursor:     c.Query("pagination") == "cursor" || c.Query("cursor") != "",
+		Cursor:        c.Query("cursor"),
+		SkipCount:     c.Query("count") == "false",
 	}
 }
@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// DeriveKey derives the key used for one purpose, such as signing cursors, from the
+// application secret, so that a value signed for one purpose is never accepted for another.
+func DeriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+
+	return mac.Sum(nil)
+}